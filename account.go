@@ -2,6 +2,7 @@ package thingscloud
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -18,90 +19,114 @@ type AccountService service
 
 // Delete deletes your current thingscloud account. This cannot be reversed
 func (s *AccountService) Delete() error {
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("/version/1/account/%s", s.client.EMail), nil)
+	return s.DeleteContext(context.Background())
+}
+
+// DeleteContext is like Delete but carries a context for cancellation and deadlines
+func (s *AccountService) DeleteContext(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("/version/1/account/%s", s.client.EMail), nil)
+	if err != nil {
+		return err
+	}
+	auth, err := s.client.authorization(ctx)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Password %s", s.client.password))
+	req.Header.Set("Authorization", auth)
 	resp, err := s.client.do(req)
 	if err != nil {
 		return err
 	}
-	resp.Body.Close()
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusAccepted {
-		if resp.StatusCode == http.StatusUnauthorized {
-			return ErrUnauthorized
-		}
-		return fmt.Errorf("http response code: %s", resp.Status)
+		return newAPIError(resp)
 	}
 	return nil
 }
 
+// AcceptSLA accepts the thingscloud service level agreement for the current account
 func (s *AccountService) AcceptSLA() error {
+	return s.AcceptSLAContext(context.Background())
+}
+
+// AcceptSLAContext is like AcceptSLA but carries a context for cancellation and deadlines
+func (s *AccountService) AcceptSLAContext(ctx context.Context) error {
 	data, err := json.Marshal(accountRequestBody{
 		SLAVersionAccepted: "https://cloud.culturedcode.com/sla/v1.5-rich.html?language=en",
 	})
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequest("PUT", fmt.Sprintf("/version/1/account/%s", s.client.EMail), bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "PUT", fmt.Sprintf("/version/1/account/%s", s.client.EMail), bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	auth, err := s.client.authorization(ctx)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Password %s", s.client.password))
+	req.Header.Set("Authorization", auth)
 	resp, err := s.client.do(req)
 	if err != nil {
 		return err
 	}
-	resp.Body.Close()
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusUnauthorized {
-			return ErrUnauthorized
-		}
-		return fmt.Errorf("http response code: %s", resp.Status)
+		return newAPIError(resp)
 	}
 	return nil
 }
 
 // Confirm finishes the account creation by providing the email token send by thingscloud
 func (s *AccountService) Confirm(code string) error {
+	return s.ConfirmContext(context.Background(), code)
+}
+
+// ConfirmContext is like Confirm but carries a context for cancellation and deadlines
+func (s *AccountService) ConfirmContext(ctx context.Context, code string) error {
 	data, err := json.Marshal(accountRequestBody{
 		ConfirmationCode: code,
 	})
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequest("PUT", fmt.Sprintf("/version/1/account/%s", s.client.EMail), bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "PUT", fmt.Sprintf("/version/1/account/%s", s.client.EMail), bytes.NewBuffer(data))
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Password %s", s.client.password))
+	auth, err := s.client.authorization(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", auth)
 	resp, err := s.client.do(req)
 	if err != nil {
 		return err
 	}
-	resp.Body.Close()
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusUnauthorized {
-			return ErrUnauthorized
-		}
-		return fmt.Errorf("http response code: %s", resp.Status)
+		return newAPIError(resp)
 	}
 	return nil
 }
 
 // SignUp creates a new thingscloud account and returns a configured client
 func (s *AccountService) SignUp(email, password string) (*Client, error) {
+	return s.SignUpContext(context.Background(), email, password)
+}
+
+// SignUpContext is like SignUp but carries a context for cancellation and deadlines
+func (s *AccountService) SignUpContext(ctx context.Context, email, password string) (*Client, error) {
 	data, err := json.Marshal(accountRequestBody{
-		Password:           password,
+		Password: password,
 	})
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("PUT", fmt.Sprintf("/version/1/account/%s", email), bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "PUT", fmt.Sprintf("/version/1/account/%s", email), bytes.NewBuffer(data))
 	if err != nil {
 		return nil, err
 	}
@@ -109,41 +134,62 @@ func (s *AccountService) SignUp(email, password string) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	resp.Body.Close()
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("http response code: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 
-	return New(s.client.Endpoint, email, password), nil
+	return s.client.withCredentials(email, password), nil
 }
 
 // ChangePassword allows you to change your account password.
 // Because things does not work with sessions you need to create a new client instance after
-// executing this method
+// executing this method.
+//
+// If the client was created with a CredentialStore, the new password is stored through it and
+// the returned client keeps using the store. If storing fails, the password was still changed:
+// the returned client holds it in memory and the error describes the failure.
 func (s *AccountService) ChangePassword(newPassword string) (*Client, error) {
+	return s.ChangePasswordContext(context.Background(), newPassword)
+}
+
+// ChangePasswordContext is like ChangePassword but carries a context for cancellation and deadlines
+func (s *AccountService) ChangePasswordContext(ctx context.Context, newPassword string) (*Client, error) {
 	data, err := json.Marshal(accountRequestBody{
 		Password: newPassword,
 	})
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("PUT", fmt.Sprintf("/version/1/account/%s", s.client.EMail), bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "PUT", fmt.Sprintf("/version/1/account/%s", s.client.EMail), bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	auth, err := s.client.authorization(ctx)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("Authorization", auth)
 	resp, err := s.client.do(req)
 	if err != nil {
 		return nil, err
 	}
-	resp.Body.Close()
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusUnauthorized {
-			return nil, ErrUnauthorized
-		}
-		return nil, fmt.Errorf("http response code: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 
-	return New(s.client.Endpoint, s.client.EMail, newPassword), nil
+	c := s.client.withCredentials(s.client.EMail, newPassword)
+	store, ok := s.client.credentials.(CredentialStore)
+	if !ok {
+		return c, nil
+	}
+	if err := store.StoreCredentials(ctx, Credentials{Email: s.client.EMail, Password: newPassword}); err != nil {
+		return c, fmt.Errorf("password changed, but storing it failed: %w", err)
+	}
+	c.password = ""
+	c.credentials = store
+	return c, nil
 }
@@ -2,6 +2,7 @@ package thingscloud
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -16,13 +17,26 @@ type AppInstanceRequest struct {
 	Dev           bool   `json:"dev"`
 }
 
-// RegisterAppInstance registers a device for push notifications via APNS
+// RegisterAppInstance registers a device for push notifications via APNS.
+// An empty AppInstanceID or AppID is taken from the client's Identity, so the
+// registration matches the device commits are attributed to.
 func (c *Client) RegisterAppInstance(req AppInstanceRequest) error {
+	return c.RegisterAppInstanceContext(context.Background(), req)
+}
+
+// RegisterAppInstanceContext is like RegisterAppInstance but carries a context for cancellation and deadlines
+func (c *Client) RegisterAppInstanceContext(ctx context.Context, req AppInstanceRequest) error {
+	if req.AppInstanceID == "" {
+		req.AppInstanceID = c.Identity.AppInstanceID
+	}
+	if req.AppID == "" {
+		req.AppID = c.Identity.AppID
+	}
 	bs, err := json.Marshal(req)
 	if err != nil {
 		return err
 	}
-	httpReq, err := http.NewRequest("PUT",
+	httpReq, err := http.NewRequestWithContext(ctx, "PUT",
 		fmt.Sprintf("/version/1/app-instance/%s", req.AppInstanceID), bytes.NewReader(bs))
 	if err != nil {
 		return err
@@ -33,7 +47,7 @@ func (c *Client) RegisterAppInstance(req AppInstanceRequest) error {
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("http response code: %s", resp.Status)
+		return newAPIError(resp)
 	}
 	return nil
 }
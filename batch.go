@@ -0,0 +1,111 @@
+package thingscloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// BatchPolicy limits the size of the commits WriteBatched creates. Zero values
+// mean no limit.
+type BatchPolicy struct {
+	// MaxItems is the maximum number of items per commit
+	MaxItems int
+	// MaxBytes is the maximum size of a commit body. An item which exceeds it on its
+	// own is committed alone.
+	MaxBytes int
+}
+
+// DefaultBatchPolicy returns a conservative policy for bulk imports
+func DefaultBatchPolicy() BatchPolicy {
+	return BatchPolicy{
+		MaxItems: 500,
+		MaxBytes: 1 << 20,
+	}
+}
+
+// BatchError is returned by WriteBatched when a commit failed. Earlier commits
+// were accepted and are not rolled back.
+type BatchError struct {
+	// Committed holds the results of the commits which succeeded
+	Committed []*CommitResult
+	// Pending holds the items which were not committed, starting with the failed commit
+	Pending []Identifiable
+	// Err is the error of the failed commit
+	Err error
+}
+
+func (e *BatchError) Error() string {
+	committed := 0
+	for _, r := range e.Committed {
+		committed += r.Count
+	}
+	return fmt.Sprintf("batch write: %d commits with %d items succeeded, %d items pending: %v", len(e.Committed), committed, len(e.Pending), e.Err)
+}
+
+// Unwrap returns the error of the failed commit
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// WriteBatched writes items as a sequence of commits limited by policy. Every commit
+// uses the server head of the previous one as ancestor, items keep their order.
+// If a commit fails, a *BatchError reports which commits landed and which items are pending.
+//
+// All items are validated as one batch before the first commit is sent, so an invalid
+// item returns a *ValidationError without committing anything, and items may refer
+// to entities created in an earlier commit of the batch.
+func (h *History) WriteBatched(items []Identifiable, policy BatchPolicy) ([]*CommitResult, error) {
+	return h.WriteBatchedContext(context.Background(), items, policy)
+}
+
+// WriteBatchedContext is like WriteBatched but carries a context for cancellation and deadlines
+func (h *History) WriteBatchedContext(ctx context.Context, items []Identifiable, policy BatchPolicy) ([]*CommitResult, error) {
+	if !h.Client.skipValidation {
+		if err := ValidateBatch(items, h.Client.refs); err != nil {
+			return nil, err
+		}
+	}
+	chunks, err := policy.split(items)
+	if err != nil {
+		return nil, err
+	}
+	var results []*CommitResult
+	done := 0
+	for _, chunk := range chunks {
+		res, err := h.write(ctx, false, chunk...)
+		if err != nil {
+			return results, &BatchError{Committed: results, Pending: items[done:], Err: err}
+		}
+		results = append(results, res)
+		done += len(chunk)
+	}
+	return results, nil
+}
+
+// split partitions items into chunks which satisfy the policy
+func (p BatchPolicy) split(items []Identifiable) ([][]Identifiable, error) {
+	var chunks [][]Identifiable
+	var chunk []Identifiable
+	size := 0
+	for _, item := range items {
+		bs, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		// "uuid":{...}, plus the braces of the commit object
+		itemSize := len(bs) + len(item.UUID()) + 4
+		full := p.MaxItems > 0 && len(chunk) >= p.MaxItems
+		tooLarge := p.MaxBytes > 0 && size+itemSize+2 > p.MaxBytes
+		if len(chunk) > 0 && (full || tooLarge) {
+			chunks = append(chunks, chunk)
+			chunk, size = nil, 0
+		}
+		chunk = append(chunk, item)
+		size += itemSize
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
@@ -0,0 +1,129 @@
+package thingscloud_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	thingscloud "github.com/arthursoares/things-cloud-sdk"
+	memory "github.com/arthursoares/things-cloud-sdk/state/memory"
+	"github.com/arthursoares/things-cloud-sdk/thingscloudtest"
+	"github.com/arthursoares/things-cloud-sdk/write"
+)
+
+func TestHistory_WriteBatched(t *testing.T) {
+	items := func(n int) []thingscloud.Identifiable {
+		var items []thingscloud.Identifiable
+		for i := 0; i < n; i++ {
+			items = append(items, newTask(fmt.Sprintf("task-%d", i), "task"))
+		}
+		return items
+	}
+
+	t.Run("MaxItems", func(t *testing.T) {
+		t.Parallel()
+		s := thingscloudtest.NewServer("martin@example.com", "secret")
+		defer s.Close()
+		h := s.Client(thingscloud.WithoutValidation()).HistoryWithID(s.OwnHistoryKey())
+
+		results, err := h.WriteBatched(items(5), thingscloud.BatchPolicy{MaxItems: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 3 {
+			t.Fatalf("Expected 3 commits, got %d", len(results))
+		}
+		for i, res := range results {
+			if res.AncestorIndex != i || res.ServerHeadIndex != i+1 {
+				t.Errorf("Expected commit %d to chain from %d to %d, got %d to %d", i, i, i+1, res.AncestorIndex, res.ServerHeadIndex)
+			}
+		}
+		if got := results[2].UUIDs; len(got) != 1 || got[0] != "task-4" {
+			t.Errorf("Expected last commit to hold task-4, got %v", got)
+		}
+		if got := len(s.Commits(s.OwnHistoryKey())); got != 3 {
+			t.Errorf("Expected 3 commits on the server, got %d", got)
+		}
+	})
+
+	t.Run("MaxBytes", func(t *testing.T) {
+		t.Parallel()
+		s := thingscloudtest.NewServer("martin@example.com", "secret")
+		defer s.Close()
+		h := s.Client(thingscloud.WithoutValidation()).HistoryWithID(s.OwnHistoryKey())
+
+		results, err := h.WriteBatched(items(4), thingscloud.BatchPolicy{MaxBytes: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 4 {
+			t.Errorf("Expected oversized items to be committed alone, got %d commits", len(results))
+		}
+	})
+
+	t.Run("PartialFailure", func(t *testing.T) {
+		t.Parallel()
+		s := thingscloudtest.NewServer("martin@example.com", "secret")
+		defer s.Close()
+		h := s.Client(thingscloud.WithoutValidation()).HistoryWithID(s.OwnHistoryKey())
+
+		// the server rejects items without a kind
+		all := items(5)
+		invalid := newTask("task-3", "invalid")
+		invalid.Kind = ""
+		all[3] = invalid
+		if _, err := h.Write(all[0]); err != nil {
+			t.Fatal(err)
+		}
+		_, err := h.WriteBatched(all[1:], thingscloud.BatchPolicy{MaxItems: 2})
+		var batchErr *thingscloud.BatchError
+		if !errors.As(err, &batchErr) {
+			t.Fatalf("Expected BatchError, got %v", err)
+		}
+		if len(batchErr.Committed) != 1 || batchErr.Committed[0].AncestorIndex != 1 {
+			t.Errorf("Expected the first commit to be reported, got %#v", batchErr.Committed)
+		}
+		if len(batchErr.Pending) != 2 || batchErr.Pending[0].UUID() != "task-3" {
+			t.Errorf("Expected task-3 and task-4 to be pending, got %d items", len(batchErr.Pending))
+		}
+		var apiErr *thingscloud.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected the commit error to be unwrapped, got %v", err)
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		t.Parallel()
+		s := thingscloudtest.NewServer("martin@example.com", "secret")
+		defer s.Close()
+		h := s.Client().HistoryWithID(s.OwnHistoryKey())
+
+		all := []thingscloud.Identifiable{write.NewTask("First"), write.NewTask("Second"), rawTask(thingscloud.ItemActionCreated, map[string]any{"tt": "Buy milk"})}
+		_, err := h.WriteBatched(all, thingscloud.BatchPolicy{MaxItems: 1})
+		var verr *thingscloud.ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("Expected ValidationError, got %v", err)
+		}
+		if got := len(s.Commits(s.OwnHistoryKey())); got != 0 {
+			t.Errorf("Expected nothing to be committed, got %d commits", got)
+		}
+	})
+
+	t.Run("References", func(t *testing.T) {
+		t.Parallel()
+		s := thingscloudtest.NewServer("martin@example.com", "secret")
+		defer s.Close()
+		h := s.Client(thingscloud.WithReferences(memory.NewState())).HistoryWithID(s.OwnHistoryKey())
+
+		area := write.NewArea("Home")
+		all := []thingscloud.Identifiable{area, write.NewTask("Task").InArea(area.UUID())}
+		results, err := h.WriteBatched(all, thingscloud.BatchPolicy{MaxItems: 1})
+		if err != nil {
+			t.Fatalf("Expected references to earlier commits to resolve, got %v", err)
+		}
+		if len(results) != 2 {
+			t.Errorf("Expected 2 commits, got %d", len(results))
+		}
+	})
+}
@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"time"
 )
 
 const (
@@ -58,10 +61,22 @@ type Client struct {
 	EMail      string
 	password   string
 	ClientInfo ClientInfo
-	Debug      bool
-
-	client *http.Client
-	common service
+	// Identity is the device commits are attributed to
+	Identity WriterIdentity
+	Debug    bool
+
+	client         *http.Client
+	credentials    CredentialProvider
+	userAgent      string
+	host           string
+	logger         *log.Logger
+	slog           *slog.Logger
+	maskContent    bool
+	drift          *DriftDetector
+	refs           References
+	skipValidation bool
+	retry          RetryPolicy
+	common         service
 
 	Accounts *AccountService
 }
@@ -70,21 +85,97 @@ type service struct {
 	client *Client
 }
 
+// Option configures a Client created with NewWithOptions
+type Option func(*Client)
+
+// WithHTTPClient replaces the http.Client used for all requests
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.client = hc
+	}
+}
+
+// WithTransport sets the RoundTripper used for all requests, e.g. to add a proxy,
+// custom TLS settings or a test double. The configured http.Client is copied,
+// so a client passed to WithHTTPClient is not modified.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		hc := *c.client
+		hc.Transport = rt
+		c.client = &hc
+	}
+}
+
+// WithClientInfo sets the device metadata sent in the things-client-info header
+func WithClientInfo(ci ClientInfo) Option {
+	return func(c *Client) {
+		c.ClientInfo = ci
+	}
+}
+
+// WithUserAgent overrides the User-Agent header, which defaults to ThingsUserAgent
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithLogger sets the logger debug output is written to. It defaults to the
+// standard logger of the log package. Credentials are redacted from the output.
+// A logger set with WithStructuredLogger takes precedence.
+func WithLogger(l *log.Logger) Option {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
+
+// WithHost overrides the Host header sent with every request. By default the host
+// of the endpoint is used, which allows talking to a local stand-in server.
+func WithHost(host string) Option {
+	return func(c *Client) {
+		c.host = host
+	}
+}
+
 // New initializes a things client
 func New(endpoint, email, password string) *Client {
+	return NewWithOptions(endpoint, email, password)
+}
+
+// NewWithOptions initializes a things client, applying opts in order
+func NewWithOptions(endpoint, email, password string, opts ...Option) *Client {
 	c := &Client{
 		Endpoint:   endpoint,
 		EMail:      email,
 		password:   password,
 		ClientInfo: DefaultClientInfo(),
 
-		client: &http.Client{},
+		client:    &http.Client{},
+		userAgent: ThingsUserAgent,
+		logger:    log.Default(),
+		retry:     DefaultRetryPolicy(),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	c.completeIdentity()
 	c.common.client = c
 	c.Accounts = (*AccountService)(&c.common)
 	return c
 }
 
+// withCredentials returns a copy of c sharing its configuration, but
+// authenticating as a different account
+func (c *Client) withCredentials(email, password string) *Client {
+	n := *c
+	n.EMail = email
+	n.password = password
+	n.credentials = nil
+	n.common.client = &n
+	n.Accounts = (*AccountService)(&n.common)
+	return &n
+}
+
 // ThingsUserAgent is the http user-agent header set by things for mac
 const ThingsUserAgent = "ThingsMac/32209501"
 
@@ -99,8 +190,10 @@ func (c *Client) do(req *http.Request) (*http.Response, error) {
 	}
 
 	// Common headers matching Things.app
-	req.Header.Set("Host", "cloud.culturedcode.com")
-	req.Header.Set("User-Agent", ThingsUserAgent)
+	if c.host != "" {
+		req.Host = c.host
+	}
+	req.Header.Set("User-Agent", c.userAgent)
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Accept-Charset", "UTF-8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
@@ -117,18 +210,38 @@ func (c *Client) do(req *http.Request) (*http.Response, error) {
 	}
 	req.Header.Set("Things-Client-Info", base64.StdEncoding.EncodeToString(ciJSON))
 
-	if c.Debug {
+	return c.doWithRetry(req)
+}
+
+// send performs a single attempt of req
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.Debug && c.slog == nil {
 		bs, _ := httputil.DumpRequest(req, true)
-		log.Println("REQUEST:", string(bs))
+		c.logger.Println("REQUEST:", c.redactDump(bs))
+	}
+	var reqBody []byte
+	if c.Debug && c.slog != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody, _ = io.ReadAll(io.LimitReader(body, maxLogBodySize+1))
+			body.Close()
+		}
 	}
 
+	start := time.Now()
 	resp, err := c.client.Do(req)
-	if c.Debug {
+	if c.slog != nil {
+		if err != nil {
+			c.logRequest(req, nil, start, 0, err, reqBody, nil)
+		} else {
+			resp.Body = &loggedBody{ReadCloser: resp.Body, c: c, req: req, resp: resp, start: start, reqBody: reqBody}
+		}
+	}
+	if c.Debug && c.slog == nil {
 		if err == nil {
 			bs, _ := httputil.DumpResponse(resp, true)
-			log.Println("RESPONSE:", string(bs))
+			c.logger.Println("RESPONSE:", c.redactDump(bs))
 		}
-		log.Println()
+		c.logger.Println()
 	}
 	return resp, err
 }
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -73,3 +74,88 @@ func TestClient_UserAgent(t *testing.T) {
 		t.Error("things-client-info header is missing or empty")
 	}
 }
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestNewWithOptions(t *testing.T) {
+	t.Run("Transport", func(t *testing.T) {
+		t.Parallel()
+		var called bool
+		rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			called = true
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{}`)),
+				Header:     http.Header{},
+				Request:    req,
+			}, nil
+		})
+
+		c := NewWithOptions("http://things.invalid", "test@example.com", "password", WithTransport(rt))
+		req, _ := http.NewRequest("GET", "/test", nil)
+		if _, err := c.do(req); err != nil {
+			t.Fatal(err)
+		}
+		if !called {
+			t.Error("expected custom transport to be used")
+		}
+	})
+
+	t.Run("TransportDoesNotModifyHTTPClient", func(t *testing.T) {
+		t.Parallel()
+		hc := &http.Client{}
+		NewWithOptions("http://things.invalid", "", "", WithHTTPClient(hc), WithTransport(http.DefaultTransport))
+		if hc.Transport != nil {
+			t.Error("expected the provided http.Client to be left untouched")
+		}
+	})
+
+	t.Run("HeadersAndHost", func(t *testing.T) {
+		t.Parallel()
+		var captured *http.Request
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured = r
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer ts.Close()
+
+		c := NewWithOptions(ts.URL, "test@example.com", "password",
+			WithUserAgent("things-sdk-test/1.0"),
+			WithHost("cloud.culturedcode.com"),
+		)
+		req, _ := http.NewRequest("GET", "/test", nil)
+		if _, err := c.do(req); err != nil {
+			t.Fatal(err)
+		}
+		if got := captured.Header.Get("User-Agent"); got != "things-sdk-test/1.0" {
+			t.Errorf("User-Agent = %q, want %q", got, "things-sdk-test/1.0")
+		}
+		if captured.Host != "cloud.culturedcode.com" {
+			t.Errorf("Host = %q, want %q", captured.Host, "cloud.culturedcode.com")
+		}
+	})
+
+	t.Run("ChangePasswordKeepsOptions", func(t *testing.T) {
+		t.Parallel()
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer ts.Close()
+
+		c := NewWithOptions(ts.URL, "test@example.com", "password", WithUserAgent("things-sdk-test/1.0"))
+		n, err := c.Accounts.ChangePassword("new-password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.userAgent != "things-sdk-test/1.0" || n.password != "new-password" {
+			t.Errorf("expected new client to keep options and use the new password")
+		}
+		if n.Accounts == c.Accounts {
+			t.Error("expected new client to have its own AccountService")
+		}
+	})
+}
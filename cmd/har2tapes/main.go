@@ -0,0 +1,59 @@
+// Command har2tapes turns HAR captures of Things.app traffic into replayable tapes
+// and reports payload fields the SDK does not decode yet.
+//
+//	har2tapes [-out tapes/har] [-json] capture.har...
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/arthursoares/things-cloud-sdk/har"
+)
+
+func main() {
+	out := flag.String("out", "tapes/har", "directory to write the tape to")
+	asJSON := flag.Bool("json", false, "print the unknown field report as JSON")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] capture.har...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var exchanges []har.Exchange
+	for _, path := range flag.Args() {
+		f, err := har.ParseFile(path)
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		exchanges = append(exchanges, har.Extract(f)...)
+	}
+	if len(exchanges) == 0 {
+		log.Fatal("no /items or /commit exchanges found")
+	}
+
+	if err := har.WriteTapes(*out, exchanges); err != nil {
+		log.Fatalf("writing tapes: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d exchanges to %s\n", len(exchanges), *out)
+
+	report := har.Analyze(exchanges)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := report.WriteText(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,165 +1,24 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"hash/crc32"
-	"math/big"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	thingscloud "github.com/arthursoares/things-cloud-sdk"
 	memory "github.com/arthursoares/things-cloud-sdk/state/memory"
+	"github.com/arthursoares/things-cloud-sdk/write"
 )
 
 // ---------------------------------------------------------------------------
-// Wire-format types (no omitempty — Things expects all fields on creates)
+// Helpers: dates, args, errors
 // ---------------------------------------------------------------------------
 
-// WireNote matches the Things note wire format exactly.
-// Field order must be _t, ch, v, t (matching what Things.app expects).
-type WireNote struct {
-	TypeTag  string `json:"_t"`
-	Checksum int64  `json:"ch"`
-	Value    string `json:"v"`
-	Type     int    `json:"t"`
-}
-
-// WireExtension is the required xx field: {sn: {}, _t: "oo"}.
-type WireExtension struct {
-	Sn      map[string]any `json:"sn"`
-	TypeTag string         `json:"_t"`
-}
-
-// writeEnvelope is a single generic wrapper for history.Write().
-// Implements Identifiable (UUID()) and json.Marshaler.
-type writeEnvelope struct {
-	id      string
-	action  int
-	kind    string
-	payload any
-}
-
-func (w writeEnvelope) UUID() string { return w.id }
-
-func (w writeEnvelope) MarshalJSON() ([]byte, error) {
-	return json.Marshal(struct {
-		T int    `json:"t"`
-		E string `json:"e"`
-		P any    `json:"p"`
-	}{w.action, w.kind, w.payload})
-}
-
-// TaskCreatePayload — all 34 fields, no omitempty, field order matches HAR.
-type TaskCreatePayload struct {
-	Tp   int              `json:"tp"`
-	Sr   *int64           `json:"sr"`
-	Dds  *int64           `json:"dds"`
-	Rt   []string         `json:"rt"`
-	Rmd  *int64           `json:"rmd"`
-	Ss   int              `json:"ss"`
-	Tr   bool             `json:"tr"`
-	Dl   []string         `json:"dl"`
-	Icp  bool             `json:"icp"`
-	St   int              `json:"st"`
-	Ar   []string         `json:"ar"`
-	Tt   string           `json:"tt"`
-	Do   int              `json:"do"`
-	Lai  *int64           `json:"lai"`
-	Tir  *int64           `json:"tir"`
-	Tg   []string         `json:"tg"`
-	Agr  []string         `json:"agr"`
-	Ix   int              `json:"ix"`
-	Cd   float64          `json:"cd"`
-	Lt   bool             `json:"lt"`
-	Icc  int              `json:"icc"`
-	Md   *float64         `json:"md"`
-	Ti   int              `json:"ti"`
-	Dd   *int64           `json:"dd"`
-	Ato  *int             `json:"ato"`
-	Nt   WireNote         `json:"nt"`
-	Icsd *int64           `json:"icsd"`
-	Pr   []string         `json:"pr"`
-	Rp   *string          `json:"rp"`
-	Acrd *int64           `json:"acrd"`
-	Sp   *float64         `json:"sp"`
-	Sb   int              `json:"sb"`
-	Rr   *json.RawMessage `json:"rr"`
-	Xx   WireExtension    `json:"xx"`
-}
-
-// ChecklistItemCreatePayload — all 9 fields for checklist item creation.
-type ChecklistItemCreatePayload struct {
-	Cd   float64       `json:"cd"`
-	Md   *float64      `json:"md"`
-	Tt   string        `json:"tt"`
-	Ss   int           `json:"ss"`
-	Sp   *float64      `json:"sp"`
-	Ix   int           `json:"ix"`
-	Ts   []string      `json:"ts"`
-	Lt   bool          `json:"lt"`
-	Xx   WireExtension `json:"xx"`
-}
-
-// TagCreatePayload — all 5 fields for tag creation.
-type TagCreatePayload struct {
-	Tt string        `json:"tt"`
-	Ix int           `json:"ix"`
-	Sh *string       `json:"sh"`
-	Pn []string      `json:"pn"`
-	Xx WireExtension `json:"xx"`
-}
-
-// ---------------------------------------------------------------------------
-// Helpers: notes, UUID, timestamps, errors
-// ---------------------------------------------------------------------------
-
-func emptyNote() WireNote {
-	return WireNote{TypeTag: "tx", Checksum: 0, Value: "", Type: 1}
-}
-
-func noteChecksum(s string) int64 {
-	return int64(crc32.ChecksumIEEE([]byte(s)))
-}
-
-func textNote(s string) WireNote {
-	return WireNote{TypeTag: "tx", Checksum: noteChecksum(s), Value: s, Type: 1}
-}
-
-func defaultExtension() WireExtension {
-	return WireExtension{Sn: map[string]any{}, TypeTag: "oo"}
-}
-
-func generateUUID() string {
-	u := uuid.New()
-	// Base58 alphabet (Bitcoin/Flickr): no 0, O, I, l
-	const alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
-	n := new(big.Int).SetBytes(u[:])
-	base := big.NewInt(58)
-	mod := new(big.Int)
-	var encoded []byte
-	for n.Sign() > 0 {
-		n.DivMod(n, base, mod)
-		encoded = append(encoded, alphabet[mod.Int64()])
-	}
-	// Reverse (big-endian)
-	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
-		encoded[i], encoded[j] = encoded[j], encoded[i]
-	}
-	return string(encoded)
-}
-
-func nowTs() float64 {
-	return float64(time.Now().UnixNano()) / 1e9
-}
-
-func todayMidnightUTC() int64 {
-	now := time.Now()
-	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Unix()
-}
-
 func parseDate(s string) *time.Time {
 	t, err := time.Parse("2006-01-02", s)
 	if err != nil {
@@ -215,235 +74,99 @@ func outputJSON(v any) {
 }
 
 // ---------------------------------------------------------------------------
-// Payload builders
+// Builders — map command options onto the write package
 // ---------------------------------------------------------------------------
 
-func newTaskCreatePayload(title string, opts map[string]string) TaskCreatePayload {
-	now := nowTs()
-
-	// Defaults
-	var st int
-	var sr *int64
-	var tir *int64
-	var dd *int64
-	tp := 0
-	pr := []string{}
-	agr := []string{}
-	ar := []string{}
-	tg := []string{}
-	nt := emptyNote()
-
-	// --type
-	if v, ok := opts["type"]; ok {
-		switch v {
-		case "project":
-			tp = 1
-		case "heading":
-			tp = 2
-			st = 1 // headings are structural — always "started" (anytime), never inbox
-		}
+// newTaskBuilder builds a task creation from create options
+func newTaskBuilder(title string, opts map[string]string) *write.TaskBuilder {
+	b := write.NewTask(title)
+	if v := opts["uuid"]; v != "" {
+		b.WithID(v)
 	}
-
-	// --when (schedule mapping per HAR)
-	if v, ok := opts["when"]; ok {
-		switch v {
-		case "today":
-			st = 1
-			today := todayMidnightUTC()
-			sr = &today
-			tir = &today
-		case "anytime":
-			st = 1
-		case "someday":
-			st = 2
-		case "inbox":
-			st = 0
-		}
+	switch opts["type"] {
+	case "project":
+		b.AsProject()
+	case "heading":
+		b.AsHeading()
 	}
-
-	// --note
-	if v, ok := opts["note"]; ok && v != "" {
-		nt = textNote(v)
+	switch opts["when"] {
+	case "today":
+		b.Today()
+	case "anytime":
+		b.Anytime()
+	case "someday":
+		b.Someday()
+	case "inbox":
+		b.Inbox()
 	}
-
-	// --deadline
-	if v, ok := opts["deadline"]; ok {
-		if t := parseDate(v); t != nil {
-			ts := t.Unix()
-			dd = &ts
-		}
+	if v := opts["note"]; v != "" {
+		b.Note(v)
 	}
-
-	// --scheduled (overrides sr/tir; sets st=1 with dates if not already set by --when)
-	if v, ok := opts["scheduled"]; ok {
-		if t := parseDate(v); t != nil {
-			ts := t.Unix()
-			sr = &ts
-			tir = &ts
-			if _, hasWhen := opts["when"]; !hasWhen {
-				st = 1 // default to anytime+date
-			}
-		}
+	if t := parseDate(opts["deadline"]); t != nil {
+		b.Deadline(*t)
 	}
-
-	// --project
-	if v, ok := opts["project"]; ok && v != "" {
-		pr = []string{v}
-		// Tasks in projects are already triaged — auto-set anytime (st=1) unless --when was explicit
-		if _, hasWhen := opts["when"]; !hasWhen {
-			st = 1
-		}
+	if t := parseDate(opts["scheduled"]); t != nil {
+		b.ScheduledOn(*t)
 	}
-
-	// --heading
-	if v, ok := opts["heading"]; ok && v != "" {
-		agr = []string{v}
-		// Tasks under headings are structural — auto-set anytime (st=1) unless --when was explicit
-		if _, hasWhen := opts["when"]; !hasWhen {
-			st = 1
-		}
+	if v := opts["project"]; v != "" {
+		b.InProject(v)
 	}
-
-	// --area
-	if v, ok := opts["area"]; ok && v != "" {
-		ar = []string{v}
-		// Tasks in areas are already triaged — auto-set anytime (st=1) unless --when was explicit
-		if _, hasWhen := opts["when"]; !hasWhen {
-			st = 1
-		}
+	if v := opts["heading"]; v != "" {
+		b.UnderHeading(v)
 	}
-
-	// --tags (comma-separated)
-	if v, ok := opts["tags"]; ok && v != "" {
-		tg = strings.Split(v, ",")
-	}
-
-	// --uuid handled by caller
-
-	return TaskCreatePayload{
-		Tp:   tp,
-		Sr:   sr,
-		Dds:  nil,
-		Rt:   []string{},
-		Rmd:  nil,
-		Ss:   0,
-		Tr:   false,
-		Dl:   []string{},
-		Icp:  false,
-		St:   st,
-		Ar:   ar,
-		Tt:   title,
-		Do:   0,
-		Lai:  nil,
-		Tir:  tir,
-		Tg:   tg,
-		Agr:  agr,
-		Ix:   0,
-		Cd:   now,
-		Lt:   false,
-		Icc:  0,
-		Md:   nil, // must be null for creates — Things.app crashes otherwise
-		Ti:   0,
-		Dd:   dd,
-		Ato:  nil,
-		Nt:   nt,
-		Icsd: nil,
-		Pr:   pr,
-		Rp:   nil,
-		Acrd: nil,
-		Sp:   nil,
-		Sb:   0,
-		Rr:   nil,
-		Xx:   defaultExtension(),
+	if v := opts["area"]; v != "" {
+		b.InArea(v)
 	}
+	if v := opts["tags"]; v != "" {
+		b.WithTags(strings.Split(v, ",")...)
+	}
+	return b
 }
 
-// ---------------------------------------------------------------------------
-// Fluent update builder — for sparse updates (edit, complete, trash, etc.)
-// ---------------------------------------------------------------------------
-
-type taskUpdate struct {
-	fields map[string]any
-}
-
-func newTaskUpdate() *taskUpdate {
-	return &taskUpdate{fields: map[string]any{
-		"md": nowTs(),
-	}}
-}
-
-func (u *taskUpdate) Title(s string) *taskUpdate {
-	u.fields["tt"] = s
-	return u
-}
-
-func (u *taskUpdate) Note(text string) *taskUpdate {
-	u.fields["nt"] = textNote(text)
-	return u
-}
-
-func (u *taskUpdate) ClearNote() *taskUpdate {
-	u.fields["nt"] = emptyNote()
-	return u
-}
-
-func (u *taskUpdate) Status(ss int) *taskUpdate {
-	u.fields["ss"] = ss
-	return u
-}
-
-func (u *taskUpdate) StopDate(ts float64) *taskUpdate {
-	u.fields["sp"] = ts
-	return u
-}
-
-func (u *taskUpdate) Trash(b bool) *taskUpdate {
-	u.fields["tr"] = b
-	return u
-}
-
-func (u *taskUpdate) Schedule(st int, sr, tir any) *taskUpdate {
-	u.fields["st"] = st
-	u.fields["sr"] = sr
-	u.fields["tir"] = tir
-	return u
-}
-
-func (u *taskUpdate) Deadline(dd int64) *taskUpdate {
-	u.fields["dd"] = dd
-	return u
-}
-
-func (u *taskUpdate) Scheduled(sr, tir int64) *taskUpdate {
-	u.fields["sr"] = sr
-	u.fields["tir"] = tir
-	return u
-}
-
-func (u *taskUpdate) Area(uuid string) *taskUpdate {
-	u.fields["ar"] = []string{uuid}
-	return u
-}
-
-func (u *taskUpdate) Project(uuid string) *taskUpdate {
-	u.fields["pr"] = []string{uuid}
-	return u
-}
-
-func (u *taskUpdate) Heading(uuid string) *taskUpdate {
-	u.fields["agr"] = []string{uuid}
-	return u
-}
-
-func (u *taskUpdate) Tags(uuids []string) *taskUpdate {
-	u.fields["tg"] = uuids
+// newTaskUpdate builds a sparse task modification from edit options
+func newTaskUpdate(taskUUID string, opts map[string]string) *write.TaskUpdate {
+	u := write.UpdateTask(taskUUID)
+	if v, ok := opts["title"]; ok {
+		u.Title(v)
+	}
+	if v, ok := opts["note"]; ok {
+		if v == "" {
+			u.ClearNote()
+		} else {
+			u.Note(v)
+		}
+	}
+	switch opts["when"] {
+	case "today":
+		u.Today()
+	case "anytime":
+		u.Anytime()
+	case "someday":
+		u.Someday()
+	case "inbox":
+		u.Inbox()
+	}
+	if t := parseDate(opts["deadline"]); t != nil {
+		u.Deadline(*t)
+	}
+	if t := parseDate(opts["scheduled"]); t != nil {
+		u.ScheduledOn(*t)
+	}
+	if v := opts["area"]; v != "" {
+		u.InArea(v)
+	}
+	if v := opts["project"]; v != "" {
+		u.InProject(v)
+	}
+	if v := opts["heading"]; v != "" {
+		u.UnderHeading(v)
+	}
+	if v := opts["tags"]; v != "" {
+		u.WithTags(strings.Split(v, ",")...)
+	}
 	return u
 }
 
-func (u *taskUpdate) build() map[string]any {
-	return u.fields
-}
-
 // ---------------------------------------------------------------------------
 // cliContext and state loading
 // ---------------------------------------------------------------------------
@@ -453,11 +176,44 @@ type cliContext struct {
 	history *thingscloud.History
 }
 
-func initCLI() *cliContext {
-	username := requireEnv("THINGS_USERNAME")
-	password := requireEnv("THINGS_PASSWORD")
+// credentialProvider picks where the credentials come from: THINGS_CREDENTIALS_FILE
+// (JSON or TOML), THINGS_KEYRING_FILE (unlocked with THINGS_KEYRING_PASSPHRASE),
+// a netrc entry for thingscloud, or THINGS_USERNAME and THINGS_PASSWORD.
+func credentialProvider() thingscloud.CredentialProvider {
+	if path := os.Getenv("THINGS_CREDENTIALS_FILE"); path != "" {
+		return thingscloud.NewFileCredentials(path)
+	}
+	if path := os.Getenv("THINGS_KEYRING_FILE"); path != "" {
+		return thingscloud.NewKeyringFile(path, requireEnv("THINGS_KEYRING_PASSPHRASE"))
+	}
+	if os.Getenv("THINGS_USERNAME") == "" {
+		netrc := thingscloud.NewNetrcCredentials()
+		if _, err := netrc.Credentials(context.Background()); err == nil {
+			return netrc
+		}
+	}
+	return thingscloud.NewEnvCredentials()
+}
+
+func initCLI(opts ...thingscloud.Option) *cliContext {
+	// THINGS_ENDPOINT points the CLI at another server, e.g. a thingscloudtest fake
+	endpoint := thingscloud.APIEndpoint
+	if v := os.Getenv("THINGS_ENDPOINT"); v != "" {
+		endpoint = v
+	}
+
+	// keep one app instance id across runs, so the CLI shows up as a single device
+	if dir, err := os.UserConfigDir(); err == nil {
+		path := filepath.Join(dir, "things-cli", "app-instance-id")
+		if id, err := thingscloud.LoadAppInstanceID(path, thingscloud.DefaultAppID); err == nil {
+			opts = append([]thingscloud.Option{thingscloud.WithWriterIdentity(thingscloud.WriterIdentity{AppInstanceID: id})}, opts...)
+		}
+	}
 
-	c := thingscloud.New(thingscloud.APIEndpoint, username, password)
+	c, err := thingscloud.NewWithCredentialProvider(endpoint, credentialProvider(), opts...)
+	if err != nil {
+		fatal("credentials", err)
+	}
 	if os.Getenv("THINGS_DEBUG") != "" {
 		c.Debug = true
 	}
@@ -478,22 +234,13 @@ func initCLI() *cliContext {
 }
 
 func (ctx *cliContext) loadState() *memory.State {
-	var allItems []thingscloud.Item
-	startIndex := 0
-	for {
-		items, hasMore, err := ctx.history.Items(thingscloud.ItemsOptions{StartIndex: startIndex})
+	state := memory.NewState()
+	for item, err := range ctx.history.All(context.Background(), 0) {
 		if err != nil {
 			fatal("fetch items", err)
 		}
-		allItems = append(allItems, items...)
-		if !hasMore {
-			break
-		}
-		startIndex = ctx.history.LoadedServerIndex
+		state.Update(item)
 	}
-
-	state := memory.NewState()
-	state.Update(allItems...)
 	return state
 }
 
@@ -529,11 +276,11 @@ func taskToOutput(t *thingscloud.Task) TaskOutput {
 		ParentIDs: t.ParentTaskIDs,
 	}
 	if t.ScheduledDate != nil {
-		s := t.ScheduledDate.Format("2006-01-02")
+		s := t.ScheduledDate.String()
 		out.ScheduledDate = &s
 	}
 	if t.DeadlineDate != nil {
-		s := t.DeadlineDate.Format("2006-01-02")
+		s := t.DeadlineDate.String()
 		out.DeadlineDate = &s
 	}
 	return out
@@ -541,7 +288,7 @@ func taskToOutput(t *thingscloud.Task) TaskOutput {
 
 func cmdList(state *memory.State, args []string) {
 	opts := parseArgs(args)
-	todayStart := time.Date(time.Now().Year(), time.Now().Month(), time.Now().Day(), 0, 0, 0, 0, time.UTC)
+	today := thingscloud.Today(time.Local)
 
 	var tasks []TaskOutput
 	for _, task := range state.Tasks {
@@ -551,7 +298,7 @@ func cmdList(state *memory.State, args []string) {
 
 		// Filters
 		if _, ok := opts["today"]; ok {
-			if task.Schedule != thingscloud.TaskScheduleAnytime || task.ScheduledDate == nil || !task.ScheduledDate.Equal(todayStart) {
+			if task.Schedule != thingscloud.TaskScheduleAnytime || task.ScheduledDate == nil || *task.ScheduledDate != today {
 				continue
 			}
 		}
@@ -659,29 +406,34 @@ func containsStr(slice []string, s string) bool {
 	return false
 }
 
+// cmdDrift reads the whole history with strict decoding and reports payload fields
+// and item kinds the SDK doesn't decode. It exits with 3 if there is any drift.
+func cmdDrift(ctx *cliContext, args []string) {
+	ctx.loadState()
+	report := ctx.client.DriftReport()
+	if _, ok := parseArgs(args)["text"]; ok {
+		if err := report.WriteText(os.Stdout); err != nil {
+			fatal("write report", err)
+		}
+	} else {
+		outputJSON(report)
+	}
+	if report.HasDrift() {
+		os.Exit(3)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Write commands
 // ---------------------------------------------------------------------------
 
-func cmdWriteChecklistItems(history *thingscloud.History, taskUUID string, items []string) {
-	now := nowTs()
-	for i, title := range items {
-		itemUUID := generateUUID()
-		payload := ChecklistItemCreatePayload{
-			Cd: now,
-			Md: nil,
-			Tt: strings.TrimSpace(title),
-			Ss: 0,
-			Sp: nil,
-			Ix: i,
-			Ts: []string{taskUUID},
-			Lt: false,
-			Xx: defaultExtension(),
-		}
-		env := writeEnvelope{id: itemUUID, action: 0, kind: "ChecklistItem3", payload: payload}
-		if err := history.Write(env); err != nil {
-			fatal("create checklist item", err)
-		}
+func cmdWriteChecklistItems(history *thingscloud.History, taskUUID string, titles []string) {
+	items := make([]thingscloud.Identifiable, len(titles))
+	for i, title := range titles {
+		items[i] = write.NewChecklistItem(taskUUID, strings.TrimSpace(title)).Index(i)
+	}
+	if _, err := history.Write(items...); err != nil {
+		fatal("create checklist item", err)
 	}
 }
 
@@ -691,23 +443,17 @@ func cmdCreate(history *thingscloud.History, args []string) {
 	title := args[0]
 	opts := parseArgs(args[1:])
 
-	taskUUID := opts["uuid"]
-	if taskUUID == "" {
-		taskUUID = generateUUID()
-	}
-
-	payload := newTaskCreatePayload(title, opts)
-	env := writeEnvelope{id: taskUUID, action: 0, kind: "Task6", payload: payload}
-	if err := history.Write(env); err != nil {
+	task := newTaskBuilder(title, opts)
+	if _, err := history.Write(task); err != nil {
 		fatal("create task", err)
 	}
 
 	// Write checklist items (if any) after the task
 	if v, ok := opts["checklist"]; ok && v != "" {
-		cmdWriteChecklistItems(history, taskUUID, strings.Split(v, ","))
+		cmdWriteChecklistItems(history, task.UUID(), strings.Split(v, ","))
 	}
 
-	outputJSON(map[string]string{"status": "created", "uuid": taskUUID, "title": title})
+	outputJSON(map[string]string{"status": "created", "uuid": task.UUID(), "title": title})
 }
 
 func cmdAddChecklist(history *thingscloud.History, taskUUID string, args []string) {
@@ -725,72 +471,7 @@ func cmdEdit(history *thingscloud.History, taskUUID string, args []string) {
 		fatalf("Usage: things-cli edit <uuid> [--title ...] [--note ...] [--when today|anytime|someday|inbox] [--deadline YYYY-MM-DD] [--scheduled YYYY-MM-DD] [--area UUID] [--project UUID] [--heading UUID] [--tags UUID,...]")
 	}
 
-	u := newTaskUpdate()
-
-	if v, ok := opts["title"]; ok {
-		u.Title(v)
-	}
-	if v, ok := opts["note"]; ok {
-		if v == "" {
-			u.ClearNote()
-		} else {
-			u.Note(v)
-		}
-	}
-	if v, ok := opts["when"]; ok {
-		switch v {
-		case "today":
-			today := todayMidnightUTC()
-			u.Schedule(1, today, today)
-		case "anytime":
-			u.Schedule(1, nil, nil)
-		case "someday":
-			u.Schedule(2, nil, nil)
-		case "inbox":
-			u.Schedule(0, nil, nil)
-		}
-	}
-	if v, ok := opts["deadline"]; ok {
-		if t := parseDate(v); t != nil {
-			u.Deadline(t.Unix())
-		}
-	}
-	if v, ok := opts["scheduled"]; ok {
-		if t := parseDate(v); t != nil {
-			ts := t.Unix()
-			u.Scheduled(ts, ts)
-			if _, hasWhen := opts["when"]; !hasWhen {
-				u.Schedule(1, ts, ts)
-			}
-		}
-	}
-	if v, ok := opts["area"]; ok && v != "" {
-		u.Area(v)
-		// When adding an area, also move out of Inbox (st=0 → st=1)
-		if _, hasWhen := opts["when"]; !hasWhen {
-			u.Schedule(1, 0, 0) // Anytime
-		}
-	}
-	if v, ok := opts["project"]; ok && v != "" {
-		u.Project(v)
-		// When adding a project, also move out of Inbox (st=0 → st=1)
-		if _, hasWhen := opts["when"]; !hasWhen {
-			u.Schedule(1, 0, 0) // Anytime
-		}
-	}
-	if v, ok := opts["heading"]; ok && v != "" {
-		u.Heading(v)
-		// When adding a heading, also move out of Inbox (st=0 → st=1)
-		if _, hasWhen := opts["when"]; !hasWhen {
-			u.Schedule(1, 0, 0) // Anytime
-		}
-	}
-	if v, ok := opts["tags"]; ok && v != "" {
-		u.Tags(strings.Split(v, ","))
-	}
-
-	env := writeEnvelope{id: taskUUID, action: 1, kind: "Task6", payload: u.build()}
-	if err := history.Write(env); err != nil {
+	if _, err := history.Write(newTaskUpdate(taskUUID, opts)); err != nil {
 		fatal("edit task", err)
 	}
 
@@ -798,11 +479,7 @@ func cmdEdit(history *thingscloud.History, taskUUID string, args []string) {
 }
 
 func cmdComplete(history *thingscloud.History, taskUUID string) {
-	ts := nowTs()
-	u := newTaskUpdate().Status(3).StopDate(ts)
-
-	env := writeEnvelope{id: taskUUID, action: 1, kind: "Task6", payload: u.build()}
-	if err := history.Write(env); err != nil {
+	if _, err := history.Write(write.UpdateTask(taskUUID).Complete()); err != nil {
 		fatal("complete task", err)
 	}
 
@@ -810,10 +487,7 @@ func cmdComplete(history *thingscloud.History, taskUUID string) {
 }
 
 func cmdTrash(history *thingscloud.History, taskUUID string) {
-	u := newTaskUpdate().Trash(true)
-
-	env := writeEnvelope{id: taskUUID, action: 1, kind: "Task6", payload: u.build()}
-	if err := history.Write(env); err != nil {
+	if _, err := history.Write(write.UpdateTask(taskUUID).Trash(true)); err != nil {
 		fatal("trash task", err)
 	}
 
@@ -821,14 +495,7 @@ func cmdTrash(history *thingscloud.History, taskUUID string) {
 }
 
 func cmdPurge(history *thingscloud.History, taskUUID string) {
-	tombstoneUUID := generateUUID()
-	payload := map[string]any{
-		"dloid": taskUUID,
-		"dld":   nowTs(),
-	}
-
-	env := writeEnvelope{id: tombstoneUUID, action: 0, kind: "Tombstone2", payload: payload}
-	if err := history.Write(env); err != nil {
+	if _, err := history.Write(write.Tombstone(taskUUID)); err != nil {
 		fatal("purge task", err)
 	}
 
@@ -836,11 +503,7 @@ func cmdPurge(history *thingscloud.History, taskUUID string) {
 }
 
 func cmdMoveToToday(history *thingscloud.History, taskUUID string) {
-	today := todayMidnightUTC()
-	u := newTaskUpdate().Schedule(1, today, today)
-
-	env := writeEnvelope{id: taskUUID, action: 1, kind: "Task6", payload: u.build()}
-	if err := history.Write(env); err != nil {
+	if _, err := history.Write(write.UpdateTask(taskUUID).Today()); err != nil {
 		fatal("move to today", err)
 	}
 
@@ -853,29 +516,19 @@ func cmdCreateArea(history *thingscloud.History, args []string) {
 	title := args[0]
 	opts := parseArgs(args[1:])
 
-	areaUUID := opts["uuid"]
-	if areaUUID == "" {
-		areaUUID = generateUUID()
+	area := write.NewArea(title)
+	if v := opts["uuid"]; v != "" {
+		area.WithID(v)
 	}
-
-	tg := []string{}
 	if v, ok := opts["tags"]; ok && v != "" {
-		tg = strings.Split(v, ",")
+		area.WithTags(strings.Split(v, ",")...)
 	}
 
-	payload := map[string]any{
-		"tt": title,
-		"ix": 0,
-		"tg": tg,
-		"xx": defaultExtension(),
-	}
-
-	env := writeEnvelope{id: areaUUID, action: 0, kind: "Area3", payload: payload}
-	if err := history.Write(env); err != nil {
+	if _, err := history.Write(area); err != nil {
 		fatal("create area", err)
 	}
 
-	outputJSON(map[string]string{"status": "created", "uuid": areaUUID, "title": title})
+	outputJSON(map[string]string{"status": "created", "uuid": area.UUID(), "title": title})
 }
 
 func cmdCreateTag(history *thingscloud.History, args []string) {
@@ -884,36 +537,22 @@ func cmdCreateTag(history *thingscloud.History, args []string) {
 	title := args[0]
 	opts := parseArgs(args[1:])
 
-	tagUUID := opts["uuid"]
-	if tagUUID == "" {
-		tagUUID = generateUUID()
+	tag := write.NewTag(title)
+	if v := opts["uuid"]; v != "" {
+		tag.WithID(v)
 	}
-
-	// Per HAR: ix is negative, sh is null on create, pn is []
-	var sh *string
 	if v, ok := opts["shorthand"]; ok {
-		sh = &v
+		tag.Shorthand(v)
 	}
-
-	pn := []string{}
 	if v, ok := opts["parent"]; ok && v != "" {
-		pn = []string{v}
+		tag.Parent(v)
 	}
 
-	payload := TagCreatePayload{
-		Tt: title,
-		Ix: -1237, // Things uses negative indices
-		Sh: sh,
-		Pn: pn,
-		Xx: defaultExtension(),
-	}
-
-	env := writeEnvelope{id: tagUUID, action: 0, kind: "Tag4", payload: payload}
-	if err := history.Write(env); err != nil {
+	if _, err := history.Write(tag); err != nil {
 		fatal("create tag", err)
 	}
 
-	outputJSON(map[string]string{"status": "created", "uuid": tagUUID, "title": title})
+	outputJSON(map[string]string{"status": "created", "uuid": tag.UUID(), "title": title})
 }
 
 // ---------------------------------------------------------------------------
@@ -960,8 +599,18 @@ func cmdBatch(history *thingscloud.History) {
 		results = append(results, result)
 	}
 
-	// Send all in one request
-	if err := history.Write(envelopes...); err != nil {
+	// Send in as few commits as the batch policy allows
+	commits, err := history.WriteBatched(envelopes, thingscloud.DefaultBatchPolicy())
+	if err != nil {
+		var batchErr *thingscloud.BatchError
+		if errors.As(err, &batchErr) && len(batchErr.Committed) > 0 {
+			outputJSON(map[string]any{
+				"status":  "partial",
+				"commits": commitReports(batchErr.Committed),
+				"pending": len(batchErr.Pending),
+				"error":   batchErr.Err.Error(),
+			})
+		}
 		fatal("batch write", err)
 	}
 
@@ -969,9 +618,25 @@ func cmdBatch(history *thingscloud.History) {
 		"status":     "ok",
 		"operations": len(envelopes),
 		"results":    results,
+		"commits":    commitReports(commits),
 	})
 }
 
+// commitReports summarises commit results for JSON output
+func commitReports(commits []*thingscloud.CommitResult) []map[string]any {
+	reports := make([]map[string]any, len(commits))
+	for i, res := range commits {
+		reports[i] = map[string]any{
+			"ancestorIndex":   res.AncestorIndex,
+			"serverHeadIndex": res.ServerHeadIndex,
+			"items":           res.Count,
+			"uuids":           res.UUIDs,
+			"latencyMs":       res.Latency.Milliseconds(),
+		}
+	}
+	return reports
+}
+
 func buildBatchEnvelope(op BatchOp) (thingscloud.Identifiable, map[string]string, error) {
 	switch op.Cmd {
 	case "create":
@@ -995,50 +660,37 @@ func buildBatchEnvelope(op BatchOp) (thingscloud.Identifiable, map[string]string
 	}
 }
 
-func buildBatchCreate(op BatchOp) (thingscloud.Identifiable, map[string]string, error) {
-	if op.Title == "" {
-		return nil, nil, fmt.Errorf("create requires title")
-	}
-
-	taskUUID := op.UUID
-	if taskUUID == "" {
-		taskUUID = generateUUID()
-	}
-
-	// Convert BatchOp to opts map for newTaskCreatePayload
+// batchOpts converts a BatchOp to the options of the create and edit commands
+func batchOpts(op BatchOp) map[string]string {
 	opts := make(map[string]string)
-	if op.Note != "" {
-		opts["note"] = op.Note
-	}
-	if op.When != "" {
-		opts["when"] = op.When
-	}
-	if op.Deadline != "" {
-		opts["deadline"] = op.Deadline
-	}
-	if op.Project != "" {
-		opts["project"] = op.Project
-	}
-	if op.Area != "" {
-		opts["area"] = op.Area
-	}
-	if op.Heading != "" {
-		opts["heading"] = op.Heading
-	}
-	if len(op.Tags) > 0 {
-		opts["tags"] = strings.Join(op.Tags, ",")
-	}
-	if op.Type != "" {
-		opts["type"] = op.Type
-	}
+	set := func(key, value string) {
+		if value != "" {
+			opts[key] = value
+		}
+	}
+	set("uuid", op.UUID)
+	set("title", op.Title)
+	set("note", op.Note)
+	set("when", op.When)
+	set("deadline", op.Deadline)
+	set("project", op.Project)
+	set("area", op.Area)
+	set("heading", op.Heading)
+	set("tags", strings.Join(op.Tags, ","))
+	set("type", op.Type)
 	for k, v := range op.Extra {
 		opts[k] = v
 	}
+	return opts
+}
 
-	payload := newTaskCreatePayload(op.Title, opts)
-	env := writeEnvelope{id: taskUUID, action: 0, kind: "Task6", payload: payload}
+func buildBatchCreate(op BatchOp) (thingscloud.Identifiable, map[string]string, error) {
+	if op.Title == "" {
+		return nil, nil, fmt.Errorf("create requires title")
+	}
 
-	return env, map[string]string{"cmd": "create", "uuid": taskUUID, "title": op.Title}, nil
+	task := newTaskBuilder(op.Title, batchOpts(op))
+	return task, map[string]string{"cmd": "create", "uuid": task.UUID(), "title": op.Title}, nil
 }
 
 func buildBatchComplete(op BatchOp) (thingscloud.Identifiable, map[string]string, error) {
@@ -1046,11 +698,7 @@ func buildBatchComplete(op BatchOp) (thingscloud.Identifiable, map[string]string
 		return nil, nil, fmt.Errorf("complete requires uuid")
 	}
 
-	ts := nowTs()
-	u := newTaskUpdate().Status(3).StopDate(ts)
-	env := writeEnvelope{id: op.UUID, action: 1, kind: "Task6", payload: u.build()}
-
-	return env, map[string]string{"cmd": "complete", "uuid": op.UUID}, nil
+	return write.UpdateTask(op.UUID).Complete(), map[string]string{"cmd": "complete", "uuid": op.UUID}, nil
 }
 
 func buildBatchTrash(op BatchOp) (thingscloud.Identifiable, map[string]string, error) {
@@ -1058,10 +706,7 @@ func buildBatchTrash(op BatchOp) (thingscloud.Identifiable, map[string]string, e
 		return nil, nil, fmt.Errorf("trash requires uuid")
 	}
 
-	u := newTaskUpdate().Trash(true)
-	env := writeEnvelope{id: op.UUID, action: 1, kind: "Task6", payload: u.build()}
-
-	return env, map[string]string{"cmd": "trash", "uuid": op.UUID}, nil
+	return write.UpdateTask(op.UUID).Trash(true), map[string]string{"cmd": "trash", "uuid": op.UUID}, nil
 }
 
 func buildBatchPurge(op BatchOp) (thingscloud.Identifiable, map[string]string, error) {
@@ -1069,14 +714,8 @@ func buildBatchPurge(op BatchOp) (thingscloud.Identifiable, map[string]string, e
 		return nil, nil, fmt.Errorf("purge requires uuid")
 	}
 
-	tombstoneUUID := generateUUID()
-	payload := map[string]any{
-		"dloid": op.UUID,
-		"dld":   nowTs(),
-	}
-	env := writeEnvelope{id: tombstoneUUID, action: 0, kind: "Tombstone2", payload: payload}
-
-	return env, map[string]string{"cmd": "purge", "uuid": op.UUID, "tombstone": tombstoneUUID}, nil
+	tombstone := write.Tombstone(op.UUID)
+	return tombstone, map[string]string{"cmd": "purge", "uuid": op.UUID, "tombstone": tombstone.UUID()}, nil
 }
 
 func buildBatchMoveToToday(op BatchOp) (thingscloud.Identifiable, map[string]string, error) {
@@ -1084,11 +723,7 @@ func buildBatchMoveToToday(op BatchOp) (thingscloud.Identifiable, map[string]str
 		return nil, nil, fmt.Errorf("move-to-today requires uuid")
 	}
 
-	today := todayMidnightUTC()
-	u := newTaskUpdate().Schedule(1, today, today)
-	env := writeEnvelope{id: op.UUID, action: 1, kind: "Task6", payload: u.build()}
-
-	return env, map[string]string{"cmd": "move-to-today", "uuid": op.UUID}, nil
+	return write.UpdateTask(op.UUID).Today(), map[string]string{"cmd": "move-to-today", "uuid": op.UUID}, nil
 }
 
 func buildBatchMoveToProject(op BatchOp) (thingscloud.Identifiable, map[string]string, error) {
@@ -1099,10 +734,8 @@ func buildBatchMoveToProject(op BatchOp) (thingscloud.Identifiable, map[string]s
 		return nil, nil, fmt.Errorf("move-to-project requires project")
 	}
 
-	u := newTaskUpdate().Project(op.Project).Schedule(1, 0, 0)
-	env := writeEnvelope{id: op.UUID, action: 1, kind: "Task6", payload: u.build()}
-
-	return env, map[string]string{"cmd": "move-to-project", "uuid": op.UUID, "project": op.Project}, nil
+	u := write.UpdateTask(op.UUID).InProject(op.Project)
+	return u, map[string]string{"cmd": "move-to-project", "uuid": op.UUID, "project": op.Project}, nil
 }
 
 func buildBatchMoveToArea(op BatchOp) (thingscloud.Identifiable, map[string]string, error) {
@@ -1113,10 +746,8 @@ func buildBatchMoveToArea(op BatchOp) (thingscloud.Identifiable, map[string]stri
 		return nil, nil, fmt.Errorf("move-to-area requires area")
 	}
 
-	u := newTaskUpdate().Area(op.Area).Schedule(1, 0, 0)
-	env := writeEnvelope{id: op.UUID, action: 1, kind: "Task6", payload: u.build()}
-
-	return env, map[string]string{"cmd": "move-to-area", "uuid": op.UUID, "area": op.Area}, nil
+	u := write.UpdateTask(op.UUID).InArea(op.Area)
+	return u, map[string]string{"cmd": "move-to-area", "uuid": op.UUID, "area": op.Area}, nil
 }
 
 func buildBatchEdit(op BatchOp) (thingscloud.Identifiable, map[string]string, error) {
@@ -1124,57 +755,10 @@ func buildBatchEdit(op BatchOp) (thingscloud.Identifiable, map[string]string, er
 		return nil, nil, fmt.Errorf("edit requires uuid")
 	}
 
-	u := newTaskUpdate()
-
-	if op.Title != "" {
-		u.Title(op.Title)
-	}
-	if op.Note != "" {
-		u.Note(op.Note)
-	}
-	if op.When != "" {
-		switch op.When {
-		case "today":
-			today := todayMidnightUTC()
-			u.Schedule(1, today, today)
-		case "anytime":
-			u.Schedule(1, nil, nil)
-		case "someday":
-			u.Schedule(2, nil, nil)
-		case "inbox":
-			u.Schedule(0, nil, nil)
-		}
-	}
-	if op.Deadline != "" {
-		if t := parseDate(op.Deadline); t != nil {
-			u.Deadline(t.Unix())
-		}
-	}
-	if op.Project != "" {
-		u.Project(op.Project)
-		if op.When == "" {
-			u.Schedule(1, 0, 0)
-		}
-	}
-	if op.Area != "" {
-		u.Area(op.Area)
-		if op.When == "" {
-			u.Schedule(1, 0, 0)
-		}
-	}
-	if op.Heading != "" {
-		u.Heading(op.Heading)
-		if op.When == "" {
-			u.Schedule(1, 0, 0)
-		}
-	}
-	if len(op.Tags) > 0 {
-		u.Tags(op.Tags)
-	}
-
-	env := writeEnvelope{id: op.UUID, action: 1, kind: "Task6", payload: u.build()}
-
-	return env, map[string]string{"cmd": "edit", "uuid": op.UUID}, nil
+	opts := batchOpts(op)
+	delete(opts, "uuid")
+	delete(opts, "type")
+	return newTaskUpdate(op.UUID, opts), map[string]string{"cmd": "edit", "uuid": op.UUID}, nil
 }
 
 // ---------------------------------------------------------------------------
@@ -1190,6 +774,8 @@ Read commands (load state from cloud):
   areas
   projects
   tags
+  drift [--text]     report payload fields and item kinds the SDK doesn't decode
+                     (exits with 3 if there are any)
 
 Write commands (fast — skip state loading):
   create "Title" [--note ...] [--when today|anytime|someday|inbox]
@@ -1208,7 +794,7 @@ Write commands (fast — skip state loading):
   purge <uuid>
   move-to-today <uuid>
 
-Batch command (reads JSON from stdin, sends all ops in one HTTP request):
+Batch command (reads JSON from stdin, sends ops in as few commits as possible):
   batch
 
   Example: echo '[{"cmd":"complete","uuid":"abc"},{"cmd":"trash","uuid":"def"}]' | things-cli batch
@@ -1222,7 +808,11 @@ Batch command (reads JSON from stdin, sends all ops in one HTTP request):
     {"cmd": "move-to-today", "uuid": "..."}
     {"cmd": "move-to-project", "uuid": "...", "project": "..."}
     {"cmd": "move-to-area", "uuid": "...", "area": "..."}
-    {"cmd": "edit", "uuid": "...", "title": "...", "note": "...", ...}`)
+    {"cmd": "edit", "uuid": "...", "title": "...", "note": "...", ...}
+
+Credentials are read from THINGS_CREDENTIALS_FILE (JSON or TOML, mode 0600),
+THINGS_KEYRING_FILE with THINGS_KEYRING_PASSPHRASE, a netrc entry for
+cloud.culturedcode.com, or THINGS_USERNAME and THINGS_PASSWORD.`)
 }
 
 func main() {
@@ -1231,8 +821,12 @@ func main() {
 		os.Exit(1)
 	}
 
-	ctx := initCLI()
 	cmd := os.Args[1]
+	var opts []thingscloud.Option
+	if cmd == "drift" {
+		opts = append(opts, thingscloud.WithStrictDecoding())
+	}
+	ctx := initCLI(opts...)
 
 	switch cmd {
 	// Read commands — need state
@@ -1247,6 +841,8 @@ func main() {
 		cmdProjects(ctx.loadState())
 	case "tags":
 		cmdTags(ctx.loadState())
+	case "drift":
+		cmdDrift(ctx, os.Args[2:])
 
 	// Write commands — skip state loading
 	case "create":
@@ -370,7 +370,7 @@ func buildRichChanges(changes []sync.Change, syncer *sync.Syncer) []RichChange {
 				rich.Context = getTaskContext(v.Task, syncer)
 				rich.To = &LocationInfo{Location: "upcoming"}
 				if v.Task.ScheduledDate != nil {
-					rich.Date = v.Task.ScheduledDate.Format("2006-01-02")
+					rich.Date = v.Task.ScheduledDate.String()
 					rich.To.Date = rich.Date
 				}
 			}
@@ -557,12 +557,12 @@ func taskToInfo(t *things.Task, syncer *sync.Syncer, when string) TaskInfo {
 
 	// Deadline
 	if t.DeadlineDate != nil && !t.DeadlineDate.IsZero() {
-		info.Deadline = t.DeadlineDate.Format("2006-01-02")
+		info.Deadline = t.DeadlineDate.String()
 	}
 
 	// Scheduled date
 	if t.ScheduledDate != nil && !t.ScheduledDate.IsZero() {
-		info.ScheduledDate = t.ScheduledDate.Format("2006-01-02")
+		info.ScheduledDate = t.ScheduledDate.String()
 	}
 
 	// Tags
@@ -878,7 +878,8 @@ func printInboxView(syncer *sync.Syncer) {
 
 func printReviewView(syncer *sync.Syncer) {
 	state := syncer.State()
-	todayStart := time.Now().Truncate(24 * time.Hour)
+	loc := syncer.Location()
+	todayStart := things.Today(loc).Time(loc)
 	
 	// Get completed tasks today
 	changes, _ := syncer.ChangesSince(todayStart)
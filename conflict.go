@@ -0,0 +1,113 @@
+package thingscloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrConflict is matched by errors.Is when a commit was rejected because another
+// device committed after the ancestor index the commit was based on
+var ErrConflict = errors.New("conflict")
+
+// maxRebaseAttempts limits how often WriteWithRebase retries a conflicting commit
+const maxRebaseAttempts = 5
+
+// ConflictError is returned by History.Write when the server rejected a commit
+// because its ancestor index is no longer the server head
+type ConflictError struct {
+	// AncestorIndex is the index the rejected commit was based on
+	AncestorIndex int
+	// ServerHeadIndex is the current server head, or -1 if it could not be determined
+	ServerHeadIndex int
+
+	apiErr *APIError
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("commit conflict: ancestor index %d, server head %d", e.AncestorIndex, e.ServerHeadIndex)
+}
+
+// Is makes errors.Is(err, ErrConflict) match
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// Unwrap returns the underlying APIError
+func (e *ConflictError) Unwrap() error {
+	return e.apiErr
+}
+
+// conflictError builds a ConflictError for a rejected commit. The server head is taken
+// from the response if present, and looked up otherwise.
+func (h *History) conflictError(ctx context.Context, apiErr *APIError) *ConflictError {
+	e := &ConflictError{
+		AncestorIndex:   h.LatestServerIndex,
+		ServerHeadIndex: -1,
+		apiErr:          apiErr,
+	}
+	var w commitResponse
+	if err := json.Unmarshal([]byte(apiErr.Body), &w); err == nil && w.ServerHeadIndex > 0 {
+		e.ServerHeadIndex = w.ServerHeadIndex
+		return e
+	}
+	if latest, err := h.Client.HistoryContext(ctx, h.ID); err == nil {
+		e.ServerHeadIndex = latest.LatestServerIndex
+	}
+	return e
+}
+
+// MergeFunc is called by WriteWithRebase after a conflict. It receives the items other
+// devices committed since the attempted ancestor index, and the items which failed to
+// commit. It returns the items to commit on top of the new server head; returning no
+// items abandons the write without error, returning an error aborts it.
+type MergeFunc func(intervening []Item, pending []Identifiable) ([]Identifiable, error)
+
+// WriteWithRebase is like Write, but resolves conflicts instead of failing: the items
+// committed in between are fetched, passed to merge together with the pending items,
+// and the merged items are committed against the new server head. If the merge
+// abandons the write, the result is nil. If every attempt conflicts, the last
+// *ConflictError is returned.
+func (h *History) WriteWithRebase(merge MergeFunc, items ...Identifiable) (*CommitResult, error) {
+	return h.WriteWithRebaseContext(context.Background(), merge, items...)
+}
+
+// WriteWithRebaseContext is like WriteWithRebase but carries a context for cancellation and deadlines
+func (h *History) WriteWithRebaseContext(ctx context.Context, merge MergeFunc, items ...Identifiable) (*CommitResult, error) {
+	var conflict *ConflictError
+	for attempt := 0; attempt < maxRebaseAttempts; attempt++ {
+		res, err := h.WriteContext(ctx, items...)
+		if !errors.As(err, &conflict) {
+			return res, err
+		}
+
+		intervening, head, ferr := h.itemsBetween(ctx, conflict.AncestorIndex)
+		if ferr != nil {
+			return nil, fmt.Errorf("fetching intervening items: %w", ferr)
+		}
+		items, err = merge(intervening, items)
+		if err != nil {
+			return nil, err
+		}
+		h.LatestServerIndex = head
+		if len(items) == 0 {
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("rebase attempts exhausted: %w", conflict)
+}
+
+// itemsBetween fetches all items committed after ancestor and returns them with the
+// server head they lead up to. The receiver's paging state is left untouched.
+func (h *History) itemsBetween(ctx context.Context, ancestor int) ([]Item, int, error) {
+	tmp := &History{ID: h.ID, Client: h.Client}
+	var all []Item
+	for item, err := range tmp.All(ctx, ancestor) {
+		if err != nil {
+			return nil, 0, err
+		}
+		all = append(all, item)
+	}
+	return all, tmp.LatestServerIndex, nil
+}
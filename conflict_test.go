@@ -0,0 +1,135 @@
+package thingscloud_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	thingscloud "github.com/arthursoares/things-cloud-sdk"
+	"github.com/arthursoares/things-cloud-sdk/thingscloudtest"
+)
+
+func newTask(uuid, title string) thingscloud.TaskActionItem {
+	return thingscloud.TaskActionItem{
+		Item: thingscloud.Item{UUID: uuid, Kind: thingscloud.ItemKindTask, Action: thingscloud.ItemActionCreated},
+		P:    thingscloud.TaskActionItemPayload{Title: thingscloud.String(title)},
+	}
+}
+
+func seedTask(t *testing.T, s *thingscloudtest.Server, uuid, title string) {
+	t.Helper()
+	p, _ := json.Marshal(thingscloud.TaskActionItemPayload{Title: thingscloud.String(title)})
+	if _, err := s.Seed(s.OwnHistoryKey(), thingscloud.Item{UUID: uuid, Kind: thingscloud.ItemKindTask, P: p}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHistory_WriteConflict(t *testing.T) {
+	t.Parallel()
+	s := thingscloudtest.NewServer("martin@example.com", "secret")
+	defer s.Close()
+
+	h := s.Client(thingscloud.WithoutValidation()).HistoryWithID(s.OwnHistoryKey())
+	seedTask(t, s, "other-1", "from another device")
+	seedTask(t, s, "other-2", "from another device")
+
+	_, err := h.Write(newTask("mine", "mine"))
+	if !errors.Is(err, thingscloud.ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+	var conflict *thingscloud.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected *ConflictError, got %T", err)
+	}
+	if conflict.AncestorIndex != 0 || conflict.ServerHeadIndex != 2 {
+		t.Errorf("Expected ancestor 0 and head 2, got %d and %d", conflict.AncestorIndex, conflict.ServerHeadIndex)
+	}
+	if !thingscloud.IsConflict(err) {
+		t.Error("Expected IsConflict to match")
+	}
+	if h.LatestServerIndex != 0 {
+		t.Errorf("Expected LatestServerIndex to be unchanged, got %d", h.LatestServerIndex)
+	}
+}
+
+func TestHistory_WriteWithRebase(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Rebases", func(t *testing.T) {
+		t.Parallel()
+		s := thingscloudtest.NewServer("martin@example.com", "secret")
+		defer s.Close()
+
+		h := s.Client(thingscloud.WithoutValidation()).HistoryWithID(s.OwnHistoryKey())
+		seedTask(t, s, "other-1", "from another device")
+		seedTask(t, s, "other-2", "from another device")
+
+		var seen []thingscloud.Item
+		_, err := h.WriteWithRebase(func(intervening []thingscloud.Item, pending []thingscloud.Identifiable) ([]thingscloud.Identifiable, error) {
+			seen = intervening
+			return pending, nil
+		}, newTask("mine", "mine"))
+		if err != nil {
+			t.Fatalf("Expected rebased write to succeed, got %v", err)
+		}
+		if len(seen) != 2 || seen[0].UUID != "other-1" || seen[1].UUID != "other-2" {
+			t.Errorf("Expected merge to see both intervening items, got %#v", seen)
+		}
+		if h.LatestServerIndex != 3 {
+			t.Errorf("Expected LatestServerIndex of 3, got %d", h.LatestServerIndex)
+		}
+		if commits := s.Commits(s.OwnHistoryKey()); len(commits) != 3 || commits[2]["mine"] == nil {
+			t.Errorf("Expected pending item to be committed last, got %v", commits)
+		}
+	})
+
+	t.Run("Exhausted", func(t *testing.T) {
+		t.Parallel()
+		s := thingscloudtest.NewServer("martin@example.com", "secret")
+		defer s.Close()
+
+		h := s.Client(thingscloud.WithoutValidation()).HistoryWithID(s.OwnHistoryKey())
+		seedTask(t, s, "other-0", "from another device")
+
+		// another device commits again before every retry
+		merges := 0
+		_, err := h.WriteWithRebase(func(_ []thingscloud.Item, pending []thingscloud.Identifiable) ([]thingscloud.Identifiable, error) {
+			merges++
+			seedTask(t, s, fmt.Sprintf("other-%d", merges), "from another device")
+			return pending, nil
+		}, newTask("mine", "mine"))
+		var conflict *thingscloud.ConflictError
+		if !errors.As(err, &conflict) {
+			t.Fatalf("Expected *ConflictError after the last attempt, got %v", err)
+		}
+		if merges != 5 {
+			t.Errorf("Expected 5 merges, got %d", merges)
+		}
+		for _, c := range s.Commits(s.OwnHistoryKey()) {
+			if c["mine"] != nil {
+				t.Errorf("Expected the pending item not to be committed")
+			}
+		}
+	})
+
+	t.Run("MergeAborts", func(t *testing.T) {
+		t.Parallel()
+		s := thingscloudtest.NewServer("martin@example.com", "secret")
+		defer s.Close()
+
+		h := s.Client(thingscloud.WithoutValidation()).HistoryWithID(s.OwnHistoryKey())
+		seedTask(t, s, "other-1", "from another device")
+
+		abort := errors.New("abort")
+		_, err := h.WriteWithRebase(func([]thingscloud.Item, []thingscloud.Identifiable) ([]thingscloud.Identifiable, error) {
+			return nil, abort
+		}, newTask("mine", "mine"))
+		if !errors.Is(err, abort) {
+			t.Fatalf("Expected merge error, got %v", err)
+		}
+		if commits := s.Commits(s.OwnHistoryKey()); len(commits) != 1 {
+			t.Errorf("Expected nothing to be committed, got %d commits", len(commits))
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package thingscloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Credentials identify a thingscloud account
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// CredentialProvider supplies the credentials of a client. Clients created with
+// NewWithCredentialProvider look the password up whenever a request needs it,
+// instead of keeping it in memory.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialStore is a CredentialProvider which can persist changed credentials.
+// AccountService.ChangePassword rotates the password through it.
+type CredentialStore interface {
+	CredentialProvider
+	StoreCredentials(ctx context.Context, creds Credentials) error
+}
+
+// ErrNoCredentials is returned by providers which have no credentials configured
+var ErrNoCredentials = errors.New("no credentials found")
+
+// EnvCredentials reads the credentials from environment variables
+type EnvCredentials struct {
+	EmailVar    string
+	PasswordVar string
+}
+
+// NewEnvCredentials returns a provider reading THINGS_USERNAME and THINGS_PASSWORD
+func NewEnvCredentials() EnvCredentials {
+	return EnvCredentials{EmailVar: "THINGS_USERNAME", PasswordVar: "THINGS_PASSWORD"}
+}
+
+// Credentials implements CredentialProvider
+func (e EnvCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	creds := Credentials{Email: os.Getenv(e.EmailVar), Password: os.Getenv(e.PasswordVar)}
+	if creds.Email == "" || creds.Password == "" {
+		return Credentials{}, fmt.Errorf("%w: %s and %s must be set", ErrNoCredentials, e.EmailVar, e.PasswordVar)
+	}
+	return creds, nil
+}
+
+// NewWithCredentialProvider initializes a things client which takes its credentials
+// from p. The account email is looked up once, the password on every request which needs it.
+func NewWithCredentialProvider(endpoint string, p CredentialProvider, opts ...Option) (*Client, error) {
+	creds, err := p.Credentials(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	c := NewWithOptions(endpoint, creds.Email, "", opts...)
+	c.credentials = p
+	return c, nil
+}
+
+// authorization returns the Authorization header for account level requests
+func (c *Client) authorization(ctx context.Context) (string, error) {
+	password := c.password
+	if c.credentials != nil {
+		creds, err := c.credentials.Credentials(ctx)
+		if err != nil {
+			return "", fmt.Errorf("looking up credentials: %w", err)
+		}
+		password = creds.Password
+	}
+	return fmt.Sprintf("Password %s", password), nil
+}
@@ -0,0 +1,181 @@
+package thingscloud
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ErrInsecurePermissions is returned when a credential file is readable by group or others
+var ErrInsecurePermissions = errors.New("credential file must not be accessible by group or others (chmod 600)")
+
+// FileCredentials reads credentials from a JSON file, or a TOML file if the path ends
+// in .toml. Both hold an email and a password:
+//
+//	{"email": "me@example.com", "password": "secret"}
+//
+//	email = "me@example.com"
+//	password = "secret"
+//
+// The file must have mode 0600 or stricter. StoreCredentials rewrites it atomically.
+type FileCredentials struct {
+	Path string
+}
+
+// NewFileCredentials returns a provider backed by the file at path
+func NewFileCredentials(path string) *FileCredentials {
+	return &FileCredentials{Path: path}
+}
+
+func (f *FileCredentials) isTOML() bool {
+	return strings.EqualFold(filepath.Ext(f.Path), ".toml")
+}
+
+// Credentials implements CredentialProvider
+func (f *FileCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	bs, err := readPrivateFile(f.Path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	var creds Credentials
+	if f.isTOML() {
+		creds, err = parseTOMLCredentials(bs)
+	} else {
+		err = json.Unmarshal(bs, &creds)
+	}
+	if err != nil {
+		return Credentials{}, fmt.Errorf("parsing %s: %w", f.Path, err)
+	}
+	if creds.Email == "" || creds.Password == "" {
+		return Credentials{}, fmt.Errorf("%w in %s", ErrNoCredentials, f.Path)
+	}
+	return creds, nil
+}
+
+// StoreCredentials implements CredentialStore
+func (f *FileCredentials) StoreCredentials(ctx context.Context, creds Credentials) error {
+	var bs []byte
+	if f.isTOML() {
+		bs = []byte(fmt.Sprintf("email = %s\npassword = %s\n", tomlQuote(creds.Email), tomlQuote(creds.Password)))
+	} else {
+		var err error
+		if bs, err = json.MarshalIndent(creds, "", "  "); err != nil {
+			return err
+		}
+		bs = append(bs, '\n')
+	}
+	return writePrivateFile(f.Path, bs)
+}
+
+// readPrivateFile reads a file holding secrets, refusing it if others may read it too
+func readPrivateFile(path string) ([]byte, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0o077 != 0 {
+		return nil, fmt.Errorf("%s: %w", path, ErrInsecurePermissions)
+	}
+	return os.ReadFile(path)
+}
+
+// writePrivateFile replaces path with a 0600 file holding bs
+func writePrivateFile(path string, bs []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// parseTOMLCredentials understands the subset of TOML needed for a credential file:
+// comments and top level string keys
+func parseTOMLCredentials(bs []byte) (Credentials, error) {
+	var creds Credentials
+	sc := bufio.NewScanner(bytes.NewReader(bs))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return creds, fmt.Errorf("line %d: expected key = value", n)
+		}
+		s, err := tomlString(strings.TrimSpace(value))
+		if err != nil {
+			return creds, fmt.Errorf("line %d: %w", n, err)
+		}
+		switch strings.Trim(strings.TrimSpace(key), `"`) {
+		case "email":
+			creds.Email = s
+		case "password":
+			creds.Password = s
+		}
+	}
+	return creds, sc.Err()
+}
+
+// tomlString decodes a basic ("...") or literal ('...') TOML string, followed by an
+// optional comment
+func tomlString(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, "'"):
+		end := strings.Index(v[1:], "'")
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		return v[1 : end+1], nil
+	case strings.HasPrefix(v, `"`):
+		for i := 1; i < len(v); i++ {
+			if v[i] == '\\' {
+				i++
+				continue
+			}
+			if v[i] == '"' {
+				return strconv.Unquote(v[:i+1])
+			}
+		}
+		return "", errors.New("unterminated string")
+	}
+	return "", fmt.Errorf("expected a string, got %q", v)
+}
+
+// tomlQuote encodes s as a basic TOML string
+func tomlQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,152 @@
+package thingscloud
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	keyringVersion = 1
+	keyringKDF     = "pbkdf2-sha256"
+	// DefaultKeyringIterations is the PBKDF2 work factor for new keyring files
+	DefaultKeyringIterations = 600000
+)
+
+// keyringAAD binds the ciphertext to the file format
+var keyringAAD = []byte("thingscloud keyring v1")
+
+// ErrKeyringPassphrase is returned when a keyring file can't be decrypted with the given passphrase
+var ErrKeyringPassphrase = errors.New("keyring: wrong passphrase or corrupted file")
+
+// KeyringFile keeps the credentials in a local file encrypted with AES-256-GCM. The key
+// is derived from a passphrase with PBKDF2, so the file can be kept on disk or in backups
+// without exposing the password. The file is written with mode 0600.
+//
+// Deriving the key is deliberately slow, so the decrypted credentials are cached
+// until the file or the passphrase changes.
+type KeyringFile struct {
+	Path       string
+	Passphrase string
+	// Iterations is the PBKDF2 work factor used when writing, DefaultKeyringIterations if 0
+	Iterations int
+
+	mu     sync.Mutex
+	cached keyringCache
+}
+
+// keyringCache holds the credentials last decrypted from or written to the file
+type keyringCache struct {
+	raw        []byte
+	passphrase string
+	creds      Credentials
+}
+
+type keyringData struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// NewKeyringFile returns a provider backed by the encrypted file at path
+func NewKeyringFile(path, passphrase string) *KeyringFile {
+	return &KeyringFile{Path: path, Passphrase: passphrase}
+}
+
+// Credentials implements CredentialProvider
+func (k *KeyringFile) Credentials(ctx context.Context) (Credentials, error) {
+	bs, err := readPrivateFile(k.Path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.cached.raw != nil && k.cached.passphrase == k.Passphrase && bytes.Equal(k.cached.raw, bs) {
+		return k.cached.creds, nil
+	}
+
+	var d keyringData
+	if err := json.Unmarshal(bs, &d); err != nil {
+		return Credentials{}, fmt.Errorf("parsing %s: %w", k.Path, err)
+	}
+	if d.Version != keyringVersion || d.KDF != keyringKDF {
+		return Credentials{}, fmt.Errorf("%s: unsupported keyring version %d (%s)", k.Path, d.Version, d.KDF)
+	}
+	aead, err := keyringCipher(k.Passphrase, d.Salt, d.Iterations)
+	if err != nil {
+		return Credentials{}, err
+	}
+	plain, err := aead.Open(nil, d.Nonce, d.Ciphertext, keyringAAD)
+	if err != nil {
+		return Credentials{}, ErrKeyringPassphrase
+	}
+	var creds Credentials
+	if err := json.Unmarshal(plain, &creds); err != nil {
+		return Credentials{}, fmt.Errorf("parsing %s: %w", k.Path, err)
+	}
+	k.cached = keyringCache{raw: bs, passphrase: k.Passphrase, creds: creds}
+	return creds, nil
+}
+
+// StoreCredentials implements CredentialStore. Every write uses a fresh salt and nonce.
+func (k *KeyringFile) StoreCredentials(ctx context.Context, creds Credentials) error {
+	if k.Passphrase == "" {
+		return errors.New("keyring: empty passphrase")
+	}
+	d := keyringData{
+		Version:    keyringVersion,
+		KDF:        keyringKDF,
+		Iterations: k.Iterations,
+		Salt:       make([]byte, 16),
+	}
+	if d.Iterations == 0 {
+		d.Iterations = DefaultKeyringIterations
+	}
+	rand.Read(d.Salt)
+	aead, err := keyringCipher(k.Passphrase, d.Salt, d.Iterations)
+	if err != nil {
+		return err
+	}
+	d.Nonce = make([]byte, aead.NonceSize())
+	rand.Read(d.Nonce)
+	plain, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	d.Ciphertext = aead.Seal(nil, d.Nonce, plain, keyringAAD)
+	bs, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	bs = append(bs, '\n')
+	if err := writePrivateFile(k.Path, bs); err != nil {
+		return err
+	}
+	k.mu.Lock()
+	k.cached = keyringCache{raw: bs, passphrase: k.Passphrase, creds: creds}
+	k.mu.Unlock()
+	return nil
+}
+
+func keyringCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,144 @@
+package thingscloud
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NetrcCredentials reads the credentials from a netrc file:
+//
+//	machine cloud.culturedcode.com login me@example.com password secret
+//
+// The file must have mode 0600 or stricter. It is read only, rotated passwords have to
+// be written to it by hand.
+type NetrcCredentials struct {
+	// Path defaults to $NETRC, or ~/.netrc
+	Path string
+	// Machine defaults to the host of APIEndpoint
+	Machine string
+}
+
+// NewNetrcCredentials returns a provider reading the entry for thingscloud from the
+// default netrc file
+func NewNetrcCredentials() *NetrcCredentials {
+	return &NetrcCredentials{}
+}
+
+func (n *NetrcCredentials) path() (string, error) {
+	if n.Path != "" {
+		return n.Path, nil
+	}
+	if p := os.Getenv("NETRC"); p != "" {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".netrc"), nil
+}
+
+func (n *NetrcCredentials) machine() string {
+	if n.Machine != "" {
+		return n.Machine
+	}
+	u, _ := url.Parse(APIEndpoint)
+	return u.Host
+}
+
+// Credentials implements CredentialProvider
+func (n *NetrcCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	path, err := n.path()
+	if err != nil {
+		return Credentials{}, err
+	}
+	bs, err := readPrivateFile(path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	creds, ok := parseNetrc(string(bs), n.machine())
+	if !ok || creds.Email == "" || creds.Password == "" {
+		return Credentials{}, fmt.Errorf("%w for %s in %s", ErrNoCredentials, n.machine(), path)
+	}
+	return creds, nil
+}
+
+// parseNetrc returns the login of machine, falling back to the default entry
+func parseNetrc(data, machine string) (Credentials, bool) {
+	var (
+		found, fallback       Credentials
+		hasFound, hasFallback bool
+		current               *Credentials
+	)
+	tokens := netrcTokens(data)
+	next := func(i *int) string {
+		if *i+1 < len(tokens) {
+			*i++
+			return tokens[*i]
+		}
+		return ""
+	}
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			current = nil
+			if next(&i) == machine && !hasFound {
+				current, hasFound = &found, true
+			}
+		case "default":
+			current = nil
+			if !hasFallback {
+				current, hasFallback = &fallback, true
+			}
+		case "login":
+			if v := next(&i); current != nil {
+				current.Email = v
+			}
+		case "password":
+			if v := next(&i); current != nil {
+				current.Password = v
+			}
+		case "account":
+			next(&i)
+		case "macdef":
+			current = nil
+			next(&i)
+		}
+	}
+	if hasFound {
+		return found, true
+	}
+	return fallback, hasFallback
+}
+
+// netrcTokens splits a netrc file into its whitespace separated tokens, across line
+// breaks. Comments and the bodies of macro definitions are left out.
+func netrcTokens(data string) []string {
+	var tokens []string
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		for j, tok := range fields {
+			if strings.HasPrefix(tok, "#") {
+				break
+			}
+			tokens = append(tokens, tok)
+			if tok == "macdef" {
+				// the name follows on the same line, the body runs until the next
+				// empty line
+				if j+1 < len(fields) {
+					tokens = append(tokens, fields[j+1])
+				}
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				break
+			}
+		}
+	}
+	return tokens
+}
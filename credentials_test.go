@@ -0,0 +1,178 @@
+package thingscloud_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	thingscloud "github.com/arthursoares/things-cloud-sdk"
+	"github.com/arthursoares/things-cloud-sdk/thingscloudtest"
+)
+
+func writeFile(t *testing.T, name, content string, perm os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCredentialProviders(t *testing.T) {
+	ctx := context.Background()
+	want := thingscloud.Credentials{Email: "martin@example.com", Password: `s3"cret`}
+
+	t.Run("Env", func(t *testing.T) {
+		t.Setenv("TEST_THINGS_USER", want.Email)
+		t.Setenv("TEST_THINGS_PASS", want.Password)
+		got, err := thingscloud.EnvCredentials{EmailVar: "TEST_THINGS_USER", PasswordVar: "TEST_THINGS_PASS"}.Credentials(ctx)
+		if err != nil || got != want {
+			t.Errorf("Expected %v, got %v (%v)", want, got, err)
+		}
+		_, err = thingscloud.EnvCredentials{EmailVar: "TEST_THINGS_UNSET", PasswordVar: "TEST_THINGS_PASS"}.Credentials(ctx)
+		if !errors.Is(err, thingscloud.ErrNoCredentials) {
+			t.Errorf("Expected ErrNoCredentials, got %v", err)
+		}
+	})
+
+	t.Run("JSONFile", func(t *testing.T) {
+		t.Parallel()
+		path := writeFile(t, "things.json", `{"email":"martin@example.com","password":"s3\"cret"}`, 0o600)
+		got, err := thingscloud.NewFileCredentials(path).Credentials(ctx)
+		if err != nil || got != want {
+			t.Errorf("Expected %v, got %v (%v)", want, got, err)
+		}
+	})
+
+	t.Run("TOMLFile", func(t *testing.T) {
+		t.Parallel()
+		path := writeFile(t, "things.toml", "# things\nemail = 'martin@example.com'\npassword = \"s3\\\"cret\" # rotated\n", 0o600)
+		f := thingscloud.NewFileCredentials(path)
+		got, err := f.Credentials(ctx)
+		if err != nil || got != want {
+			t.Errorf("Expected %v, got %v (%v)", want, got, err)
+		}
+
+		rotated := thingscloud.Credentials{Email: want.Email, Password: "new\\pass"}
+		if err := f.StoreCredentials(ctx, rotated); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := f.Credentials(ctx); err != nil || got != rotated {
+			t.Errorf("Expected stored %v, got %v (%v)", rotated, got, err)
+		}
+		if fi, _ := os.Stat(path); fi.Mode().Perm() != 0o600 {
+			t.Errorf("Expected mode 0600, got %v", fi.Mode().Perm())
+		}
+	})
+
+	t.Run("InsecureFile", func(t *testing.T) {
+		t.Parallel()
+		if runtime.GOOS == "windows" {
+			t.Skip("file modes are not enforced on windows")
+		}
+		path := writeFile(t, "things.json", `{"email":"martin@example.com","password":"secret"}`, 0o644)
+		_, err := thingscloud.NewFileCredentials(path).Credentials(ctx)
+		if !errors.Is(err, thingscloud.ErrInsecurePermissions) {
+			t.Errorf("Expected ErrInsecurePermissions, got %v", err)
+		}
+	})
+
+	t.Run("Netrc", func(t *testing.T) {
+		t.Parallel()
+		path := writeFile(t, "netrc", `machine example.com login other password nope
+macdef init
+machine cloud.culturedcode.com login trap password trap
+
+machine cloud.culturedcode.com
+  login martin@example.com
+  password s3"cret
+default login anonymous password guest
+`, 0o600)
+		got, err := (&thingscloud.NetrcCredentials{Path: path}).Credentials(ctx)
+		if err != nil || got != want {
+			t.Errorf("Expected %v, got %v (%v)", want, got, err)
+		}
+		got, err = (&thingscloud.NetrcCredentials{Path: path, Machine: "unknown.example.com"}).Credentials(ctx)
+		if err != nil || got.Email != "anonymous" {
+			t.Errorf("Expected the default entry, got %v (%v)", got, err)
+		}
+
+		// netrc is split at any whitespace, values may be on the next line
+		path = writeFile(t, "netrc-lines", "machine\ncloud.culturedcode.com login\n  martin@example.com\npassword\n\ts3\"cret\n", 0o600)
+		got, err = (&thingscloud.NetrcCredentials{Path: path}).Credentials(ctx)
+		if err != nil || got != want {
+			t.Errorf("Expected %v from values on their own lines, got %v (%v)", want, got, err)
+		}
+	})
+
+	t.Run("Keyring", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "things.keyring")
+		k := &thingscloud.KeyringFile{Path: path, Passphrase: "correct horse", Iterations: 1000}
+		if err := k.StoreCredentials(ctx, want); err != nil {
+			t.Fatal(err)
+		}
+		bs, _ := os.ReadFile(path)
+		if len(bs) == 0 || strings.Contains(string(bs), want.Password) {
+			t.Errorf("Expected the password to be encrypted, got %s", bs)
+		}
+		if got, err := k.Credentials(ctx); err != nil || got != want {
+			t.Errorf("Expected %v, got %v (%v)", want, got, err)
+		}
+		wrong := thingscloud.NewKeyringFile(path, "battery staple")
+		if _, err := wrong.Credentials(ctx); !errors.Is(err, thingscloud.ErrKeyringPassphrase) {
+			t.Errorf("Expected ErrKeyringPassphrase, got %v", err)
+		}
+		// the cached credentials are dropped once another writer rotates the password
+		rotated := thingscloud.Credentials{Email: want.Email, Password: "rotated"}
+		other := &thingscloud.KeyringFile{Path: path, Passphrase: "correct horse", Iterations: 1000}
+		if err := other.StoreCredentials(ctx, rotated); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := k.Credentials(ctx); err != nil || got != rotated {
+			t.Errorf("Expected %v, got %v (%v)", rotated, got, err)
+		}
+	})
+}
+
+func TestNewWithCredentialProvider(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s := thingscloudtest.NewServer("martin@example.com", "secret")
+	defer s.Close()
+
+	path := filepath.Join(t.TempDir(), "things.toml")
+	store := thingscloud.NewFileCredentials(path)
+	if err := store.StoreCredentials(ctx, thingscloud.Credentials{Email: s.Email, Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := thingscloud.NewWithCredentialProvider(s.URL, store, thingscloud.WithRetryPolicy(thingscloud.NoRetry()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.EMail != s.Email {
+		t.Errorf("Expected email %q, got %q", s.Email, c.EMail)
+	}
+	if _, err := c.Verify(); err != nil {
+		t.Fatalf("Expected Verification to succeed, but didn't: %v", err)
+	}
+
+	rotated, err := c.Accounts.ChangePassword("rotated")
+	if err != nil {
+		t.Fatalf("Expected password change to succeed, but didn't: %v", err)
+	}
+	if got, _ := store.Credentials(ctx); got.Password != "rotated" {
+		t.Errorf("Expected the store to hold the new password, got %q", got.Password)
+	}
+	if _, err := rotated.Verify(); err != nil {
+		t.Errorf("Expected the rotated client to authenticate, but didn't: %v", err)
+	}
+	// the original client looks the password up again, so it follows the rotation too
+	if _, err := c.Verify(); err != nil {
+		t.Errorf("Expected the original client to pick up the new password, but didn't: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package thingscloud
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// dateLayout is the textual representation of a Date
+const dateLayout = "2006-01-02"
+
+// Date is a calendar day without a time of day or time zone. Things stores day
+// granular fields such as sr, tir and dd as the unix epoch of midnight UTC of the day,
+// independent of the time zone of the device which wrote them. Use DateIn and Time
+// to convert from and to instants in the user's time zone.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// NewDate returns the date of the given day. Values out of range are normalized the
+// way time.Date does, e.g. October 32 becomes November 1.
+func NewDate(year int, month time.Month, day int) Date {
+	return DateOf(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// DateOf returns the calendar day of t in t's location
+func DateOf(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{Year: y, Month: m, Day: d}
+}
+
+// DateIn returns the calendar day of t in loc
+func DateIn(t time.Time, loc *time.Location) Date {
+	return DateOf(t.In(loc))
+}
+
+// Today returns the current calendar day in loc
+func Today(loc *time.Location) Date {
+	return DateIn(time.Now(), loc)
+}
+
+// DateFromUnix returns the date encoded as the given unix epoch. Epochs which are not
+// midnight UTC are truncated to their UTC day.
+func DateFromUnix(sec int64) Date {
+	return DateOf(time.Unix(sec, 0).UTC())
+}
+
+// ParseDate parses a date in YYYY-MM-DD format
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return Date{}, err
+	}
+	return DateOf(t), nil
+}
+
+// Time returns midnight at the beginning of the day in loc
+func (d Date) Time(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// Unix returns the wire representation: the unix epoch of midnight UTC
+func (d Date) Unix() int64 {
+	return d.Time(time.UTC).Unix()
+}
+
+// IsZero reports whether d is the zero Date
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+// AddDays returns the date n days after d
+func (d Date) AddDays(n int) Date {
+	return NewDate(d.Year, d.Month, d.Day+n)
+}
+
+// Compare returns -1 if d is before o, 0 if they are the same day and +1 otherwise
+func (d Date) Compare(o Date) int {
+	a, b := d.Unix(), o.Unix()
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Before reports whether d is before o
+func (d Date) Before(o Date) bool { return d.Compare(o) < 0 }
+
+// After reports whether d is after o
+func (d Date) After(o Date) bool { return d.Compare(o) > 0 }
+
+// String returns the date in YYYY-MM-DD format
+func (d Date) String() string {
+	return d.Time(time.UTC).Format(dateLayout)
+}
+
+// MarshalJSON encodes the date as the unix epoch of midnight UTC
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Unix())
+}
+
+// UnmarshalJSON decodes a unix epoch given as int or float
+func (d *Date) UnmarshalJSON(bs []byte) error {
+	var f float64
+	if err := json.Unmarshal(bs, &f); err != nil {
+		return err
+	}
+	*d = DateFromUnix(int64(math.Floor(f)))
+	return nil
+}
@@ -0,0 +1,81 @@
+package thingscloud
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDate_JSON(t *testing.T) {
+	t.Parallel()
+	d := NewDate(2026, time.February, 10)
+	bs, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != "1770681600" {
+		t.Errorf("Expected midnight UTC epoch 1770681600, got %s", bs)
+	}
+
+	for _, in := range []string{"1770681600", "1770681600.0", "1770713623.4716659"} {
+		var got Date
+		if err := json.Unmarshal([]byte(in), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != d {
+			t.Errorf("Expected %s to decode to %s, got %s", in, d, got)
+		}
+	}
+
+	var p TaskActionItemPayload
+	if err := json.Unmarshal([]byte(`{"sr":1770681600,"tir":1770681600,"dd":1770854400}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	if *p.ScheduledDate != d || *p.TaskIR != d || p.DeadlineDate.String() != "2026-02-12" {
+		t.Errorf("Expected payload dates to decode, got %v %v %v", p.ScheduledDate, p.TaskIR, p.DeadlineDate)
+	}
+}
+
+func TestDate_Location(t *testing.T) {
+	t.Parallel()
+	la := time.FixedZone("PST", -8*60*60)
+	// 23:30 on March 4th in Los Angeles is already March 5th in UTC
+	instant := time.Date(2026, time.March, 4, 23, 30, 0, 0, la)
+
+	if got := DateIn(instant, la); got != NewDate(2026, time.March, 4) {
+		t.Errorf("Expected March 4th in PST, got %s", got)
+	}
+	if got := DateIn(instant, time.UTC); got != NewDate(2026, time.March, 5) {
+		t.Errorf("Expected March 5th in UTC, got %s", got)
+	}
+	d := NewDate(2026, time.March, 4)
+	if got := d.Time(la); !got.Equal(time.Date(2026, time.March, 4, 0, 0, 0, 0, la)) {
+		t.Errorf("Expected midnight in PST, got %s", got)
+	}
+	if d.Unix() != time.Date(2026, time.March, 4, 0, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("Expected the wire value to be independent of the location, got %d", d.Unix())
+	}
+}
+
+func TestDate_Arithmetic(t *testing.T) {
+	t.Parallel()
+	d := NewDate(2026, time.February, 28)
+	if got := d.AddDays(1); got != NewDate(2026, time.March, 1) {
+		t.Errorf("Expected March 1st, got %s", got)
+	}
+	if got := NewDate(2026, time.January, 32); got != NewDate(2026, time.February, 1) {
+		t.Errorf("Expected normalization to February 1st, got %s", got)
+	}
+	if !d.Before(d.AddDays(1)) || !d.After(d.AddDays(-1)) || d.Compare(d) != 0 {
+		t.Errorf("Expected dates to be ordered")
+	}
+	if p, err := ParseDate("2026-02-28"); err != nil || p != d {
+		t.Errorf("Expected to parse %s, got %s (%v)", d, p, err)
+	}
+	if _, err := ParseDate("28.02.2026"); err == nil {
+		t.Errorf("Expected an invalid date to fail")
+	}
+	if !(Date{}).IsZero() || d.IsZero() {
+		t.Errorf("Expected only the zero Date to be zero")
+	}
+}
@@ -0,0 +1,250 @@
+package thingscloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxDriftExamples limits how many distinct values are kept per unknown field
+const maxDriftExamples = 3
+
+// payloadTypes maps every item kind the SDK knows to the type its payload decodes
+// into. Kinds mapped to nil are known, but their payload is not decoded.
+var payloadTypes = map[ItemKind]reflect.Type{
+	ItemKindTask:           reflect.TypeOf(TaskActionItemPayload{}),
+	ItemKindTask4:          reflect.TypeOf(TaskActionItemPayload{}),
+	ItemKindTask3:          reflect.TypeOf(TaskActionItemPayload{}),
+	ItemKindTaskPlain:      reflect.TypeOf(TaskActionItemPayload{}),
+	ItemKindChecklistItem:  reflect.TypeOf(CheckListActionItemPayload{}),
+	ItemKindChecklistItem2: reflect.TypeOf(CheckListActionItemPayload{}),
+	ItemKindChecklistItem3: reflect.TypeOf(CheckListActionItemPayload{}),
+	ItemKindArea:           reflect.TypeOf(AreaActionItemPayload{}),
+	ItemKindArea3:          reflect.TypeOf(AreaActionItemPayload{}),
+	ItemKindAreaPlain:      reflect.TypeOf(AreaActionItemPayload{}),
+	ItemKindTag:            reflect.TypeOf(TagActionItemPayload{}),
+	ItemKindTag4:           reflect.TypeOf(TagActionItemPayload{}),
+	ItemKindTagPlain:       reflect.TypeOf(TagActionItemPayload{}),
+	ItemKindTombstone:      reflect.TypeOf(TombstoneActionItemPayload{}),
+	ItemKindSettings:       nil,
+}
+
+// UnknownField is a payload field which the payload type of its kind does not decode
+type UnknownField struct {
+	Kind ItemKind `json:"kind"`
+	// PayloadType is the Go type the kind decodes into, e.g. thingscloud.TaskActionItemPayload
+	PayloadType string `json:"payloadType"`
+	Field       string `json:"field"`
+	// Count is the number of items the field was seen on
+	Count int `json:"count"`
+	// Examples holds up to three distinct raw values
+	Examples []json.RawMessage `json:"examples,omitempty"`
+}
+
+// DriftReport lists what the SDK did not understand in the items it decoded
+type DriftReport struct {
+	// Items is the number of items checked, per kind
+	Items map[ItemKind]int `json:"items"`
+	// UnknownKinds counts the items of kinds the SDK does not know
+	UnknownKinds map[ItemKind]int `json:"unknownKinds,omitempty"`
+	// UnknownFields are sorted by kind and field
+	UnknownFields []UnknownField `json:"unknownFields,omitempty"`
+}
+
+// HasDrift reports whether any unknown kind or field was seen
+func (r DriftReport) HasDrift() bool {
+	return len(r.UnknownKinds) > 0 || len(r.UnknownFields) > 0
+}
+
+// WriteText writes the report in a human readable form
+func (r DriftReport) WriteText(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("Items checked:\n")
+	for _, kind := range sortedKinds(r.Items) {
+		fmt.Fprintf(&b, "  %s: %d\n", kind, r.Items[kind])
+	}
+	if len(r.UnknownKinds) > 0 {
+		b.WriteString("Unknown item kinds:\n")
+		for _, kind := range sortedKinds(r.UnknownKinds) {
+			fmt.Fprintf(&b, "  %s (%d items)\n", kind, r.UnknownKinds[kind])
+		}
+	}
+	if len(r.UnknownFields) > 0 {
+		b.WriteString("Unknown payload fields:\n")
+		for _, f := range r.UnknownFields {
+			examples := make([]string, len(f.Examples))
+			for i, ex := range f.Examples {
+				examples[i] = string(ex)
+			}
+			fmt.Fprintf(&b, "  %s.%s (%d items): %s\n", f.Kind, f.Field, f.Count, strings.Join(examples, ", "))
+		}
+	}
+	if !r.HasDrift() {
+		b.WriteString("No protocol drift\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func sortedKinds(m map[ItemKind]int) []ItemKind {
+	kinds := make([]ItemKind, 0, len(m))
+	for kind := range m {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
+}
+
+type driftKey struct {
+	kind  ItemKind
+	field string
+}
+
+// DriftDetector checks items for payload fields and item kinds the SDK does not
+// decode, which usually means the server side schema changed. It is safe for
+// concurrent use.
+type DriftDetector struct {
+	mu     sync.Mutex
+	items  map[ItemKind]int
+	kinds  map[ItemKind]int
+	fields map[driftKey]*UnknownField
+	known  map[reflect.Type]map[string]bool
+}
+
+// NewDriftDetector returns an empty DriftDetector
+func NewDriftDetector() *DriftDetector {
+	d := &DriftDetector{known: map[reflect.Type]map[string]bool{}}
+	d.Reset()
+	return d
+}
+
+// Reset forgets everything observed so far
+func (d *DriftDetector) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.items = map[ItemKind]int{}
+	d.kinds = map[ItemKind]int{}
+	d.fields = map[driftKey]*UnknownField{}
+}
+
+// Observe checks items. Payloads which aren't JSON objects are counted, but not checked.
+func (d *DriftDetector) Observe(items ...Item) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, item := range items {
+		d.items[item.Kind]++
+		typ, ok := payloadTypes[item.Kind]
+		if !ok {
+			d.kinds[item.Kind]++
+			continue
+		}
+		if typ == nil {
+			continue
+		}
+		var payload map[string]json.RawMessage
+		if err := json.Unmarshal(item.P, &payload); err != nil {
+			continue
+		}
+		known := d.known[typ]
+		if known == nil {
+			known = jsonFields(typ)
+			d.known[typ] = known
+		}
+		for name, value := range payload {
+			if known[name] {
+				continue
+			}
+			k := driftKey{item.Kind, name}
+			f := d.fields[k]
+			if f == nil {
+				f = &UnknownField{Kind: item.Kind, PayloadType: typ.String(), Field: name}
+				d.fields[k] = f
+			}
+			f.Count++
+			f.addExample(value)
+		}
+	}
+}
+
+// Report returns a snapshot of everything observed so far
+func (d *DriftDetector) Report() DriftReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r := DriftReport{Items: make(map[ItemKind]int, len(d.items))}
+	for kind, n := range d.items {
+		r.Items[kind] = n
+	}
+	if len(d.kinds) > 0 {
+		r.UnknownKinds = make(map[ItemKind]int, len(d.kinds))
+		for kind, n := range d.kinds {
+			r.UnknownKinds[kind] = n
+		}
+	}
+	for _, f := range d.fields {
+		c := *f
+		c.Examples = append([]json.RawMessage(nil), f.Examples...)
+		r.UnknownFields = append(r.UnknownFields, c)
+	}
+	sort.Slice(r.UnknownFields, func(i, j int) bool {
+		a, b := r.UnknownFields[i], r.UnknownFields[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Field < b.Field
+	})
+	return r
+}
+
+func (f *UnknownField) addExample(value json.RawMessage) {
+	if len(f.Examples) >= maxDriftExamples {
+		return
+	}
+	for _, ex := range f.Examples {
+		if string(ex) == string(value) {
+			return
+		}
+	}
+	f.Examples = append(f.Examples, append(json.RawMessage(nil), value...))
+}
+
+// jsonFields returns the object keys a struct type decodes
+func jsonFields(typ reflect.Type) map[string]bool {
+	fields := map[string]bool{}
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields[name] = true
+	}
+	return fields
+}
+
+// WithStrictDecoding makes the client check every item it reads through History.Items,
+// Commits, All and AllCommits (and therefore every sync) for payload fields and item
+// kinds the SDK does not decode. The findings are available through Client.DriftReport.
+func WithStrictDecoding() Option {
+	return func(c *Client) {
+		c.drift = NewDriftDetector()
+	}
+}
+
+// DriftReport returns what strict decoding found so far. It is empty unless the client
+// was created with WithStrictDecoding.
+func (c *Client) DriftReport() DriftReport {
+	if c.drift == nil {
+		return DriftReport{Items: map[ItemKind]int{}}
+	}
+	return c.drift.Report()
+}
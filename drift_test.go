@@ -0,0 +1,71 @@
+package thingscloud_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	thingscloud "github.com/arthursoares/things-cloud-sdk"
+	"github.com/arthursoares/things-cloud-sdk/thingscloudtest"
+)
+
+func TestClient_DriftReport(t *testing.T) {
+	t.Parallel()
+	s := thingscloudtest.NewServer("martin@example.com", "secret")
+	defer s.Close()
+	item := func(uuid string, kind thingscloud.ItemKind, payload map[string]any) thingscloud.Item {
+		p, _ := json.Marshal(payload)
+		return thingscloud.Item{UUID: uuid, Kind: kind, P: p}
+	}
+	_, err := s.Seed(s.OwnHistoryKey(),
+		item("task-1", thingscloud.ItemKindTask, map[string]any{"tt": "one", "rp": 1}),
+		item("task-2", thingscloud.ItemKindTask, map[string]any{"tt": "two", "rp": 2}),
+		item("reminder-1", "Reminder1", map[string]any{"tt": "new kind"}),
+		item("settings", thingscloud.ItemKindSettings, map[string]any{"ww": 1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		c := s.Client()
+		if _, _, err := c.HistoryWithID(s.OwnHistoryKey()).Items(thingscloud.ItemsOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if r := c.DriftReport(); r.HasDrift() || len(r.Items) != 0 {
+			t.Errorf("Expected an empty report without strict decoding, but got %+v", r)
+		}
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		c := s.Client(thingscloud.WithStrictDecoding())
+		if _, _, err := c.HistoryWithID(s.OwnHistoryKey()).Items(thingscloud.ItemsOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		r := c.DriftReport()
+		if r.Items[thingscloud.ItemKindTask] != 2 {
+			t.Errorf("Expected 2 checked tasks, but got %v", r.Items)
+		}
+		if len(r.UnknownKinds) != 1 || r.UnknownKinds["Reminder1"] != 1 {
+			t.Errorf("Expected Reminder1 as unknown kind, but got %v", r.UnknownKinds)
+		}
+		if len(r.UnknownFields) != 1 {
+			t.Fatalf("Expected only rp as unknown field, but got %+v", r.UnknownFields)
+		}
+		f := r.UnknownFields[0]
+		if f.Kind != thingscloud.ItemKindTask || f.Field != "rp" || f.Count != 2 || len(f.Examples) != 2 {
+			t.Errorf("Expected rp seen on 2 tasks with 2 examples, but got %+v", f)
+		}
+
+		var buf bytes.Buffer
+		if err := r.WriteText(&buf); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"Reminder1 (1 items)", "Task6.rp (2 items): 1, 2"} {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("Expected text report to contain %q, but got %s", want, buf.String())
+			}
+		}
+	})
+}
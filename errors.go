@@ -0,0 +1,107 @@
+package thingscloud
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodySize limits how much of a failed response body is kept in an APIError
+const maxErrorBodySize = 512
+
+// requestIDHeaders lists the response headers which may carry a server side request id
+var requestIDHeaders = []string{"X-Request-Id", "X-Amzn-Requestid", "X-Amz-Request-Id", "Request-Id"}
+
+// APIError is returned when thingscloud answers with an unexpected status code
+type APIError struct {
+	StatusCode int
+	Status     string
+	Method     string
+	Path       string
+	// Body contains the beginning of the response body, if any
+	Body string
+	// RequestID is the server side request id, if the response carried one
+	RequestID string
+	// Response contains the full dumped response for failed commits
+	Response string
+
+	err error
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: http response code: %s", e.Method, e.Path, e.Status)
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request id %s)", e.RequestID)
+	}
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// Unwrap allows errors.Is to match sentinel errors such as ErrUnauthorized
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// newAPIError builds an APIError from a response with an unexpected status code.
+// The response body is read, but not closed.
+func newAPIError(resp *http.Response) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}
+	if resp.Request != nil {
+		e.Method = resp.Request.Method
+		e.Path = resp.Request.URL.Path
+	}
+	for _, h := range requestIDHeaders {
+		if id := resp.Header.Get(h); id != "" {
+			e.RequestID = id
+			break
+		}
+	}
+	if resp.Body != nil {
+		bs, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		e.Body = strings.TrimSpace(string(bs))
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		e.err = ErrUnauthorized
+	}
+	return e
+}
+
+// IsRetryable reports whether err is a temporary server side failure, which may
+// succeed when the request is sent again
+func IsRetryable(err error) bool {
+	var e *APIError
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// IsNotFound reports whether err was caused by a 404 response
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether err was caused by a 409 response
+func IsConflict(err error) bool {
+	return hasStatusCode(err, http.StatusConflict)
+}
+
+func hasStatusCode(err error, code int) bool {
+	var e *APIError
+	return errors.As(err, &e) && e.StatusCode == code
+}
@@ -0,0 +1,104 @@
+package thingscloud
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	t.Run("Unauthorized", func(t *testing.T) {
+		t.Parallel()
+		server := fakeServer(fakeResponse{401, "error.json"})
+		defer server.Close()
+
+		c := New(fmt.Sprintf("http://%s", server.Listener.Addr().String()), "unknown@example.com", "")
+		_, err := c.Verify()
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Fatalf("Expected ErrUnauthorized, got %v", err)
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("Expected *APIError, got %T", err)
+		}
+		if apiErr.StatusCode != 401 || apiErr.Method != "GET" || apiErr.Path != "/version/1/account/unknown@example.com" {
+			t.Errorf("Unexpected error details: %#v", apiErr)
+		}
+		if apiErr.Body == "" {
+			t.Error("Expected response body snippet to be captured")
+		}
+	})
+
+	t.Run("Classification", func(t *testing.T) {
+		t.Parallel()
+		var status int
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-Id", "req-123")
+			w.WriteHeader(status)
+		}))
+		defer ts.Close()
+		c := NewWithOptions(ts.URL, "martin@example.com", "", WithRetryPolicy(NoRetry()))
+
+		for _, tc := range []struct {
+			status    int
+			retryable bool
+			notFound  bool
+			conflict  bool
+		}{
+			{http.StatusInternalServerError, true, false, false},
+			{http.StatusServiceUnavailable, true, false, false},
+			{http.StatusTooManyRequests, true, false, false},
+			{http.StatusNotFound, false, true, false},
+			{http.StatusConflict, false, false, true},
+			{http.StatusBadRequest, false, false, false},
+		} {
+			status = tc.status
+			_, err := c.History("33333abb-bfe4-4b03-a5c9-106d42220c72")
+			if got := IsRetryable(err); got != tc.retryable {
+				t.Errorf("%d: IsRetryable = %v, want %v", tc.status, got, tc.retryable)
+			}
+			if got := IsNotFound(err); got != tc.notFound {
+				t.Errorf("%d: IsNotFound = %v, want %v", tc.status, got, tc.notFound)
+			}
+			if got := IsConflict(err); got != tc.conflict {
+				t.Errorf("%d: IsConflict = %v, want %v", tc.status, got, tc.conflict)
+			}
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.RequestID != "req-123" {
+				t.Errorf("%d: RequestID = %q, want %q", tc.status, apiErr.RequestID, "req-123")
+			}
+		}
+	})
+
+	t.Run("WriteIncludesResponse", func(t *testing.T) {
+		t.Parallel()
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"bad commit"}`)
+		}))
+		defer ts.Close()
+
+		c := New(ts.URL, "martin@example.com", "")
+		h := c.HistoryWithID("33333abb-bfe4-4b03-a5c9-106d42220c72")
+		_, err := h.Write()
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("Expected *APIError, got %T", err)
+		}
+		if apiErr.Body != `{"error":"bad commit"}` {
+			t.Errorf("Body = %q", apiErr.Body)
+		}
+		if apiErr.Response == "" {
+			t.Error("Expected dumped response in error")
+		}
+	})
+
+	t.Run("NonAPIError", func(t *testing.T) {
+		t.Parallel()
+		if IsRetryable(errors.New("500")) {
+			t.Error("Expected plain errors not to be retryable")
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"math/big"
@@ -116,11 +118,11 @@ func main() {
 	anytime := thingscloud.TaskScheduleAnytime
 	taskUUID := base58Encode(uuid.New())
 	now := thingscloud.Timestamp(time.Now())
-	date := thingscloud.Timestamp(time.Now().Truncate(24 * time.Hour))
+	date := thingscloud.Today(time.Local)
 	todayIdx := 0
 	idx := -4000
 	log.Printf("Creating task %s\n", taskUUID)
-	if err := history.Write(thingscloud.TaskActionItem{
+	if _, err := history.Write(thingscloud.TaskActionItem{
 		Item: thingscloud.Item{
 			Kind:   thingscloud.ItemKindTask,
 			Action: thingscloud.ItemActionCreated,
@@ -136,13 +138,15 @@ func main() {
 			Index:         &idx,
 			TaskIndex:     &todayIdx,
 			Type:          thingscloud.TaskTypePtr(thingscloud.TaskTypeTask),
+			Note:          json.RawMessage(`{"_t":"tx","ch":0,"v":"","t":1}`),
+			ExtensionData: json.RawMessage(`{"sn":{},"_t":"oo"}`),
 		},
 	}); err != nil {
 		log.Fatalf("Task creation failed: %q\n", err.Error())
 	}
 
 	log.Printf("Deleting task %s\n", taskUUID)
-	if err := history.Write(thingscloud.TaskActionItem{
+	if _, err := history.Write(thingscloud.TaskActionItem{
 		Item: thingscloud.Item{
 			Kind:   thingscloud.ItemKindTask,
 			Action: thingscloud.ItemActionDeleted,
@@ -153,12 +157,13 @@ func main() {
 		log.Fatalf("Task deletion failed: %q\n", err.Error())
 	}
 
-	items, _, err := history.Items(thingscloud.ItemsOptions{StartIndex: 0})
-	if err != nil {
-		log.Fatalf("Failed to lookup items: %q\n", err.Error())
-	}
-	if err := state.Update(items...); err != nil {
-		log.Fatalf("Failed to update state: %q\n", err.Error())
+	for item, err := range history.All(context.Background(), 0) {
+		if err != nil {
+			log.Fatalf("Failed to lookup items: %q\n", err.Error())
+		}
+		if err := state.Update(item); err != nil {
+			log.Fatalf("Failed to update state: %q\n", err.Error())
+		}
 	}
 
 	doneTasks := 0
@@ -208,13 +213,12 @@ Tags:           %d
 	}
 
 	fmt.Printf("Today\n")
-	currentTime := time.Now()
-	todayStart := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), 0, 0, 0, 0, time.UTC)
+	today := thingscloud.Today(time.Local)
 	for _, task := range state.Tasks {
 		if task.Schedule != thingscloud.TaskScheduleAnytime {
 			continue
 		}
-		if task.ScheduledDate == nil || !task.ScheduledDate.Equal(todayStart) {
+		if task.ScheduledDate == nil || *task.ScheduledDate != today {
 			continue
 		}
 		if task.Status != thingscloud.TaskStatusPending {
@@ -0,0 +1,229 @@
+// Package har imports HTTP Archive (HAR) captures of Things.app traffic, e.g. from
+// Proxyman or a browser. It extracts the /items and /commit exchanges, decodes them
+// into things items, writes them as replayable tapes and reports payload fields the
+// SDK does not know yet.
+package har
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	things "github.com/arthursoares/things-cloud-sdk"
+	"github.com/arthursoares/things-cloud-sdk/recorder"
+)
+
+// File is a parsed HAR file. Only the parts needed for thingscloud traffic are decoded.
+type File struct {
+	Log struct {
+		Entries []Entry `json:"entries"`
+	} `json:"log"`
+}
+
+// Entry is a single request and response
+type Entry struct {
+	StartedDateTime string   `json:"startedDateTime"`
+	Request         Request  `json:"request"`
+	Response        Response `json:"response"`
+}
+
+// Header is a HAR name/value pair
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Request is the request of an entry
+type Request struct {
+	Method   string    `json:"method"`
+	URL      string    `json:"url"`
+	Headers  []Header  `json:"headers"`
+	PostData *PostData `json:"postData,omitempty"`
+}
+
+// PostData is the body of a request
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Response is the response of an entry
+type Response struct {
+	Status     int      `json:"status"`
+	StatusText string   `json:"statusText"`
+	Headers    []Header `json:"headers"`
+	Content    Content  `json:"content"`
+}
+
+// Content is the body of a response, which may be base64 encoded
+type Content struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Parse reads a HAR file
+func Parse(r io.Reader) (*File, error) {
+	var f File
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("parsing har: %w", err)
+	}
+	return &f, nil
+}
+
+// ParseFile reads the HAR file at path
+func ParseFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// RequestBody returns the body of the request
+func (e Entry) RequestBody() []byte {
+	if e.Request.PostData == nil {
+		return nil
+	}
+	return []byte(e.Request.PostData.Text)
+}
+
+// ResponseBody returns the decoded body of the response
+func (e Entry) ResponseBody() ([]byte, error) {
+	if e.Response.Content.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(e.Response.Content.Text)
+	}
+	return []byte(e.Response.Content.Text), nil
+}
+
+// HTTP rebuilds the exchange as http values, e.g. to add it to a recorder.
+// Request headers are left out, as they carry the credentials.
+func (e Entry) HTTP() (*http.Request, *http.Response, error) {
+	req, err := http.NewRequest(e.Request.Method, e.Request.URL, bytes.NewReader(e.RequestBody()))
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := e.ResponseBody()
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	for _, h := range e.Response.Headers {
+		header.Add(h.Name, h.Value)
+	}
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Response.Status, e.Response.StatusText),
+		StatusCode:    e.Response.Status,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	return req, resp, nil
+}
+
+// ExchangeKind tells the endpoint of an exchange
+type ExchangeKind string
+
+const (
+	// Items is a GET /history/{id}/items exchange
+	Items ExchangeKind = "items"
+	// Commit is a POST /history/{id}/commit exchange
+	Commit ExchangeKind = "commit"
+)
+
+// Exchange is an /items or /commit entry, decoded
+type Exchange struct {
+	Kind      ExchangeKind
+	Entry     Entry
+	HistoryID string
+	// Index is the start-index of an items request, or the ancestor-index of a commit
+	Index int
+	// Items are the items read or written, in the order they were sent
+	Items []things.Item
+}
+
+// Extract returns the /items and /commit exchanges of f in capture order. Exchanges
+// which failed, or whose bodies can't be decoded, are skipped.
+func Extract(f *File) []Exchange {
+	var exchanges []Exchange
+	for _, e := range f.Log.Entries {
+		ex, ok := decodeEntry(e)
+		if ok {
+			exchanges = append(exchanges, ex)
+		}
+	}
+	return exchanges
+}
+
+func decodeEntry(e Entry) (Exchange, bool) {
+	u, err := url.Parse(e.Request.URL)
+	if err != nil || e.Response.Status != http.StatusOK {
+		return Exchange{}, false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 5 || parts[0] != "version" || parts[2] != "history" {
+		return Exchange{}, false
+	}
+	ex := Exchange{Entry: e, HistoryID: parts[3]}
+	switch {
+	case parts[4] == "items" && e.Request.Method == http.MethodGet:
+		ex.Kind = Items
+		ex.Index, _ = strconv.Atoi(u.Query().Get("start-index"))
+		body, err := e.ResponseBody()
+		if err != nil {
+			return Exchange{}, false
+		}
+		var v struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		if err := json.Unmarshal(body, &v); err != nil {
+			return Exchange{}, false
+		}
+		for _, commit := range v.Items {
+			items, err := things.DecodeCommitItems(commit)
+			if err != nil {
+				return Exchange{}, false
+			}
+			ex.Items = append(ex.Items, items...)
+		}
+	case parts[4] == "commit" && e.Request.Method == http.MethodPost:
+		ex.Kind = Commit
+		ex.Index, _ = strconv.Atoi(u.Query().Get("ancestor-index"))
+		items, err := things.DecodeCommitItems(e.RequestBody())
+		if err != nil {
+			return Exchange{}, false
+		}
+		ex.Items = items
+	default:
+		return Exchange{}, false
+	}
+	return ex, true
+}
+
+// WriteTapes records the exchanges as a recorder tape in dir, which can be replayed
+// with recorder.Replay and whose bodies can be used as fixtures directly
+func WriteTapes(dir string, exchanges []Exchange) error {
+	rec, err := recorder.New(dir, recorder.Record)
+	if err != nil {
+		return err
+	}
+	for _, ex := range exchanges {
+		req, resp, err := ex.Entry.HTTP()
+		if err != nil {
+			return err
+		}
+		if err := rec.Add(req, resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package har
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	things "github.com/arthursoares/things-cloud-sdk"
+	"github.com/arthursoares/things-cloud-sdk/recorder"
+)
+
+const historyID = "33333abb-bfe4-4b03-a5c9-106d42220c72"
+
+func extractFixture(t *testing.T) []Exchange {
+	t.Helper()
+	f, err := ParseFile("../tapes/capture.har")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Extract(f)
+}
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+	exchanges := extractFixture(t)
+	if len(exchanges) != 2 {
+		t.Fatalf("Expected 2 exchanges, but got %d", len(exchanges))
+	}
+
+	items := exchanges[0]
+	if items.Kind != Items || items.HistoryID != historyID || items.Index != 0 {
+		t.Errorf("Expected items exchange of %s at 0, but got %s of %s at %d", historyID, items.Kind, items.HistoryID, items.Index)
+	}
+	var uuids []string
+	for _, item := range items.Items {
+		uuids = append(uuids, item.UUID)
+	}
+	if got := strings.Join(uuids, ","); got != "A1,S1,B1" {
+		t.Errorf("Expected items A1,S1,B1 decoded from the base64 body in commit order, but got %s", got)
+	}
+
+	commit := exchanges[1]
+	if commit.Kind != Commit || commit.Index != 2 {
+		t.Errorf("Expected commit exchange at 2, but got %s at %d", commit.Kind, commit.Index)
+	}
+	if len(commit.Items) != 2 || commit.Items[0].UUID != "C2" || commit.Items[1].UUID != "C1" || commit.Items[0].Kind != things.ItemKindChecklistItem3 {
+		t.Errorf("Expected commit items in the order they were sent, but got %+v", commit.Items)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	t.Parallel()
+	r := Analyze(extractFixture(t))
+
+	if r.Items[things.ItemKindChecklistItem3] != 2 || r.Items[things.ItemKindSettings] != 1 {
+		t.Errorf("Expected item counts per kind, but got %v", r.Items)
+	}
+	if len(r.UnknownKinds) != 0 {
+		t.Errorf("Expected no unknown kinds, but got %v", r.UnknownKinds)
+	}
+	if len(r.UnknownFields) != 1 {
+		t.Fatalf("Expected 1 unknown field, but got %+v", r.UnknownFields)
+	}
+	f := r.UnknownFields[0]
+	if f.Kind != things.ItemKindTask || f.Field != "ai" || f.PayloadType != "thingscloud.TaskActionItemPayload" {
+		t.Errorf("Expected Task6.ai on TaskActionItemPayload, but got %+v", f)
+	}
+	if len(f.Examples) != 1 || string(f.Examples[0]) != `{"sync":true}` {
+		t.Errorf("Expected the raw value as example, but got %s", f.Examples)
+	}
+}
+
+func TestWriteTapes(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(t.TempDir(), "har")
+	if err := WriteTapes(dir, extractFixture(t)); err != nil {
+		t.Fatal(err)
+	}
+	bs, err := os.ReadFile(filepath.Join(dir, "tape.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(bs), "secret") {
+		t.Errorf("Expected request headers to be dropped, but got %s", bs)
+	}
+
+	rec, err := recorder.New(dir, recorder.Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := things.NewWithOptions(things.APIEndpoint, "martin@example.com", "secret", things.WithTransport(rec))
+	items, _, err := c.HistoryWithID(historyID).Items(things.ItemsOptions{})
+	if err != nil {
+		t.Fatalf("Expected tape to replay, but got %v", err)
+	}
+	if len(items) != 3 || items[0].UUID != "A1" {
+		t.Errorf("Expected 3 items starting with A1, but got %+v", items)
+	}
+}
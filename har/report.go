@@ -0,0 +1,15 @@
+package har
+
+import (
+	things "github.com/arthursoares/things-cloud-sdk"
+)
+
+// Analyze checks the items of every exchange for payload fields and item kinds the
+// SDK does not decode
+func Analyze(exchanges []Exchange) things.DriftReport {
+	d := things.NewDriftDetector()
+	for _, ex := range exchanges {
+		d.Observe(ex.Items...)
+	}
+	return d.Report()
+}
@@ -7,6 +7,11 @@ func String(str string) *string {
 	return &str
 }
 
+// Int64 returns a pointer to an int64
+func Int64(val int64) *int64 {
+	return &val
+}
+
 // Status returns a pointer to a TaskStatus
 func Status(val TaskStatus) *TaskStatus {
 	return &val
@@ -27,3 +32,8 @@ func Time(val time.Time) *Timestamp {
 	ts := Timestamp(val)
 	return &ts
 }
+
+// DatePtr returns a pointer to a Date
+func DatePtr(val Date) *Date {
+	return &val
+}
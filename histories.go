@@ -2,13 +2,14 @@ package thingscloud
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/http/httputil"
 	"strconv"
+	"time"
 )
 
 // History represents a synchronization stream. It's identified with a uuid v4
@@ -31,7 +32,12 @@ type historyResponse struct {
 
 // Sync ensures the history object is able to write to things
 func (h *History) Sync() error {
-	req, err := http.NewRequest("GET", fmt.Sprintf("/version/1/history/%s/items", h.ID), nil)
+	return h.SyncContext(context.Background())
+}
+
+// SyncContext is like Sync but carries a context for cancellation and deadlines
+func (h *History) SyncContext(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("/version/1/history/%s/items", h.ID), nil)
 	if err != nil {
 		return err
 	}
@@ -45,7 +51,7 @@ func (h *History) Sync() error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("http response code: %s", resp.Status)
+		return newAPIError(resp)
 	}
 
 	bs, err := io.ReadAll(resp.Body)
@@ -62,7 +68,12 @@ func (h *History) Sync() error {
 
 // History requests a specific history
 func (c *Client) History(id string) (*History, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("/version/1/history/%s", id), nil)
+	return c.HistoryContext(context.Background(), id)
+}
+
+// HistoryContext is like History but carries a context for cancellation and deadlines
+func (c *Client) HistoryContext(ctx context.Context, id string) (*History, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("/version/1/history/%s", id), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -72,10 +83,7 @@ func (c *Client) History(id string) (*History, error) {
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusUnauthorized {
-			return nil, ErrUnauthorized
-		}
-		return nil, fmt.Errorf("http response code: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	bs, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -104,7 +112,12 @@ type v1historyResponse struct {
 
 // OwnHistory returns the clients own history
 func (c *Client) OwnHistory() (*History, error) {
-	resp, err := c.Verify()
+	return c.OwnHistoryContext(context.Background())
+}
+
+// OwnHistoryContext is like OwnHistory but carries a context for cancellation and deadlines
+func (c *Client) OwnHistoryContext(ctx context.Context) (*History, error) {
+	resp, err := c.VerifyContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -126,21 +139,27 @@ func (c *Client) HistoryWithID(id string) *History {
 
 // Histories requests all known history keys
 func (c *Client) Histories() ([]*History, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("/version/1/account/%s/own-history-keys", c.EMail), nil)
+	return c.HistoriesContext(context.Background())
+}
+
+// HistoriesContext is like Histories but carries a context for cancellation and deadlines
+func (c *Client) HistoriesContext(ctx context.Context) ([]*History, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("/version/1/account/%s/own-history-keys", c.EMail), nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Password %s", c.password))
+	auth, err := c.authorization(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", auth)
 	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusUnauthorized {
-			return nil, ErrUnauthorized
-		}
-		return nil, fmt.Errorf("http response code: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	bs, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -165,22 +184,27 @@ type createHistoryResponse struct {
 
 // CreateHistory requests a new history key
 func (c *Client) CreateHistory() (*History, error) {
-	req, err := http.NewRequest("POST", fmt.Sprintf("/version/1/account/%s/own-history-keys", c.EMail), nil)
+	return c.CreateHistoryContext(context.Background())
+}
+
+// CreateHistoryContext is like CreateHistory but carries a context for cancellation and deadlines
+func (c *Client) CreateHistoryContext(ctx context.Context) (*History, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("/version/1/account/%s/own-history-keys", c.EMail), nil)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := c.authorization(ctx)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Password %s", c.password))
+	req.Header.Set("Authorization", auth)
 	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusUnauthorized {
-			return nil, ErrUnauthorized
-		}
-		return nil, fmt.Errorf("http response code: %s", resp.Status)
-
+		return nil, newAPIError(resp)
 	}
 	bs, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -197,18 +221,27 @@ func (c *Client) CreateHistory() (*History, error) {
 // Delete destroys a history
 // Note that thingscloud will always return 202, even if the key is unknown
 func (h *History) Delete() error {
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("/version/1/account/%s/own-history-keys/%s", h.Client.EMail, h.ID), nil)
+	return h.DeleteContext(context.Background())
+}
+
+// DeleteContext is like Delete but carries a context for cancellation and deadlines
+func (h *History) DeleteContext(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("/version/1/account/%s/own-history-keys/%s", h.Client.EMail, h.ID), nil)
+	if err != nil {
+		return err
+	}
+	auth, err := h.Client.authorization(ctx)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Password %s", h.Client.password))
+	req.Header.Set("Authorization", auth)
 	resp, err := h.Client.do(req)
 	if err != nil {
 		return err
 	}
-	resp.Body.Close()
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("http response code: %s", resp.Status)
+		return newAPIError(resp)
 	}
 	return nil
 }
@@ -223,47 +256,135 @@ type Identifiable interface {
 	UUID() string
 }
 
-func (h *History) Write(items ...Identifiable) error {
-	m := map[string]interface{}{}
+// CommitResult describes a commit which was accepted by thingscloud
+type CommitResult struct {
+	// HistoryID is the history the commit was written to
+	HistoryID string
+	// AncestorIndex is the server head the commit was based on. It is also the index
+	// the commit has in the history.
+	AncestorIndex int
+	// ServerHeadIndex is the server head after the commit
+	ServerHeadIndex int
+	// Count is the number of items committed
+	Count int
+	// UUIDs lists the committed items in the order they were passed to Write
+	UUIDs []string
+	// Items are the committed items as they were sent
+	Items []Item
+	// Latency is the time the commit took, including retries
+	Latency time.Duration
+}
+
+// Commit returns the written commit the way History.Commits returns it
+func (r *CommitResult) Commit() Commit {
+	return Commit{Index: r.AncestorIndex, Items: r.Items}
+}
+
+// Write commits the given items to the history, using LatestServerIndex as ancestor.
+// On success LatestServerIndex is advanced to the new server head.
+//
+// Items are validated before they are sent, see ValidateBatch. Items breaking an
+// invariant Things.app relies on are not committed, a *ValidationError is returned
+// instead. Use WithoutValidation to opt out.
+func (h *History) Write(items ...Identifiable) (*CommitResult, error) {
+	return h.WriteContext(context.Background(), items...)
+}
+
+// WriteContext is like Write but carries a context for cancellation and deadlines
+func (h *History) WriteContext(ctx context.Context, items ...Identifiable) (*CommitResult, error) {
+	return h.write(ctx, !h.Client.skipValidation, items...)
+}
+
+// write commits items, validating them first if validate is set
+func (h *History) write(ctx context.Context, validate bool, items ...Identifiable) (*CommitResult, error) {
+	res := &CommitResult{HistoryID: h.ID, AncestorIndex: h.LatestServerIndex}
+	m := map[string]json.RawMessage{}
 	for _, item := range items {
-		m[item.UUID()] = item
+		bs, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		id := item.UUID()
+		var written Item
+		if err := json.Unmarshal(bs, &written); err != nil {
+			return nil, err
+		}
+		written.UUID = id
+		if _, ok := m[id]; ok {
+			// the commit is keyed by UUID, later items replace earlier ones
+			for i := range res.Items {
+				if res.Items[i].UUID == id {
+					res.Items[i] = written
+				}
+			}
+		} else {
+			res.UUIDs = append(res.UUIDs, id)
+			res.Items = append(res.Items, written)
+		}
+		m[id] = bs
 	}
-	bs, err := json.Marshal(m)
+	res.Count = len(res.UUIDs)
+	if validate {
+		if err := validateItems(res.Items, h.Client.refs); err != nil {
+			return nil, err
+		}
+	}
+	// the body is built by hand to send the items in order
+	var body bytes.Buffer
+	body.WriteByte('{')
+	for i, id := range res.UUIDs {
+		key, err := json.Marshal(id)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			body.WriteByte(',')
+		}
+		body.Write(key)
+		body.WriteByte(':')
+		body.Write(m[id])
+	}
+	body.WriteByte('}')
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("/version/1/history/%s/commit", h.ID), &body)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	req, err := http.NewRequest("POST", fmt.Sprintf("/version/1/history/%s/commit", h.ID), bytes.NewReader(bs))
-	req.Header.Add("Schema", "301")
-	req.Header.Add("Push-Priority", "5")
-	// Full App-Instance-Id matching Things format: {hash}-{bundleId}-{hash}
-	req.Header.Add("App-Instance-Id", "000000000000000000000000000000000000000000000000000000000000000-com.culturedcode.ThingsMac-000000000000000000000000000000000000000000000000000000000000000")
-	req.Header.Add("App-Id", "com.culturedcode.ThingsMac")
+	id := h.Client.Identity
+	req.Header.Add("Schema", h.schemaVersion())
+	req.Header.Add("Push-Priority", strconv.Itoa(id.PushPriority))
+	req.Header.Add("App-Instance-Id", id.AppInstanceID)
+	req.Header.Add("App-Id", id.AppID)
 	req.Header.Add("Content-Encoding", "UTF-8")
-	req.Header.Add("Host", "cloud.culturedcode.com")
 	req.Header.Add("Accept", "application/json")
 	query := req.URL.Query()
 	query.Add("ancestor-index", strconv.Itoa(h.LatestServerIndex))
 	query.Add("_cnt", "1")
 	req.URL.RawQuery = query.Encode()
-	if err != nil {
-		return err
-	}
+	start := time.Now()
 	resp, err := h.Client.do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		bs, _ := httputil.DumpResponse(resp, true)
-		log.Println(string(bs))
-		return fmt.Errorf("Write failed: %d", resp.StatusCode)
+		dump, _ := httputil.DumpResponse(resp, true)
+		apiErr := newAPIError(resp)
+		apiErr.Response = string(dump)
+		if resp.StatusCode == http.StatusConflict {
+			return nil, h.conflictError(ctx, apiErr)
+		}
+		return nil, apiErr
 	}
 	rs, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	res.Latency = time.Since(start)
 	var w commitResponse
-	json.Unmarshal(rs, &w)
+	if err := json.Unmarshal(rs, &w); err != nil {
+		return nil, fmt.Errorf("decoding commit response: %w", err)
+	}
 	h.LatestServerIndex = w.ServerHeadIndex
-	return nil
+	res.ServerHeadIndex = w.ServerHeadIndex
+	return res, nil
 }
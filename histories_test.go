@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -144,3 +145,62 @@ func TestHistory_Sync(t *testing.T) {
 		}
 	})
 }
+
+func TestHistory_Write(t *testing.T) {
+	t.Parallel()
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, _ := io.ReadAll(r.Body)
+		body = string(bs)
+		fmt.Fprintln(w, `{"server-head-index":43}`)
+	}))
+	defer server.Close()
+
+	c := NewWithOptions(server.URL, "martin@example.com", "", WithoutValidation())
+	h := History{Client: c, ID: "33333abb-bfe4-4b03-a5c9-106d42220c72", LatestServerIndex: 42}
+	title := func(s string) TaskActionItemPayload { return TaskActionItemPayload{Title: String(s)} }
+	res, err := h.Write(
+		TaskActionItem{Item: Item{UUID: "b", Kind: ItemKindTask, Action: ItemActionCreated}, P: title("first")},
+		TaskActionItem{Item: Item{UUID: "a", Kind: ItemKindTask, Action: ItemActionCreated}, P: title("second")},
+		TaskActionItem{Item: Item{UUID: "b", Kind: ItemKindTask, Action: ItemActionModified}, P: title("third")},
+	)
+	if err != nil {
+		t.Fatalf("Expected write to succeed, but didn't: %q", err.Error())
+	}
+	if h.LatestServerIndex != 43 || res.ServerHeadIndex != 43 || res.AncestorIndex != 42 {
+		t.Errorf("Expected commit from 42 to 43, but got %d to %d (history at %d)", res.AncestorIndex, res.ServerHeadIndex, h.LatestServerIndex)
+	}
+	if res.Count != 2 || fmt.Sprint(res.UUIDs) != "[b a]" {
+		t.Errorf("Expected 2 items [b a], but got %d %v", res.Count, res.UUIDs)
+	}
+	if res.Items[0].Action != ItemActionModified || res.Items[0].Kind != ItemKindTask {
+		t.Errorf("Expected the later item to replace the earlier one, but got %#v", res.Items[0])
+	}
+	if res.Latency <= 0 {
+		t.Errorf("Expected latency to be measured, but got %s", res.Latency)
+	}
+	if c := res.Commit(); c.Index != 42 || len(c.Items) != 2 {
+		t.Errorf("Expected commit at index 42 with 2 items, but got %#v", c)
+	}
+	if !strings.Contains(body, `"tt":"third"`) || strings.Contains(body, `"tt":"first"`) {
+		t.Errorf("Expected the commit to carry the last version of every item, but got %s", body)
+	}
+}
+
+func TestHistory_WriteMalformedResponse(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `<html>maintenance</html>`)
+	}))
+	defer server.Close()
+
+	c := NewWithOptions(server.URL, "martin@example.com", "", WithoutValidation())
+	h := History{Client: c, ID: "33333abb-bfe4-4b03-a5c9-106d42220c72", LatestServerIndex: 42}
+	res, err := h.Write(TaskActionItem{Item: Item{UUID: "a", Kind: ItemKindTask, Action: ItemActionCreated}})
+	if err == nil {
+		t.Fatalf("Expected malformed response to fail, but got %#v", res)
+	}
+	if h.LatestServerIndex != 42 {
+		t.Errorf("Expected LatestServerIndex to be unchanged, but got %d", h.LatestServerIndex)
+	}
+}
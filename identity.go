@@ -0,0 +1,135 @@
+package thingscloud
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultAppID is the bundle id of Things for Mac
+	DefaultAppID = "com.culturedcode.ThingsMac"
+	// DefaultSchemaVersion is the highest item schema the SDK writes
+	DefaultSchemaVersion = 301
+	// DefaultPushPriority is the push priority Things for Mac sends with commits
+	DefaultPushPriority = 5
+)
+
+// WriterIdentity describes the device commits and app instance registrations are
+// attributed to. Every client carries one, so different tools using the SDK don't
+// look like the same device to thingscloud and other clients.
+type WriterIdentity struct {
+	// AppID is the bundle id of the writing app
+	AppID string
+	// AppInstanceID identifies the device, in the format {hash}-{AppID}-{hash}
+	AppInstanceID string
+	// Schema is the highest schema version the writer understands. Commits use the
+	// lower of Schema and the history's LatestSchemaVersion.
+	Schema int
+	// PushPriority is sent with every commit
+	PushPriority int
+}
+
+// DefaultWriterIdentity returns the identity of a Things for Mac client with the given
+// app instance id
+func DefaultWriterIdentity(appInstanceID string) WriterIdentity {
+	return WriterIdentity{
+		AppID:         DefaultAppID,
+		AppInstanceID: appInstanceID,
+		Schema:        DefaultSchemaVersion,
+		PushPriority:  DefaultPushPriority,
+	}
+}
+
+// NewAppInstanceID generates a random app instance id for the given app. Every call
+// yields a new id, so it has to be stored to keep the identity across runs, e.g. with
+// LoadAppInstanceID. Clients without an explicit id use a stable one instead, see
+// WithWriterIdentity.
+func NewAppInstanceID(appID string) string {
+	var device, install [32]byte
+	rand.Read(device[:])
+	rand.Read(install[:])
+	return hex.EncodeToString(device[:]) + "-" + appID + "-" + hex.EncodeToString(install[:])
+}
+
+// LoadAppInstanceID returns the app instance id stored at path. If there is no such
+// file, a new id is generated with NewAppInstanceID and stored there with mode 0600.
+func LoadAppInstanceID(path, appID string) (string, error) {
+	bs, err := readPrivateFile(path)
+	if err == nil {
+		if id := strings.TrimSpace(string(bs)); id != "" {
+			return id, nil
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	id := NewAppInstanceID(appID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	if err := writePrivateFile(path, []byte(id+"\n")); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// WithWriterIdentity sets the identity used for commits and app instance registration.
+// Empty fields are filled in with defaults. A missing app instance id is derived from
+// the host name, the user's home directory, the account email, the app id and the
+// client's ClientInfo: it stays the same across runs of a tool on one machine and
+// differs between machines and users. Tools sharing a machine and account get
+// their own id by sending their own ClientInfo, or by storing one with
+// LoadAppInstanceID.
+func WithWriterIdentity(id WriterIdentity) Option {
+	return func(c *Client) {
+		c.Identity = id
+	}
+}
+
+// completeIdentity fills in the missing fields of c.Identity
+func (c *Client) completeIdentity() {
+	if c.Identity.AppID == "" {
+		c.Identity.AppID = DefaultAppID
+	}
+	if c.Identity.AppInstanceID == "" {
+		c.Identity.AppInstanceID = stableAppInstanceID(c.Identity.AppID, c.EMail, c.ClientInfo)
+	}
+	if c.Identity.Schema == 0 {
+		c.Identity.Schema = DefaultSchemaVersion
+	}
+	if c.Identity.PushPriority == 0 {
+		c.Identity.PushPriority = DefaultPushPriority
+	}
+}
+
+// stableAppInstanceID derives the default app instance id of a client, see
+// WithWriterIdentity. Without a host name and home directory to tell installs apart,
+// a random id is used.
+func stableAppInstanceID(appID, email string, ci ClientInfo) string {
+	host, _ := os.Hostname()
+	home, _ := os.UserHomeDir()
+	if host == "" && home == "" {
+		return NewAppInstanceID(appID)
+	}
+	info, _ := json.Marshal(ci)
+	device := sha256.Sum256([]byte(host + "\x00" + home))
+	install := sha256.Sum256([]byte(strings.Join([]string{host, home, email, appID, string(info)}, "\x00")))
+	return hex.EncodeToString(device[:]) + "-" + appID + "-" + hex.EncodeToString(install[:])
+}
+
+// schemaVersion negotiates the schema a commit to h is written with
+func (h *History) schemaVersion() string {
+	schema := h.Client.Identity.Schema
+	if h.LatestSchemaVersion > 0 && h.LatestSchemaVersion < schema {
+		schema = h.LatestSchemaVersion
+	}
+	return strconv.Itoa(schema)
+}
@@ -0,0 +1,131 @@
+package thingscloud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewAppInstanceID(t *testing.T) {
+	t.Parallel()
+	id := NewAppInstanceID(DefaultAppID)
+	if id == NewAppInstanceID(DefaultAppID) {
+		t.Errorf("Expected every install to get its own app instance id")
+	}
+	parts := strings.Split(id, "-"+DefaultAppID+"-")
+	if len(parts) != 2 || len(parts[0]) != 64 || len(parts[1]) != 64 {
+		t.Errorf("Expected {hash}-{bundleId}-{hash}, but got %q", id)
+	}
+
+}
+
+func TestClient_DefaultAppInstanceID(t *testing.T) {
+	t.Parallel()
+	a, b := New("", "martin@example.com", ""), New("", "martin@example.com", "")
+	if a.Identity.AppInstanceID != b.Identity.AppInstanceID {
+		t.Errorf("Expected clients to keep their app instance id across runs, got %q and %q", a.Identity.AppInstanceID, b.Identity.AppInstanceID)
+	}
+	if parts := strings.Split(a.Identity.AppInstanceID, "-"+DefaultAppID+"-"); len(parts) != 2 || len(parts[0]) != 64 || len(parts[1]) != 64 {
+		t.Errorf("Expected {hash}-{bundleId}-{hash}, but got %q", a.Identity.AppInstanceID)
+	}
+
+	ci := DefaultClientInfo()
+	ci.AppName = "things-cli"
+	for _, c := range []*Client{
+		New("", "anna@example.com", ""),
+		NewWithOptions("", "martin@example.com", "", WithClientInfo(ci)),
+		NewWithOptions("", "martin@example.com", "", WithWriterIdentity(WriterIdentity{AppID: "com.example.tool"})),
+	} {
+		if c.Identity.AppInstanceID == a.Identity.AppInstanceID {
+			t.Errorf("Expected a different account, ClientInfo or app to get its own app instance id")
+		}
+	}
+}
+
+func TestLoadAppInstanceID(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "things", "app-instance-id")
+	id, err := LoadAppInstanceID(path, DefaultAppID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := LoadAppInstanceID(path, DefaultAppID); err != nil || again != id {
+		t.Errorf("Expected the stored id %q, got %q, %v", id, again, err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the id to be stored with mode 0600, got %v", fi.Mode())
+	}
+}
+
+func TestHistory_WriteIdentity(t *testing.T) {
+	t.Parallel()
+	var header http.Header
+	var registered string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			registered = r.URL.Path
+		} else {
+			header = r.Header.Clone()
+			w.Write([]byte(`{"server-head-index":1}`))
+		}
+	}))
+	defer ts.Close()
+
+	t.Run("Defaults", func(t *testing.T) {
+		c := New(ts.URL, "martin@example.com", "")
+		h := &History{Client: c, ID: "history"}
+		if _, err := h.Write(); err != nil {
+			t.Fatal(err)
+		}
+		if got := header.Get("App-Instance-Id"); got != c.Identity.AppInstanceID || !strings.Contains(got, DefaultAppID) {
+			t.Errorf("Expected App-Instance-Id %q, but got %q", c.Identity.AppInstanceID, got)
+		}
+		if got := header.Get("App-Id"); got != DefaultAppID {
+			t.Errorf("Expected App-Id %q, but got %q", DefaultAppID, got)
+		}
+		if got := header.Get("Schema"); got != "301" {
+			t.Errorf("Expected Schema 301, but got %q", got)
+		}
+		if got := header.Get("Push-Priority"); got != "5" {
+			t.Errorf("Expected Push-Priority 5, but got %q", got)
+		}
+
+		if err := c.RegisterAppInstance(AppInstanceRequest{HistoryKey: "history", APNSToken: "token"}); err != nil {
+			t.Fatal(err)
+		}
+		if registered != "/version/1/app-instance/"+c.Identity.AppInstanceID {
+			t.Errorf("Expected registration to share the commit identity, but got %q", registered)
+		}
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		c := NewWithOptions(ts.URL, "martin@example.com", "", WithWriterIdentity(WriterIdentity{
+			AppID:         "com.example.tool",
+			AppInstanceID: "a-com.example.tool-b",
+			PushPriority:  1,
+		}))
+		h := &History{Client: c, ID: "history", LatestSchemaVersion: 300}
+		if _, err := h.Write(); err != nil {
+			t.Fatal(err)
+		}
+		if got := header.Get("App-Instance-Id"); got != "a-com.example.tool-b" {
+			t.Errorf("Expected custom App-Instance-Id, but got %q", got)
+		}
+		if got := header.Get("App-Id"); got != "com.example.tool" {
+			t.Errorf("Expected custom App-Id, but got %q", got)
+		}
+		if got := header.Get("Schema"); got != "300" {
+			t.Errorf("Expected Schema to be negotiated down to 300, but got %q", got)
+		}
+		if got := header.Get("Push-Priority"); got != "1" {
+			t.Errorf("Expected Push-Priority 1, but got %q", got)
+		}
+	})
+}
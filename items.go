@@ -1,9 +1,11 @@
 package thingscloud
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"iter"
 	"net/http"
 	"strconv"
 )
@@ -18,13 +20,61 @@ type Item struct {
 	Action ItemAction      `json:"t"`
 }
 
+// commitItems holds the items of a single commit. Unlike a map it keeps the items
+// in the order the server sent them.
+type commitItems []Item
+
+// UnmarshalJSON decodes a {"uuid": item, ...} object, preserving key order
+func (c *commitItems) UnmarshalJSON(bs []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		*c = nil
+		return nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return fmt.Errorf("commit: expected object, got %v", tok)
+	}
+	items := commitItems{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var item Item
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+		item.UUID = tok.(string)
+		items = append(items, item)
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	*c = items
+	return nil
+}
+
+// DecodeCommitItems decodes the items of a single commit, a {"uuid": item, ...} object
+// as sent to and returned by thingscloud, in the order they appear
+func DecodeCommitItems(bs []byte) ([]Item, error) {
+	var items commitItems
+	if err := json.Unmarshal(bs, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 type itemsResponse struct {
-	Items                  []map[string]Item `json:"items"`
-	LatestTotalContentSize int               `json:"latest-total-content-size"`
-	StartTotalContentSize  int               `json:"start-total-content-size"`
-	EndTotalContentSize    int               `json:"end-total-content-size"`
-	SchemaVersion          int               `json:"schema"`
-	CurrentItemIndex       int               `json:"current-item-index"`
+	Items                  []commitItems `json:"items"`
+	LatestTotalContentSize int           `json:"latest-total-content-size"`
+	StartTotalContentSize  int           `json:"start-total-content-size"`
+	EndTotalContentSize    int           `json:"end-total-content-size"`
+	SchemaVersion          int           `json:"schema"`
+	CurrentItemIndex       int           `json:"current-item-index"`
 }
 
 // ItemsOptions allows a client to pickup changes from a specific index
@@ -32,49 +82,209 @@ type ItemsOptions struct {
 	StartIndex int
 }
 
+// Commit is a single entry of a history. All items of a commit were written together.
+type Commit struct {
+	// Index is the server index of the commit, i.e. the start-index at which it is returned first
+	Index int
+	// Items are the items of the commit, in the order the server sent them
+	Items []Item
+}
+
 // Items fetches changes from thingscloud. Every change contains multiple items which have been modified.
 // The Items method unwraps these objects and returns a list instead.
 //
 // Note that if a item was changed multiple times it will be present multiple times in the result too.
 func (h *History) Items(opts ItemsOptions) ([]Item, bool, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("/version/1/history/%s/items", h.ID), nil)
+	return h.ItemsContext(context.Background(), opts)
+}
 
-	values := req.URL.Query()
-	values.Set("start-index", strconv.Itoa(opts.StartIndex))
-	req.URL.RawQuery = values.Encode()
+// ItemsContext is like Items but carries a context for cancellation and deadlines
+func (h *History) ItemsContext(ctx context.Context, opts ItemsOptions) ([]Item, bool, error) {
+	commits, hasMore, err := h.CommitsContext(ctx, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	var items = []Item{}
+	for _, c := range commits {
+		items = append(items, c.Items...)
+	}
+	return items, hasMore, nil
+}
+
+// Commits fetches changes from thingscloud like Items, but keeps the commit boundaries
+// and the server index of every commit.
+func (h *History) Commits(opts ItemsOptions) ([]Commit, bool, error) {
+	return h.CommitsContext(context.Background(), opts)
+}
 
+// CommitsContext is like Commits but carries a context for cancellation and deadlines
+func (h *History) CommitsContext(ctx context.Context, opts ItemsOptions) ([]Commit, bool, error) {
+	var commits = []Commit{}
+	v, err := h.streamPage(ctx, opts.StartIndex, func(c Commit) bool {
+		commits = append(commits, c)
+		return true
+	})
 	if err != nil {
 		return nil, false, err
 	}
+	h.LoadedServerIndex = h.LoadedServerIndex + len(commits)
+	h.updateServerIndex(v)
+	hasMoreItems := h.LoadedServerIndex < h.LatestServerIndex
+	return commits, hasMoreItems, nil
+}
+
+// All returns an iterator over every item from startIndex up to the server head.
+// Pages are requested as the iteration proceeds and decoded while they are read,
+// so memory use does not grow with the size of the history. Stopping the iteration
+// early cancels the remaining requests.
+//
+// The first error ends the iteration. LoadedServerIndex points behind the last
+// commit whose items were all yielded, so resuming from it after stopping neither
+// repeats nor skips items of whole commits.
+func (h *History) All(ctx context.Context, startIndex int) iter.Seq2[Item, error] {
+	return func(yield func(Item, error) bool) {
+		for c, err := range h.AllCommits(ctx, startIndex) {
+			if err != nil {
+				yield(Item{}, err)
+				return
+			}
+			// the commit only counts as consumed once its last item was yielded
+			h.LoadedServerIndex = c.Index
+			for i, item := range c.Items {
+				if i == len(c.Items)-1 {
+					h.LoadedServerIndex = c.Index + 1
+				}
+				if !yield(item, nil) {
+					return
+				}
+			}
+			h.LoadedServerIndex = c.Index + 1
+		}
+	}
+}
+
+// AllCommits is like All, but yields whole commits together with their server index.
+// LoadedServerIndex points behind the last commit yielded.
+func (h *History) AllCommits(ctx context.Context, startIndex int) iter.Seq2[Commit, error] {
+	return func(yield func(Commit, error) bool) {
+		h.LoadedServerIndex = startIndex
+		for {
+			n, stopped := 0, false
+			v, err := h.streamPage(ctx, h.LoadedServerIndex, func(c Commit) bool {
+				n++
+				h.LoadedServerIndex = c.Index + 1
+				if !yield(c, nil) {
+					stopped = true
+					return false
+				}
+				return true
+			})
+			if stopped {
+				return
+			}
+			if err != nil {
+				yield(Commit{}, err)
+				return
+			}
+			h.updateServerIndex(v)
+			if n == 0 || h.LoadedServerIndex >= h.LatestServerIndex {
+				return
+			}
+		}
+	}
+}
+
+// updateServerIndex copies the server position of a page into the history
+func (h *History) updateServerIndex(v itemsResponse) {
+	h.LatestServerIndex = v.CurrentItemIndex
+	h.EndTotalContentSize = v.EndTotalContentSize
+	h.LatestTotalContentSize = v.LatestTotalContentSize
+}
+
+// streamPage requests the page of commits starting at startIndex and passes every
+// commit to fn as soon as it is decoded. If fn returns false the rest of the page
+// is discarded. The returned response holds everything but the items.
+func (h *History) streamPage(ctx context.Context, startIndex int, fn func(Commit) bool) (itemsResponse, error) {
+	var v itemsResponse
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("/version/1/history/%s/items", h.ID), nil)
+	if err != nil {
+		return v, err
+	}
+
+	values := req.URL.Query()
+	values.Set("start-index", strconv.Itoa(startIndex))
+	req.URL.RawQuery = values.Encode()
+
 	resp, err := h.Client.do(req)
 	if err != nil {
-		return nil, false, err
+		return v, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, false, fmt.Errorf("http response code: %s", resp.Status)
+		return v, newAPIError(resp)
 	}
 
-	bs, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, false, err
+	fields := map[string]any{
+		"latest-total-content-size": &v.LatestTotalContentSize,
+		"start-total-content-size":  &v.StartTotalContentSize,
+		"end-total-content-size":    &v.EndTotalContentSize,
+		"schema":                    &v.SchemaVersion,
+		"current-item-index":        &v.CurrentItemIndex,
 	}
-	var v itemsResponse
-	if err := json.Unmarshal(bs, &v); err != nil {
-		return nil, false, err
+	dec := json.NewDecoder(resp.Body)
+	if err := expectDelim(dec, '{'); err != nil {
+		return v, err
 	}
-	var items = []Item{}
-	for _, m := range v.Items {
-		for id, item := range m {
-			item.UUID = id
-			items = append(items, item)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return v, err
+		}
+		key, _ := tok.(string)
+		if key != "items" {
+			dst, ok := fields[key]
+			if !ok {
+				dst = new(json.RawMessage)
+			}
+			if err := dec.Decode(dst); err != nil {
+				return v, err
+			}
+			continue
+		}
+		if tok, err := dec.Token(); err != nil {
+			return v, err
+		} else if tok == nil {
+			continue
+		} else if d, ok := tok.(json.Delim); !ok || d != '[' {
+			return v, fmt.Errorf("items: expected array, got %v", tok)
+		}
+		for i := startIndex; dec.More(); i++ {
+			var items commitItems
+			if err := dec.Decode(&items); err != nil {
+				return v, err
+			}
+			if d := h.Client.drift; d != nil {
+				d.Observe(items...)
+			}
+			if !fn(Commit{Index: i, Items: items}) {
+				return v, nil
+			}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return v, err
 		}
 	}
-	h.LoadedServerIndex = h.LoadedServerIndex + len(v.Items)
-	h.LatestServerIndex = v.CurrentItemIndex
-	h.EndTotalContentSize = v.EndTotalContentSize
-	h.LatestTotalContentSize = v.LatestTotalContentSize
-	hasMoreItems := h.LoadedServerIndex < h.LatestServerIndex
-	return items, hasMoreItems, nil
+	return v, expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %v, got %v", delim, tok)
+	}
+	return nil
 }
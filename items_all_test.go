@@ -0,0 +1,114 @@
+package thingscloud_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	thingscloud "github.com/arthursoares/things-cloud-sdk"
+	"github.com/arthursoares/things-cloud-sdk/thingscloudtest"
+)
+
+func TestHistory_All(t *testing.T) {
+	t.Parallel()
+	s := thingscloudtest.NewServer("martin@example.com", "secret")
+	defer s.Close()
+	s.SetPageSize(2)
+	for i := 0; i < 5; i++ {
+		seedTask(t, s, fmt.Sprintf("task-%d", i), "task")
+	}
+
+	t.Run("AllPages", func(t *testing.T) {
+		h := s.Client().HistoryWithID(s.OwnHistoryKey())
+		var uuids []string
+		for item, err := range h.All(context.Background(), 1) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			uuids = append(uuids, item.UUID)
+		}
+		if len(uuids) != 4 || uuids[0] != "task-1" || uuids[3] != "task-4" {
+			t.Errorf("Expected task-1 to task-4, but got %v", uuids)
+		}
+		if h.LoadedServerIndex != 5 || h.LatestServerIndex != 5 {
+			t.Errorf("Expected history to be loaded up to 5, but got %d/%d", h.LoadedServerIndex, h.LatestServerIndex)
+		}
+	})
+
+	t.Run("StopEarly", func(t *testing.T) {
+		h := s.Client().HistoryWithID(s.OwnHistoryKey())
+		var indexes []int
+		for c, err := range h.AllCommits(context.Background(), 0) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			indexes = append(indexes, c.Index)
+			if c.Index == 2 {
+				break
+			}
+		}
+		if fmt.Sprint(indexes) != "[0 1 2]" {
+			t.Errorf("Expected commits 0 to 2, but got %v", indexes)
+		}
+		if h.LoadedServerIndex != 3 {
+			t.Errorf("Expected LoadedServerIndex of 3, but got %d", h.LoadedServerIndex)
+		}
+
+		// stopping on the last item of a commit resumes after it
+		for item, err := range h.All(context.Background(), 0) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.UUID == "task-2" {
+				break
+			}
+		}
+		if h.LoadedServerIndex != 3 {
+			t.Errorf("Expected LoadedServerIndex of 3 after the last item of commit 2, but got %d", h.LoadedServerIndex)
+		}
+		for item, err := range h.All(context.Background(), h.LoadedServerIndex) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.UUID != "task-3" {
+				t.Errorf("Expected to resume with task-3, but got %s", item.UUID)
+			}
+			break
+		}
+	})
+
+	t.Run("StopWithinCommit", func(t *testing.T) {
+		s := thingscloudtest.NewServer("martin@example.com", "secret")
+		defer s.Close()
+		p, _ := json.Marshal(thingscloud.TaskActionItemPayload{Title: thingscloud.String("task")})
+		if _, err := s.Seed(s.OwnHistoryKey(), thingscloud.Item{UUID: "a", Kind: thingscloud.ItemKindTask, P: p}, thingscloud.Item{UUID: "b", Kind: thingscloud.ItemKindTask, P: p}); err != nil {
+			t.Fatal(err)
+		}
+		h := s.Client().HistoryWithID(s.OwnHistoryKey())
+		for _, err := range h.All(context.Background(), 0) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			break
+		}
+		if h.LoadedServerIndex != 0 {
+			t.Errorf("Expected the partly consumed commit to be delivered again, but got LoadedServerIndex %d", h.LoadedServerIndex)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		h := s.Client().HistoryWithID(s.OwnHistoryKey())
+		s.FailNext(http.StatusBadGateway)
+		var n int
+		var last error
+		for _, err := range h.All(context.Background(), 0) {
+			n++
+			last = err
+		}
+		if n != 1 || !thingscloud.IsRetryable(last) {
+			t.Errorf("Expected a single error, but got %d values ending in %v", n, last)
+		}
+	})
+}
@@ -26,3 +26,35 @@ func TestHistory_Items(t *testing.T) {
 		}
 	})
 }
+
+func TestHistory_Commits(t *testing.T) {
+	t.Parallel()
+	server := fakeServer(fakeResponse{200, "history-items-success.json"})
+	defer server.Close()
+
+	c := New(fmt.Sprintf("http://%s", server.Listener.Addr().String()), "martin@example.com", "")
+	h := &History{
+		Client:            c,
+		ID:                "33333abb-bfe4-4b03-a5c9-106d42220c72",
+		LoadedServerIndex: 10,
+	}
+	commits, _, err := h.Commits(ItemsOptions{StartIndex: 10})
+	if err != nil {
+		t.Fatalf("Expected commits request to succeed, but didn't: %q", err.Error())
+	}
+	if len(commits) != 5 {
+		t.Fatalf("Expected 5 commits, but got %d", len(commits))
+	}
+	for i, commit := range commits {
+		if commit.Index != 10+i {
+			t.Errorf("Expected commit %d to have index %d, but got %d", i, 10+i, commit.Index)
+		}
+	}
+	got := commits[3].Items
+	if len(got) != 2 || got[0].UUID != "12984C5D-F287-4DDE-AE80-8E05C87B441E" || got[1].UUID != "Settings" {
+		t.Errorf("Expected items of the fourth commit in server order, but got %#v", got)
+	}
+	if h.LoadedServerIndex != 15 {
+		t.Errorf("Expected LoadedServerIndex of 15, but got %d", h.LoadedServerIndex)
+	}
+}
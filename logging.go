@@ -0,0 +1,197 @@
+package thingscloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxLogBodySize limits the bodies attached to debug log records
+const maxLogBodySize = 64 << 10
+
+const (
+	redacted = "[REDACTED]"
+	masked   = "[MASKED]"
+)
+
+// sensitiveHeaders are never written to logs
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// secretKeys are JSON keys whose values are never written to logs
+var secretKeys = map[string]bool{"password": true, "apns-token": true}
+
+// contentKeys are JSON keys holding user content, masked with WithMaskedContent:
+// titles, notes, checklist or tag names and the account's email addresses
+var contentKeys = map[string]bool{"tt": true, "nt": true, "sh": true, "email": true, "maildrop-email": true}
+
+// WithStructuredLogger makes the client emit a log record for every request it sends,
+// carrying method, path, status, duration, body sizes and schema. Requests which
+// succeeded are logged at debug level, failures as warnings. With Debug set, redacted
+// request and response bodies are attached as well.
+//
+// Credentials are always redacted. Use WithMaskedContent to mask titles and notes too.
+func WithStructuredLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		c.slog = l
+	}
+}
+
+// WithMaskedContent masks task titles, notes and the account email in debug output,
+// so logs can be shared without exposing personal data
+func WithMaskedContent() Option {
+	return func(c *Client) {
+		c.maskContent = true
+	}
+}
+
+// redactBody removes secrets, and with content masking user content, from a JSON body.
+// Bodies which are not JSON are returned unchanged.
+func (c *Client) redactBody(bs []byte) []byte {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return bs
+	}
+	out, err := json.Marshal(c.redactValue(v))
+	if err != nil {
+		return bs
+	}
+	return out
+}
+
+func (c *Client) redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			switch {
+			case secretKeys[k]:
+				v[k] = redacted
+			case c.maskContent && contentKeys[k] && e != nil:
+				v[k] = masked
+			default:
+				v[k] = c.redactValue(e)
+			}
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = c.redactValue(e)
+		}
+	}
+	return v
+}
+
+// redactPath masks the account email in a request path
+func (c *Client) redactPath(path string) string {
+	if !c.maskContent || c.EMail == "" {
+		return path
+	}
+	path = strings.ReplaceAll(path, url.PathEscape(c.EMail), masked)
+	return strings.ReplaceAll(path, c.EMail, masked)
+}
+
+// redactDump redacts a request or response dumped by httputil
+func (c *Client) redactDump(dump []byte) string {
+	head, body, _ := bytes.Cut(dump, []byte("\r\n\r\n"))
+	lines := strings.Split(string(head), "\r\n")
+	for i, line := range lines {
+		if i == 0 {
+			lines[i] = c.redactPath(line)
+			continue
+		}
+		for _, h := range sensitiveHeaders {
+			if name, _, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, h) {
+				lines[i] = name + ": " + redacted
+			}
+		}
+	}
+	out := strings.Join(lines, "\r\n") + "\r\n\r\n"
+	if len(body) > 0 {
+		out += string(c.redactBody(body))
+	}
+	return out
+}
+
+// logRequest emits the structured record of a finished request
+func (c *Client) logRequest(req *http.Request, resp *http.Response, start time.Time, respBytes int64, err error, reqBody, respBody []byte) {
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("path", c.redactPath(req.URL.Path)),
+		slog.Duration("duration", time.Since(start)),
+		slog.Int64("request_bytes", max(req.ContentLength, 0)),
+	}
+	if schema := req.Header.Get("Schema"); schema != "" {
+		attrs = append(attrs, slog.String("schema", schema))
+	}
+	level := slog.LevelDebug
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode), slog.Int64("response_bytes", respBytes))
+		if resp.StatusCode >= 400 {
+			level = slog.LevelWarn
+		}
+		for _, h := range requestIDHeaders {
+			if id := resp.Header.Get(h); id != "" {
+				attrs = append(attrs, slog.String("request_id", id))
+				break
+			}
+		}
+	}
+	if c.Debug {
+		if body := c.logBody(reqBody); body != "" {
+			attrs = append(attrs, slog.String("request_body", body))
+		}
+		if body := c.logBody(respBody); body != "" {
+			attrs = append(attrs, slog.String("response_body", body))
+		}
+	}
+	c.slog.LogAttrs(req.Context(), level, "thingscloud request", attrs...)
+}
+
+func (c *Client) logBody(bs []byte) string {
+	if len(bs) > maxLogBodySize {
+		return "[body too large]"
+	}
+	return string(c.redactBody(bs))
+}
+
+// loggedBody counts, and in debug mode captures, a response body. The request is
+// logged once the body is closed.
+type loggedBody struct {
+	io.ReadCloser
+	c       *Client
+	req     *http.Request
+	resp    *http.Response
+	start   time.Time
+	reqBody []byte
+
+	n      int64
+	buf    bytes.Buffer
+	logged bool
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if b.c.Debug && b.buf.Len() <= maxLogBodySize {
+		b.buf.Write(p[:n])
+	}
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if !b.logged {
+		b.logged = true
+		b.c.logRequest(b.req, b.resp, b.start, b.n, nil, b.reqBody, b.buf.Bytes())
+	}
+	return err
+}
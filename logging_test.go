@@ -0,0 +1,116 @@
+package thingscloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_StructuredLogging(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-1")
+		fmt.Fprint(w, `{"server-head-index":1}`)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c := NewWithOptions(server.URL, "martin@example.com", "secret", WithStructuredLogger(logger), WithMaskedContent(), WithoutValidation())
+	c.Debug = true
+
+	h := &History{Client: c, ID: "history"}
+	_, err := h.Write(TaskActionItem{
+		Item: Item{UUID: "task", Kind: ItemKindTask, Action: ItemActionCreated},
+		P:    TaskActionItemPayload{Title: String("Buy milk"), Note: json.RawMessage(`{"_t":"tx","t":1,"v":"call mum"}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a single JSON record, but got %q", out.String())
+	}
+	for key, want := range map[string]any{
+		"method":     "POST",
+		"path":       "/version/1/history/history/commit",
+		"status":     float64(200),
+		"schema":     "301",
+		"request_id": "req-1",
+	} {
+		if record[key] != want {
+			t.Errorf("Expected %s to be %v, but got %v", key, want, record[key])
+		}
+	}
+	if record["response_bytes"] != float64(len(`{"server-head-index":1}`)) {
+		t.Errorf("Expected response_bytes to be counted, but got %v", record["response_bytes"])
+	}
+	if _, ok := record["duration"]; !ok {
+		t.Errorf("Expected a duration, but got %v", record)
+	}
+	if body, _ := record["request_body"].(string); !strings.Contains(body, masked) {
+		t.Errorf("Expected the request body to be attached, but got %q", body)
+	}
+	for _, secret := range []string{"Buy milk", "call mum"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("Expected %q to be masked, but got %s", secret, out.String())
+		}
+	}
+
+	out.Reset()
+	c.Verify() //nolint:errcheck
+	if strings.Contains(out.String(), "martin@example.com") {
+		t.Errorf("Expected the email to be masked, but got %s", out.String())
+	}
+}
+
+func TestClient_MaskedEmail(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"email":"martin@example.com","maildrop-email":"add-to-things-x1@things.email","status":"SYAccountStatusActive"}`)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c := NewWithOptions(server.URL, "martin@example.com", "secret", WithStructuredLogger(logger), WithMaskedContent())
+	c.Debug = true
+	if _, err := c.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "SYAccountStatusActive") {
+		t.Fatalf("Expected the response body to be logged, but got %s", out.String())
+	}
+	for _, email := range []string{"martin@example.com", "add-to-things-x1@things.email"} {
+		if strings.Contains(out.String(), email) {
+			t.Errorf("Expected %q to be masked, but got %s", email, out.String())
+		}
+	}
+}
+
+func TestClient_DebugRedaction(t *testing.T) {
+	t.Parallel()
+	server := fakeServer(fakeResponse{200, "verify-success.json"})
+	defer server.Close()
+
+	var out bytes.Buffer
+	c := NewWithOptions(fmt.Sprintf("http://%s", server.Listener.Addr().String()), "martin@example.com", "secret",
+		WithLogger(log.New(&out, "", 0)))
+	c.Debug = true
+	if _, err := c.Accounts.ChangePassword("new-secret"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "secret") {
+		t.Errorf("Expected credentials to be redacted, but got %s", out.String())
+	}
+	if !strings.Contains(out.String(), "Authorization: "+redacted) {
+		t.Errorf("Expected the Authorization header to be redacted, but got %s", out.String())
+	}
+}
@@ -1,5 +1,11 @@
 package thingscloud
 
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
 // NoteTypeFullText indicates a note with complete text
 const NoteTypeFullText = 1
 
@@ -23,7 +29,13 @@ type Note struct {
 	Patches  []NotePatch `json:"ps,omitempty"`
 }
 
-// ApplyPatches applies a series of text patches to an original string
+// NoteChecksum returns the checksum Things.app stores with a note text
+func NoteChecksum(s string) int64 {
+	return int64(crc32.ChecksumIEEE([]byte(s)))
+}
+
+// ApplyPatches applies a series of text patches to an original string. Patches out of
+// range are clamped and checksums are ignored, see ApplyPatchesVerified.
 func ApplyPatches(original string, patches []NotePatch) string {
 	runes := []rune(original)
 	for _, p := range patches {
@@ -51,3 +63,200 @@ func ApplyPatches(original string, patches []NotePatch) string {
 	}
 	return string(runes)
 }
+
+// ErrNoteDiverged is matched by errors.Is when note patches do not fit the text they
+// are applied to, i.e. the local note differs from the one the patches were made for
+var ErrNoteDiverged = errors.New("note diverged")
+
+// NoteDivergedError is returned by ApplyPatchesVerified
+type NoteDivergedError struct {
+	// Patch is the index of the first patch which did not apply, or -1 if the note's
+	// own checksum did not match the resulting text
+	Patch int
+	// Checksum is the checksum the patch carries and Actual the one of the patched
+	// text. Both are 0 if the patch did not fit the text's range.
+	Checksum, Actual int64
+}
+
+func (e *NoteDivergedError) Error() string {
+	if e.Patch < 0 {
+		return fmt.Sprintf("%v: note expects checksum %d, got %d", ErrNoteDiverged, e.Checksum, e.Actual)
+	}
+	if e.Checksum == 0 && e.Actual == 0 {
+		return fmt.Sprintf("%v: patch %d is out of range", ErrNoteDiverged, e.Patch)
+	}
+	return fmt.Sprintf("%v: patch %d expects checksum %d, got %d", ErrNoteDiverged, e.Patch, e.Checksum, e.Actual)
+}
+
+// Is makes errors.Is(err, ErrNoteDiverged) match
+func (e *NoteDivergedError) Is(target error) bool {
+	return target == ErrNoteDiverged
+}
+
+// ApplyPatchesVerified is like ApplyPatches, but checks every patch instead of
+// clamping it: the replaced range must lie within the text, and the text after the
+// patch must have the patch's checksum. Patches without checksum (0) are only checked
+// for their range. On a mismatch it returns a *NoteDivergedError.
+func ApplyPatchesVerified(original string, patches []NotePatch) (string, error) {
+	runes := []rune(original)
+	for i, p := range patches {
+		if p.Position < 0 || p.Length < 0 || p.Position+p.Length > len(runes) {
+			return "", &NoteDivergedError{Patch: i}
+		}
+		result := make([]rune, 0, len(runes)-p.Length+len(p.Replacement))
+		result = append(result, runes[:p.Position]...)
+		result = append(result, []rune(p.Replacement)...)
+		result = append(result, runes[p.Position+p.Length:]...)
+		runes = result
+		if p.Checksum == 0 {
+			continue
+		}
+		if actual := NoteChecksum(string(runes)); actual != p.Checksum {
+			return "", &NoteDivergedError{Patch: i, Checksum: p.Checksum, Actual: actual}
+		}
+	}
+	return string(runes), nil
+}
+
+// maxDiffEdits bounds the edit distance DiffNotes searches for. Beyond it, the changed
+// region is replaced by a single patch.
+const maxDiffEdits = 1000
+
+// NewDeltaNote returns a delta note (t=2) turning old into new, see DiffNotes
+func NewDeltaNote(old, new string) Note {
+	return Note{TypeTag: "tx", Type: NoteTypeDelta, Patches: DiffNotes(old, new)}
+}
+
+// DiffNotes returns the patches turning old into new. Positions and lengths count
+// runes, and the patches are meant to be applied in order by ApplyPatches: each
+// position refers to the text with all earlier patches applied. The checksum of every
+// patch is the checksum of the text after applying it, so the last one matches new.
+// Equal texts produce no patches.
+func DiffNotes(old, new string) []NotePatch {
+	a, b := []rune(old), []rune(new)
+
+	// the common prefix and suffix never change
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	hunks := diffRunes(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	patches := make([]NotePatch, len(hunks))
+	for i, h := range hunks {
+		oldEnd := prefix + h.oldStart + h.oldLen
+		newStart := prefix + h.newStart
+		newEnd := newStart + h.newLen
+		text := string(b[:newEnd]) + string(a[oldEnd:])
+		patches[i] = NotePatch{
+			Replacement: string(b[newStart:newEnd]),
+			Position:    newStart,
+			Length:      h.oldLen,
+			Checksum:    NoteChecksum(text),
+		}
+	}
+	return patches
+}
+
+// hunk replaces oldLen runes at oldStart of the old text by newLen runes at newStart
+// of the new text
+type hunk struct {
+	oldStart, oldLen int
+	newStart, newLen int
+}
+
+// diffRunes returns the hunks of a shortest edit script turning a into b, using
+// Myers' O(ND) algorithm. Adjacent deletions and insertions are merged into one hunk.
+func diffRunes(a, b []rune) []hunk {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	if n == 0 || m == 0 {
+		return []hunk{{oldLen: n, newLen: m}}
+	}
+
+	limit := min(n+m, maxDiffEdits)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+	found := false
+	for d := 0; d <= limit && !found; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return []hunk{{oldLen: n, newLen: m}}
+	}
+
+	// walk the trace backwards, collecting the edits
+	type edit struct {
+		x, y   int
+		insert bool
+	}
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+		}
+		if prevK == k+1 {
+			edits = append(edits, edit{x: prevX, y: prevY, insert: true})
+		} else {
+			edits = append(edits, edit{x: prevX, y: prevY})
+		}
+		x, y = prevX, prevY
+	}
+
+	var hunks []hunk
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		if len(hunks) > 0 {
+			last := &hunks[len(hunks)-1]
+			if last.oldStart+last.oldLen == e.x && last.newStart+last.newLen == e.y {
+				if e.insert {
+					last.newLen++
+				} else {
+					last.oldLen++
+				}
+				continue
+			}
+		}
+		h := hunk{oldStart: e.x, newStart: e.y, oldLen: 1}
+		if e.insert {
+			h.oldLen, h.newLen = 0, 1
+		}
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
@@ -0,0 +1,212 @@
+package notes
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// autolinkSchemes are the URL schemes recognized in bare text
+var autolinkSchemes = []string{"https://", "http://", "things:", "mailto:"}
+
+// parseInlines parses the formatting of a single line
+func parseInlines(s string) []Inline {
+	var inlines []Inline
+	var text strings.Builder
+	emit := func(in Inline) {
+		if text.Len() > 0 {
+			inlines = append(inlines, Inline{Kind: InlineKindText, Text: text.String()})
+			text.Reset()
+		}
+		inlines = append(inlines, in)
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			text.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '`':
+			if in, n := parseCode(s[i:]); n > 0 {
+				emit(in)
+				i += n
+				continue
+			}
+		case c == '[':
+			if in, n := parseLink(s[i:]); n > 0 {
+				emit(in)
+				i += n
+				continue
+			}
+		case c == '<':
+			if end := strings.IndexByte(s[i:], '>'); end > 0 && hasScheme(s[i+1:i+end]) && !strings.ContainsAny(s[i+1:i+end], " \t<") {
+				u := s[i+1 : i+end]
+				emit(Inline{Kind: InlineKindLink, URL: u, Children: []Inline{{Kind: InlineKindText, Text: u}}})
+				i += end + 1
+				continue
+			}
+		case c == '*' || c == '_':
+			if in, n := parseEmphasis(s, i); n > 0 {
+				emit(in)
+				i += n
+				continue
+			}
+		case hasScheme(s[i:]) && (i == 0 || !isWordByte(s[i-1])):
+			if u := bareURL(s[i:]); u != "" {
+				emit(Inline{Kind: InlineKindLink, URL: u, Children: []Inline{{Kind: InlineKindText, Text: u}}})
+				i += len(u)
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		text.WriteString(s[i : i+size])
+		i += size
+	}
+	if text.Len() > 0 {
+		inlines = append(inlines, Inline{Kind: InlineKindText, Text: text.String()})
+	}
+	return inlines
+}
+
+// parseCode parses a code span starting with a run of backticks, returning the
+// number of bytes consumed or 0 if the run is not closed
+func parseCode(s string) (Inline, int) {
+	n := len(s) - len(strings.TrimLeft(s, "`"))
+	fence := s[:n]
+	for j := n; j < len(s); {
+		k := strings.Index(s[j:], fence)
+		if k < 0 {
+			break
+		}
+		k += j
+		run := len(s[k:]) - len(strings.TrimLeft(s[k:], "`"))
+		if run == n {
+			code := s[n:k]
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+				code = code[1 : len(code)-1]
+			}
+			return Inline{Kind: InlineKindCode, Text: code}, k + n
+		}
+		j = k + run
+	}
+	return Inline{}, 0
+}
+
+// parseLink parses [label](url), returning the number of bytes consumed or 0
+func parseLink(s string) (Inline, int) {
+	depth := 0
+	for j := 0; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			if j+1 >= len(s) || s[j+1] != '(' {
+				return Inline{}, 0
+			}
+			end := closingParen(s[j+1:])
+			if end < 0 {
+				return Inline{}, 0
+			}
+			u := strings.TrimSpace(s[j+2 : j+1+end])
+			u = strings.TrimSuffix(strings.TrimPrefix(u, "<"), ">")
+			return Inline{Kind: InlineKindLink, URL: u, Children: parseInlines(s[1:j])}, j + 2 + end
+		}
+	}
+	return Inline{}, 0
+}
+
+// closingParen returns the index of the parenthesis closing the one s starts with
+func closingParen(s string) int {
+	depth := 0
+	for j := 0; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// parseEmphasis parses *emphasis* or **strong** text starting at s[i], returning the
+// number of bytes consumed or 0 if the delimiter is not closed
+func parseEmphasis(s string, i int) (Inline, int) {
+	c := s[i]
+	n, kind := 1, InlineKindEmphasis
+	if i+1 < len(s) && s[i+1] == c {
+		n, kind = 2, InlineKindStrong
+	}
+	delim := s[i : i+n]
+	start := i + n
+	// the opening delimiter must be followed by text, and _ must not be within a word
+	if start >= len(s) || s[start] == ' ' || s[start] == '\t' || (c == '_' && i > 0 && isWordByte(s[i-1])) {
+		return Inline{}, 0
+	}
+	for j := start; j+n <= len(s); j++ {
+		if s[j] == '\\' {
+			// escaped delimiters don't close
+			j++
+			continue
+		}
+		if j == start || s[j:j+n] != delim || s[j-1] == ' ' || s[j-1] == '\t' {
+			continue
+		}
+		// a single delimiter must not be part of a double one
+		if n == 1 && ((j+1 < len(s) && s[j+1] == c) || s[j-1] == c) {
+			j++
+			continue
+		}
+		if c == '_' && j+n < len(s) && isWordByte(s[j+n]) {
+			continue
+		}
+		return Inline{Kind: kind, Children: parseInlines(s[start:j])}, j + n - i
+	}
+	return Inline{}, 0
+}
+
+// bareURL returns the URL s starts with, without trailing punctuation
+func bareURL(s string) string {
+	end := strings.IndexFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == '<' })
+	if end < 0 {
+		end = len(s)
+	}
+	u := strings.TrimRight(s[:end], ".,;:!?'\"")
+	for strings.HasSuffix(u, ")") && strings.Count(u, ")") > strings.Count(u, "(") {
+		u = strings.TrimRight(u[:len(u)-1], ".,;:!?'\"")
+	}
+	if hasScheme(u) && len(u) > strings.IndexByte(u, ':')+1 {
+		return u
+	}
+	return ""
+}
+
+func hasScheme(s string) bool {
+	for _, scheme := range autolinkSchemes {
+		if len(s) >= len(scheme) && strings.EqualFold(s[:len(scheme)], scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 0x80 || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// isPunct reports whether c is ASCII punctuation, which can be escaped with a backslash
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
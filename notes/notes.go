@@ -0,0 +1,287 @@
+// Package notes parses task notes into a small Markdown AST and renders them as
+// HTML, Markdown or plain text. It understands the Markdown-like formatting
+// Things.app supports (headings, lists, checklist lines, emphasis, code and links,
+// including things:/// deep links) and the legacy <note xml:space="preserve"> form
+// older clients wrote.
+package notes
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BlockKind is the kind of a Block
+type BlockKind int
+
+const (
+	// BlockKindParagraph is a run of text lines
+	BlockKindParagraph BlockKind = iota
+	// BlockKindHeading is a line starting with one to six #
+	BlockKindHeading
+	// BlockKindListItem is a bulleted, numbered or checklist line
+	BlockKindListItem
+	// BlockKindCode is a fenced code block
+	BlockKindCode
+	// BlockKindQuote is a run of lines starting with >
+	BlockKindQuote
+)
+
+// InlineKind is the kind of an Inline
+type InlineKind int
+
+const (
+	// InlineKindText is literal text
+	InlineKindText InlineKind = iota
+	// InlineKindBreak is a line break within a block
+	InlineKindBreak
+	// InlineKindEmphasis is *emphasized* text
+	InlineKindEmphasis
+	// InlineKindStrong is **strong** text
+	InlineKindStrong
+	// InlineKindCode is a `code` span
+	InlineKindCode
+	// InlineKindLink is a [label](url) link or a bare URL
+	InlineKindLink
+)
+
+// Document is a parsed note
+type Document struct {
+	Blocks []Block
+}
+
+// Block is a line level element of a note
+type Block struct {
+	Kind BlockKind
+	// Level is the heading level (1-6), or the nesting depth of a list item starting at 0
+	Level int
+	// Ordered marks numbered list items, Number is their number
+	Ordered bool
+	Number  int
+	// Task marks checklist lines such as "- [ ] milk", Checked whether they are done
+	Task    bool
+	Checked bool
+	// Lang is the info string and Code the verbatim content of a code block
+	Lang string
+	Code string
+	// Inlines is the content of paragraphs, headings, list items and quotes
+	Inlines []Inline
+}
+
+// Inline is a span of formatted text within a Block
+type Inline struct {
+	Kind InlineKind
+	// Text is the content of text and code inlines
+	Text string
+	// URL is the target of links
+	URL string
+	// Children is the content of emphasis, strong and link inlines
+	Children []Inline
+}
+
+// Link is a link found in a note
+type Link struct {
+	// Text is the plain text label, which equals URL for bare URLs
+	Text string
+	URL  string
+}
+
+// ThingsID returns the id of the item a things:/// deep link shows, e.g. the UUID in
+// things:///show?id=UUID
+func (l Link) ThingsID() (string, bool) {
+	u, err := url.Parse(l.URL)
+	if err != nil || u.Scheme != "things" {
+		return "", false
+	}
+	id := u.Query().Get("id")
+	return id, id != ""
+}
+
+// ChecklistLine is a checklist-like line of a note, e.g. "- [x] milk" or "☐ eggs"
+type ChecklistLine struct {
+	Text    string
+	Checked bool
+}
+
+// Unwrap returns the text of a note, unwrapping the legacy XML form
+// <note xml:space="preserve">...</note>. Other notes are returned unchanged.
+func Unwrap(note string) string {
+	s := strings.TrimSpace(note)
+	if !strings.HasPrefix(s, "<note") || !strings.HasSuffix(s, "</note>") {
+		return note
+	}
+	var b strings.Builder
+	dec := xml.NewDecoder(strings.NewReader(s))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return b.String()
+		}
+		if err != nil {
+			return note
+		}
+		if cd, ok := tok.(xml.CharData); ok {
+			b.Write(cd)
+		}
+	}
+}
+
+var (
+	headingRe = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	listRe    = regexp.MustCompile(`^([ \t]*)([-*+•]|\d{1,9}[.)])[ \t]+(.*)$`)
+	taskRe    = regexp.MustCompile(`^(?:\[([ xX])\]|([☐☑☒]))[ \t]+(.*)$`)
+	fenceRe   = regexp.MustCompile("^[ \t]*(```+|~~~+)[ \t]*([^ \t`]*)")
+)
+
+// Parse parses a note as stored in Task.Note, in either the legacy XML or the text form
+func Parse(note string) *Document {
+	lines := strings.Split(strings.ReplaceAll(Unwrap(note), "\r\n", "\n"), "\n")
+	p := parser{doc: &Document{}}
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			p.flush()
+			p.indents = nil
+		case fenceRe.MatchString(line):
+			p.flush()
+			m := fenceRe.FindStringSubmatch(line)
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]); i++ {
+				code = append(code, lines[i])
+			}
+			p.add(Block{Kind: BlockKindCode, Lang: m[2], Code: strings.Join(code, "\n")})
+		case headingRe.MatchString(trimmed):
+			p.flush()
+			m := headingRe.FindStringSubmatch(trimmed)
+			p.add(Block{Kind: BlockKindHeading, Level: len(m[1]), Inlines: parseInlines(m[2])})
+		case strings.HasPrefix(trimmed, ">"):
+			p.flush()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote = append(quote, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			i--
+			p.add(Block{Kind: BlockKindQuote, Inlines: parseLines(quote)})
+		case listRe.MatchString(line):
+			p.flush()
+			m := listRe.FindStringSubmatch(line)
+			b := Block{Kind: BlockKindListItem, Level: p.level(indentWidth(m[1]))}
+			if n, err := strconv.Atoi(strings.TrimRight(m[2], ".)")); err == nil {
+				b.Ordered, b.Number = true, n
+			}
+			p.addItem(b, m[3])
+		case taskRe.MatchString(trimmed):
+			p.flush()
+			p.addItem(Block{Kind: BlockKindListItem, Level: p.level(indentWidth(line[:len(line)-len(strings.TrimLeft(line, " \t"))]))}, trimmed)
+		case p.continuesItem(line):
+			last := &p.doc.Blocks[len(p.doc.Blocks)-1]
+			last.Inlines = append(last.Inlines, Inline{Kind: InlineKindBreak})
+			last.Inlines = append(last.Inlines, parseInlines(trimmed)...)
+		default:
+			p.indents = nil
+			p.para = append(p.para, trimmed)
+		}
+	}
+	p.flush()
+	return p.doc
+}
+
+type parser struct {
+	doc *Document
+	// para are the lines of the current paragraph
+	para []string
+	// indents are the indentation widths of the open list levels
+	indents []int
+}
+
+func (p *parser) add(b Block) {
+	p.doc.Blocks = append(p.doc.Blocks, b)
+}
+
+// addItem adds a list item, detecting checklist markers in its text
+func (p *parser) addItem(b Block, text string) {
+	if m := taskRe.FindStringSubmatch(text); m != nil {
+		b.Task = true
+		b.Checked = m[1] == "x" || m[1] == "X" || m[2] == "☑" || m[2] == "☒"
+		text = m[3]
+	}
+	b.Inlines = parseInlines(text)
+	p.add(b)
+}
+
+func (p *parser) flush() {
+	if len(p.para) > 0 {
+		p.add(Block{Kind: BlockKindParagraph, Inlines: parseLines(p.para)})
+		p.para = nil
+	}
+}
+
+// level returns the nesting depth of a list item indented by width
+func (p *parser) level(width int) int {
+	for len(p.indents) > 0 && p.indents[len(p.indents)-1] > width {
+		p.indents = p.indents[:len(p.indents)-1]
+	}
+	if len(p.indents) == 0 || p.indents[len(p.indents)-1] < width {
+		p.indents = append(p.indents, width)
+	}
+	return len(p.indents) - 1
+}
+
+// continuesItem reports whether an indented line continues the previous list item
+func (p *parser) continuesItem(line string) bool {
+	if len(p.para) > 0 || len(p.doc.Blocks) == 0 || p.doc.Blocks[len(p.doc.Blocks)-1].Kind != BlockKindListItem {
+		return false
+	}
+	return line[0] == ' ' || line[0] == '\t'
+}
+
+// indentWidth counts columns, with tabs as four
+func indentWidth(s string) int {
+	return len(s) + 3*strings.Count(s, "\t")
+}
+
+// parseLines parses lines joined by line breaks
+func parseLines(lines []string) []Inline {
+	var inlines []Inline
+	for i, line := range lines {
+		if i > 0 {
+			inlines = append(inlines, Inline{Kind: InlineKindBreak})
+		}
+		inlines = append(inlines, parseInlines(line)...)
+	}
+	return inlines
+}
+
+// Links returns the links of the note in order, including bare URLs
+func (d *Document) Links() []Link {
+	var links []Link
+	var walk func([]Inline)
+	walk = func(inlines []Inline) {
+		for _, in := range inlines {
+			if in.Kind == InlineKindLink {
+				links = append(links, Link{Text: plainInlines(in.Children), URL: in.URL})
+			}
+			walk(in.Children)
+		}
+	}
+	for _, b := range d.Blocks {
+		walk(b.Inlines)
+	}
+	return links
+}
+
+// Checklist returns the checklist-like lines of the note in order
+func (d *Document) Checklist() []ChecklistLine {
+	var lines []ChecklistLine
+	for _, b := range d.Blocks {
+		if b.Task {
+			lines = append(lines, ChecklistLine{Text: plainInlines(b.Inlines), Checked: b.Checked})
+		}
+	}
+	return lines
+}
@@ -0,0 +1,204 @@
+package notes
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sample = `# Groceries
+
+Buy *fresh* **organic** food, see [the list](https://example.com/list) or https://example.com/more.
+
+- [ ] milk
+- [x] eggs
+  - free range
+1. first
+2. second
+
+> call mum
+> before 6
+
+` + "```go\nfmt.Println(\"<hi>\")\n```" + `
+
+Project: things:///show?id=2YqFRTcdoyNQkBqyRgHh3R`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	doc := Parse(sample)
+	kinds := make([]BlockKind, len(doc.Blocks))
+	for i, b := range doc.Blocks {
+		kinds[i] = b.Kind
+	}
+	expected := []BlockKind{
+		BlockKindHeading, BlockKindParagraph,
+		BlockKindListItem, BlockKindListItem, BlockKindListItem, BlockKindListItem, BlockKindListItem,
+		BlockKindQuote, BlockKindCode, BlockKindParagraph,
+	}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Fatalf("Expected blocks %v, got %v", expected, kinds)
+	}
+
+	if b := doc.Blocks[4]; b.Level != 1 || b.Task {
+		t.Errorf("Expected a nested plain item, got %+v", b)
+	}
+	if b := doc.Blocks[6]; !b.Ordered || b.Number != 2 || b.Level != 0 {
+		t.Errorf("Expected the second numbered item, got %+v", b)
+	}
+	if b := doc.Blocks[8]; b.Lang != "go" || b.Code != `fmt.Println("<hi>")` {
+		t.Errorf("Expected the code block verbatim, got %+v", b)
+	}
+
+	expectedInlines := []Inline{
+		{Kind: InlineKindText, Text: "Buy "},
+		{Kind: InlineKindEmphasis, Children: []Inline{{Kind: InlineKindText, Text: "fresh"}}},
+		{Kind: InlineKindText, Text: " "},
+		{Kind: InlineKindStrong, Children: []Inline{{Kind: InlineKindText, Text: "organic"}}},
+		{Kind: InlineKindText, Text: " food, see "},
+		{Kind: InlineKindLink, URL: "https://example.com/list", Children: []Inline{{Kind: InlineKindText, Text: "the list"}}},
+		{Kind: InlineKindText, Text: " or "},
+		{Kind: InlineKindLink, URL: "https://example.com/more", Children: []Inline{{Kind: InlineKindText, Text: "https://example.com/more"}}},
+		{Kind: InlineKindText, Text: "."},
+	}
+	if got := doc.Blocks[1].Inlines; !reflect.DeepEqual(got, expectedInlines) {
+		t.Errorf("Expected inlines %+v, got %+v", expectedInlines, got)
+	}
+}
+
+func TestParse_Inlines(t *testing.T) {
+	t.Parallel()
+	text := func(s string) Inline { return Inline{Kind: InlineKindText, Text: s} }
+	testCases := []struct {
+		Name     string
+		Input    string
+		Expected []Inline
+	}{
+		{"SnakeCase", "rename foo_bar_baz", []Inline{text("rename foo_bar_baz")}},
+		{"Underscore", "_done_", []Inline{{Kind: InlineKindEmphasis, Children: []Inline{text("done")}}}},
+		{"Unclosed", "2 * 3 = 6", []Inline{text("2 * 3 = 6")}},
+		{"Escaped", `\*not emphasized\*`, []Inline{text("*not emphasized*")}},
+		{"Code", "run `go *test*`", []Inline{text("run "), {Kind: InlineKindCode, Text: "go *test*"}}},
+		{"Nested", "*a **b** c*", []Inline{{Kind: InlineKindEmphasis, Children: []Inline{
+			text("a "), {Kind: InlineKindStrong, Children: []Inline{text("b")}}, text(" c"),
+		}}}},
+		{"Parens", "(see https://en.wikipedia.org/wiki/Go_(language))", []Inline{
+			text("(see "),
+			{Kind: InlineKindLink, URL: "https://en.wikipedia.org/wiki/Go_(language)", Children: []Inline{text("https://en.wikipedia.org/wiki/Go_(language)")}},
+			text(")"),
+		}},
+		{"Angle", "<mailto:me@example.com>", []Inline{{Kind: InlineKindLink, URL: "mailto:me@example.com", Children: []Inline{text("mailto:me@example.com")}}}},
+		{"NotALink", "[todo] later", []Inline{text("[todo] later")}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			t.Parallel()
+			if got := parseInlines(testCase.Input); !reflect.DeepEqual(got, testCase.Expected) {
+				t.Errorf("Expected %+v, got %+v", testCase.Expected, got)
+			}
+		})
+	}
+}
+
+func TestParse_Legacy(t *testing.T) {
+	t.Parallel()
+	doc := Parse(`<note xml:space="preserve">test body pm
+- [ ] a &amp; b</note>`)
+	if got := doc.PlainText(); got != "test body pm\n\n- [ ] a & b" {
+		t.Errorf("Expected the unwrapped note, got %q", got)
+	}
+	if got := Unwrap("<note>broken"); got != "<note>broken" {
+		t.Errorf("Expected other notes to be unchanged, got %q", got)
+	}
+}
+
+func TestDocument_HTML(t *testing.T) {
+	t.Parallel()
+	expected := `<h1>Groceries</h1>
+<p>Buy <em>fresh</em> <strong>organic</strong> food, see <a href="https://example.com/list">the list</a> or <a href="https://example.com/more">https://example.com/more</a>.</p>
+<ul>
+<li><input type="checkbox" disabled> milk</li>
+<li><input type="checkbox" disabled checked> eggs<ul>
+<li>free range</li>
+</ul>
+</li>
+</ul>
+<ol>
+<li>first</li>
+<li>second</li>
+</ol>
+<blockquote><p>call mum<br>
+before 6</p></blockquote>
+<pre><code class="language-go">fmt.Println(&#34;&lt;hi&gt;&#34;)</code></pre>
+<p>Project: <a href="things:///show?id=2YqFRTcdoyNQkBqyRgHh3R">things:///show?id=2YqFRTcdoyNQkBqyRgHh3R</a></p>
+`
+	if got := Parse(sample).HTML(); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+
+	if got := Parse("[click](javascript:alert(1)) <b>").HTML(); got != "<p>click &lt;b&gt;</p>\n" {
+		t.Errorf("Expected unsafe links and tags to be neutralized, got %q", got)
+	}
+}
+
+func TestDocument_Markdown(t *testing.T) {
+	t.Parallel()
+	if got := Parse(sample).Markdown(); got != sample {
+		t.Errorf("Expected the sample to round trip, got\n%s", got)
+	}
+	for _, s := range []string{`1\. not a list`, `\# not a heading`, `a \*b\* c`, "snake_case and `co*de`"} {
+		if got := Parse(s).Markdown(); got != s {
+			t.Errorf("Expected %q to round trip, got %q", s, got)
+		}
+	}
+	for _, s := range []string{`_a*b_`, `*a\*b*`, `**a\**b**`, `*\**`} {
+		doc := Parse(s)
+		if got := Parse(doc.Markdown()); !reflect.DeepEqual(got, doc) {
+			t.Errorf("Expected %q to parse the same after rendering it as %q", s, doc.Markdown())
+		}
+	}
+}
+
+func TestDocument_PlainText(t *testing.T) {
+	t.Parallel()
+	expected := "Groceries\n\n" +
+		"Buy fresh organic food, see the list (https://example.com/list) or https://example.com/more.\n\n" +
+		"- [ ] milk\n- [x] eggs\n  - free range\n1. first\n2. second\n\n" +
+		"call mum\nbefore 6\n\n" +
+		"fmt.Println(\"<hi>\")\n\n" +
+		"Project: things:///show?id=2YqFRTcdoyNQkBqyRgHh3R"
+	if got := Parse(sample).PlainText(); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestDocument_Links(t *testing.T) {
+	t.Parallel()
+	links := Parse(sample).Links()
+	expected := []Link{
+		{Text: "the list", URL: "https://example.com/list"},
+		{Text: "https://example.com/more", URL: "https://example.com/more"},
+		{Text: "things:///show?id=2YqFRTcdoyNQkBqyRgHh3R", URL: "things:///show?id=2YqFRTcdoyNQkBqyRgHh3R"},
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, links)
+	}
+	if id, ok := links[2].ThingsID(); !ok || id != "2YqFRTcdoyNQkBqyRgHh3R" {
+		t.Errorf("Expected the deep link's id, got %q", id)
+	}
+	if _, ok := links[0].ThingsID(); ok {
+		t.Errorf("Expected no id for web links")
+	}
+}
+
+func TestDocument_Checklist(t *testing.T) {
+	t.Parallel()
+	doc := Parse("Packing:\n\n- [ ] passport\n* [X] tickets\n☐ charger\n☑ *socks*\n- shirts")
+	expected := []ChecklistLine{
+		{Text: "passport"},
+		{Text: "tickets", Checked: true},
+		{Text: "charger"},
+		{Text: "socks", Checked: true},
+	}
+	if got := doc.Checklist(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
@@ -0,0 +1,275 @@
+package notes
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// linkSchemes are the URL schemes HTML renders as anchors. Links with other schemes,
+// e.g. javascript:, are rendered as their label only.
+var linkSchemes = []string{"http:", "https:", "mailto:", "things:"}
+
+// HTML renders the note as an HTML fragment
+func (d *Document) HTML() string {
+	var b strings.Builder
+	var lists []bool // ordered flag of the open lists
+	closeLists := func(depth int) {
+		for len(lists) > depth {
+			if lists[len(lists)-1] {
+				b.WriteString("</li>\n</ol>\n")
+			} else {
+				b.WriteString("</li>\n</ul>\n")
+			}
+			lists = lists[:len(lists)-1]
+		}
+	}
+
+	for _, blk := range d.Blocks {
+		if blk.Kind != BlockKindListItem {
+			closeLists(0)
+		}
+		switch blk.Kind {
+		case BlockKindParagraph:
+			fmt.Fprintf(&b, "<p>%s</p>\n", htmlInlines(blk.Inlines))
+		case BlockKindHeading:
+			fmt.Fprintf(&b, "<h%d>%s</h%[1]d>\n", blk.Level, htmlInlines(blk.Inlines))
+		case BlockKindQuote:
+			fmt.Fprintf(&b, "<blockquote><p>%s</p></blockquote>\n", htmlInlines(blk.Inlines))
+		case BlockKindCode:
+			b.WriteString("<pre><code")
+			if blk.Lang != "" {
+				fmt.Fprintf(&b, ` class="language-%s"`, html.EscapeString(blk.Lang))
+			}
+			fmt.Fprintf(&b, ">%s</code></pre>\n", html.EscapeString(blk.Code))
+		case BlockKindListItem:
+			closeLists(blk.Level + 1)
+			if len(lists) == blk.Level+1 {
+				if lists[blk.Level] == blk.Ordered {
+					b.WriteString("</li>\n")
+				} else {
+					closeLists(blk.Level)
+				}
+			}
+			for len(lists) < blk.Level+1 {
+				switch {
+				case !blk.Ordered:
+					b.WriteString("<ul>\n")
+				case blk.Number != 1:
+					fmt.Fprintf(&b, "<ol start=\"%d\">\n", blk.Number)
+				default:
+					b.WriteString("<ol>\n")
+				}
+				lists = append(lists, blk.Ordered)
+			}
+			b.WriteString("<li>")
+			if blk.Task {
+				b.WriteString(`<input type="checkbox" disabled`)
+				if blk.Checked {
+					b.WriteString(" checked")
+				}
+				b.WriteString("> ")
+			}
+			b.WriteString(htmlInlines(blk.Inlines))
+		}
+	}
+	closeLists(0)
+	return b.String()
+}
+
+func htmlInlines(inlines []Inline) string {
+	var b strings.Builder
+	for _, in := range inlines {
+		switch in.Kind {
+		case InlineKindText:
+			b.WriteString(html.EscapeString(in.Text))
+		case InlineKindBreak:
+			b.WriteString("<br>\n")
+		case InlineKindEmphasis:
+			fmt.Fprintf(&b, "<em>%s</em>", htmlInlines(in.Children))
+		case InlineKindStrong:
+			fmt.Fprintf(&b, "<strong>%s</strong>", htmlInlines(in.Children))
+		case InlineKindCode:
+			fmt.Fprintf(&b, "<code>%s</code>", html.EscapeString(in.Text))
+		case InlineKindLink:
+			if safeURL(in.URL) {
+				fmt.Fprintf(&b, `<a href="%s">%s</a>`, html.EscapeString(in.URL), htmlInlines(in.Children))
+			} else {
+				b.WriteString(htmlInlines(in.Children))
+			}
+		}
+	}
+	return b.String()
+}
+
+func safeURL(u string) bool {
+	for _, scheme := range linkSchemes {
+		if len(u) > len(scheme) && strings.EqualFold(u[:len(scheme)], scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Markdown renders the note as Markdown, in the form Parse reads
+func (d *Document) Markdown() string {
+	return d.render(markdownBlock)
+}
+
+// PlainText renders the note as text without formatting. List markers are kept and
+// links are followed by their URL in parentheses.
+func (d *Document) PlainText() string {
+	return d.render(plainBlock)
+}
+
+// render joins the rendered blocks, with a blank line between blocks except
+// consecutive list items
+func (d *Document) render(block func(Block) string) string {
+	var b strings.Builder
+	for i, blk := range d.Blocks {
+		if i > 0 {
+			b.WriteString("\n")
+			if blk.Kind != BlockKindListItem || d.Blocks[i-1].Kind != BlockKindListItem {
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString(block(blk))
+	}
+	return b.String()
+}
+
+// listMarker returns the marker of a list item, including its checkbox
+func listMarker(blk Block) string {
+	marker := "- "
+	if blk.Ordered {
+		marker = fmt.Sprintf("%d. ", blk.Number)
+	}
+	if blk.Task {
+		if blk.Checked {
+			return marker + "[x] "
+		}
+		return marker + "[ ] "
+	}
+	return marker
+}
+
+func markdownBlock(blk Block) string {
+	switch blk.Kind {
+	case BlockKindHeading:
+		return strings.Repeat("#", blk.Level) + " " + markdownInlines(blk.Inlines)
+	case BlockKindQuote:
+		return "> " + strings.ReplaceAll(markdownInlines(blk.Inlines), "\n", "\n> ")
+	case BlockKindCode:
+		return "```" + blk.Lang + "\n" + blk.Code + "\n```"
+	case BlockKindListItem:
+		indent := strings.Repeat("  ", blk.Level)
+		marker := listMarker(blk)
+		text := strings.ReplaceAll(markdownInlines(blk.Inlines), "\n", "\n"+indent+strings.Repeat(" ", len(marker)))
+		return indent + marker + text
+	}
+	lines := strings.Split(markdownInlines(blk.Inlines), "\n")
+	for i, line := range lines {
+		lines[i] = escapeLineStart(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+var orderedStartRe = regexp.MustCompile(`^(\d{1,9})([.)][ \t])`)
+
+// escapeLineStart escapes a paragraph line which would otherwise be read as another block
+func escapeLineStart(line string) string {
+	if m := orderedStartRe.FindStringSubmatch(line); m != nil {
+		return m[1] + `\` + line[len(m[1]):]
+	}
+	if headingRe.MatchString(line) || listRe.MatchString(line) || taskRe.MatchString(line) ||
+		fenceRe.MatchString(line) || strings.HasPrefix(line, ">") {
+		return `\` + line
+	}
+	return line
+}
+
+// escapeMarkdown escapes the characters of text which would start formatting. An _
+// within a word, as in snake_case, is kept as is.
+func escapeMarkdown(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch c {
+		case '\\', '`', '*', '[', ']', '<':
+			b.WriteByte('\\')
+		case '_':
+			if i == 0 || i == len(text)-1 || !isWordByte(text[i-1]) || !isWordByte(text[i+1]) {
+				b.WriteByte('\\')
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func markdownInlines(inlines []Inline) string {
+	var b strings.Builder
+	for _, in := range inlines {
+		switch in.Kind {
+		case InlineKindText:
+			b.WriteString(escapeMarkdown(in.Text))
+		case InlineKindBreak:
+			b.WriteString("\n")
+		case InlineKindEmphasis:
+			b.WriteString("*" + markdownInlines(in.Children) + "*")
+		case InlineKindStrong:
+			b.WriteString("**" + markdownInlines(in.Children) + "**")
+		case InlineKindCode:
+			fence := "`"
+			for strings.Contains(in.Text, fence) {
+				fence += "`"
+			}
+			if strings.HasPrefix(in.Text, "`") || strings.HasSuffix(in.Text, "`") {
+				b.WriteString(fence + " " + in.Text + " " + fence)
+			} else {
+				b.WriteString(fence + in.Text + fence)
+			}
+		case InlineKindLink:
+			if len(in.Children) == 1 && in.Children[0].Kind == InlineKindText && in.Children[0].Text == in.URL {
+				b.WriteString(in.URL)
+			} else {
+				fmt.Fprintf(&b, "[%s](%s)", markdownInlines(in.Children), in.URL)
+			}
+		}
+	}
+	return b.String()
+}
+
+func plainBlock(blk Block) string {
+	switch blk.Kind {
+	case BlockKindCode:
+		return blk.Code
+	case BlockKindListItem:
+		indent := strings.Repeat("  ", blk.Level)
+		marker := listMarker(blk)
+		return indent + marker + strings.ReplaceAll(plainInlines(blk.Inlines), "\n", "\n"+indent+strings.Repeat(" ", len(marker)))
+	}
+	return plainInlines(blk.Inlines)
+}
+
+func plainInlines(inlines []Inline) string {
+	var b strings.Builder
+	for _, in := range inlines {
+		switch in.Kind {
+		case InlineKindText, InlineKindCode:
+			b.WriteString(in.Text)
+		case InlineKindBreak:
+			b.WriteString("\n")
+		case InlineKindEmphasis, InlineKindStrong:
+			b.WriteString(plainInlines(in.Children))
+		case InlineKindLink:
+			label := plainInlines(in.Children)
+			b.WriteString(label)
+			if label != in.URL {
+				b.WriteString(" (" + in.URL + ")")
+			}
+		}
+	}
+	return b.String()
+}
@@ -2,6 +2,10 @@ package thingscloud
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
 	"testing"
 )
 
@@ -70,3 +74,127 @@ func TestNote_ApplyMultiplePatches(t *testing.T) {
 		t.Errorf("expected 'XBCDEF', got '%s'", result)
 	}
 }
+
+func TestDiffNotes(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Old, New string
+		Expected []NotePatch
+	}{
+		{"Equal", "Hello world", "Hello world", []NotePatch{}},
+		{"Insert", "Hello world", "Hello brave world", []NotePatch{{Replacement: "brave ", Position: 6}}},
+		{"Delete", "Hello brave world", "Hello world", []NotePatch{{Position: 6, Length: 6}}},
+		{"Replace", "Hello world", "Hello Sam", []NotePatch{{Replacement: "Sam", Position: 6, Length: 5}}},
+		{"FromEmpty", "", "Milk", []NotePatch{{Replacement: "Milk"}}},
+		{"ToEmpty", "Milk", "", []NotePatch{{Length: 4}}},
+		{"TwoEdits", "buy milk\ncall mum", "buy oat milk\ncall dad", []NotePatch{
+			{Replacement: "oat ", Position: 4},
+			{Replacement: "dad", Position: 18, Length: 3},
+		}},
+		{"Runes", "Grüße 👋 Welt", "Grüße 👍 Welt", []NotePatch{{Replacement: "👍", Position: 6, Length: 1}}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			patches := DiffNotes(testCase.Old, testCase.New)
+			if len(patches) != len(testCase.Expected) {
+				t.Fatalf("Expected %d patches, got %+v", len(testCase.Expected), patches)
+			}
+			text := testCase.Old
+			for i, p := range patches {
+				e := testCase.Expected[i]
+				if p.Replacement != e.Replacement || p.Position != e.Position || p.Length != e.Length {
+					t.Errorf("Expected patch %d to be %+v, got %+v", i, e, p)
+				}
+				text = ApplyPatches(text, []NotePatch{p})
+				if p.Checksum != NoteChecksum(text) {
+					t.Errorf("Expected patch %d to carry the checksum of %q", i, text)
+				}
+			}
+			if text != testCase.New {
+				t.Errorf("Expected %q, got %q", testCase.New, text)
+			}
+		})
+	}
+}
+
+func TestDiffNotes_RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	alphabet := []rune("ab c\nä👋")
+	random := func() string {
+		rs := make([]rune, rng.IntN(40))
+		for i := range rs {
+			rs[i] = alphabet[rng.IntN(len(alphabet))]
+		}
+		return string(rs)
+	}
+	for i := 0; i < 500; i++ {
+		old, new := random(), random()
+		patches := DiffNotes(old, new)
+		if got := ApplyPatches(old, patches); got != new {
+			t.Fatalf("Expected patches %+v to turn %q into %q, got %q", patches, old, new, got)
+		}
+		if len(patches) > 0 && patches[len(patches)-1].Checksum != NoteChecksum(new) {
+			t.Fatalf("Expected the last checksum to match %q", new)
+		}
+	}
+
+	// beyond the edit bound the changed region is replaced as a whole
+	old, new := strings.Repeat("ab", maxDiffEdits), strings.Repeat("ac", maxDiffEdits)
+	if patches := DiffNotes(old, new); len(patches) != 1 || ApplyPatches(old, patches) != new {
+		t.Errorf("Expected a single patch beyond the edit bound, got %d", len(patches))
+	}
+}
+
+func TestNewDeltaNote(t *testing.T) {
+	bs, err := json.Marshal(NewDeltaNote("Hello world", "Hello Sam"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := fmt.Sprintf(`{"_t":"tx","t":2,"ps":[{"r":"Sam","p":6,"l":5,"ch":%d}]}`, NoteChecksum("Hello Sam"))
+	if string(bs) != expected {
+		t.Errorf("Expected %s, got %s", expected, bs)
+	}
+}
+
+func TestApplyPatchesVerified(t *testing.T) {
+	old, new := "buy milk\ncall mum", "buy oat milk\ncall dad"
+	patches := DiffNotes(old, new)
+
+	t.Run("Matching", func(t *testing.T) {
+		got, err := ApplyPatchesVerified(old, patches)
+		if err != nil || got != new {
+			t.Errorf("Expected %q, got %q (%v)", new, got, err)
+		}
+	})
+
+	t.Run("Unchecked", func(t *testing.T) {
+		got, err := ApplyPatchesVerified("Hello", []NotePatch{{Replacement: "!", Position: 5}})
+		if err != nil || got != "Hello!" {
+			t.Errorf("Expected patches without checksum to apply, got %q (%v)", got, err)
+		}
+	})
+
+	testCases := []struct {
+		Name     string
+		Original string
+		Patches  []NotePatch
+		Expected NoteDivergedError
+	}{
+		{"Diverged", "buy milk\ncall dad", patches, NoteDivergedError{Patch: 0, Checksum: patches[0].Checksum, Actual: NoteChecksum("buy oat milk\ncall dad")}},
+		{"PastEnd", "buy", patches, NoteDivergedError{Patch: 0}},
+		{"LengthPastEnd", "Hello", []NotePatch{{Position: 3, Length: 3}}, NoteDivergedError{Patch: 0}},
+		{"Negative", "Hello", []NotePatch{{Position: -1}}, NoteDivergedError{Patch: 0}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			_, err := ApplyPatchesVerified(testCase.Original, testCase.Patches)
+			if !errors.Is(err, ErrNoteDiverged) {
+				t.Fatalf("Expected ErrNoteDiverged, got %v", err)
+			}
+			var derr *NoteDivergedError
+			if !errors.As(err, &derr) || *derr != testCase.Expected {
+				t.Errorf("Expected %+v, got %+v", testCase.Expected, derr)
+			}
+		})
+	}
+}
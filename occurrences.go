@@ -0,0 +1,178 @@
+package thingscloud
+
+import (
+	"iter"
+	"slices"
+	"time"
+)
+
+// Occurrences returns the days the rule occurs on, from the calendar day of from up to
+// and including the calendar day of to, as midnight in from's location. Unlike
+// NextScheduledAt, the days are generated period by period starting at the period
+// containing from, so listing a range doesn't replay the rule from its first occurrence
+// unless it ends after a repeat count.
+//
+// Occurrences start at FirstScheduledAt, or StartReference if it isn't set, and stop
+// after RepeatCount occurrences or after LastScheduledAt. TimeShift moves every
+// occurrence by that many days; the end date and count apply to the unshifted days.
+// Days a detail configuration doesn't exist in, e.g. the 31st of a 30 day month or the
+// 5th Monday of a month with four, are skipped.
+func (c RepeaterConfiguration) Occurrences(from, to time.Time) iter.Seq[time.Time] {
+	return func(yield func(time.Time) bool) {
+		start := c.FirstScheduledAt
+		if start == nil {
+			start = c.StartReference
+		}
+		if start == nil || c.FrequencyAmplitude < 1 || len(c.DetailConfiguration) == 0 {
+			return
+		}
+		switch c.FrequencyUnit {
+		case FrequencyUnitDaily, FrequencyUnitWeekly, FrequencyUnitMonthly, FrequencyUnitYearly:
+		default:
+			return
+		}
+
+		first := DateFromUnix(start.Time().Unix())
+		lo, hi := DateOf(from), DateOf(to)
+		var end *Date
+		if c.LastScheduledAt != nil && !c.IsNeverending() {
+			d := DateFromUnix(c.LastScheduledAt.Time().Unix())
+			end = &d
+		}
+		var limit int64
+		if c.RepeatCount != nil {
+			limit = *c.RepeatCount
+		}
+
+		// without a count, earlier periods can be skipped; one period of slack
+		// covers TimeShift and periods whose days spill past their start
+		period, count := 0, int64(0)
+		if limit <= 0 {
+			period = max(0, c.periodsUntil(first, lo.AddDays(-c.TimeShift))-1)
+		}
+		for ; ; period++ {
+			if c.periodStart(first, period).AddDays(c.TimeShift).After(hi) {
+				return
+			}
+			for _, d := range c.periodDays(first, period) {
+				if d.Before(first) {
+					continue
+				}
+				if end != nil && d.After(*end) {
+					return
+				}
+				if count++; limit > 0 && count > limit {
+					return
+				}
+				d = d.AddDays(c.TimeShift)
+				if d.After(hi) {
+					return
+				}
+				if !d.Before(lo) && !yield(d.Time(from.Location())) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// periodStart returns the first day of the nth period of the rule, counting the period
+// containing first as 0
+func (c RepeaterConfiguration) periodStart(first Date, n int) Date {
+	step := n * int(c.FrequencyAmplitude)
+	switch c.FrequencyUnit {
+	case FrequencyUnitDaily:
+		return first.AddDays(step)
+	case FrequencyUnitWeekly:
+		return weekStart(first).AddDays(7 * step)
+	case FrequencyUnitMonthly:
+		return NewDate(first.Year, first.Month+time.Month(step), 1)
+	}
+	return NewDate(first.Year+step, time.January, 1)
+}
+
+// periodsUntil returns the number of whole periods from the one containing first to the
+// one containing d
+func (c RepeaterConfiguration) periodsUntil(first, d Date) int {
+	var n int
+	switch c.FrequencyUnit {
+	case FrequencyUnitDaily:
+		n = daysBetween(first, d)
+	case FrequencyUnitWeekly:
+		n = daysBetween(weekStart(first), weekStart(d)) / 7
+	case FrequencyUnitMonthly:
+		n = (d.Year-first.Year)*12 + int(d.Month-first.Month)
+	default:
+		n = d.Year - first.Year
+	}
+	return n / int(c.FrequencyAmplitude)
+}
+
+// periodDays returns the days the rule occurs on in its nth period, in order
+func (c RepeaterConfiguration) periodDays(first Date, n int) []Date {
+	ps := c.periodStart(first, n)
+	if c.FrequencyUnit == FrequencyUnitDaily {
+		return []Date{ps}
+	}
+
+	var days []Date
+	for _, dc := range c.DetailConfiguration {
+		switch c.FrequencyUnit {
+		case FrequencyUnitWeekly:
+			if dc.Weekday != nil {
+				days = append(days, ps.AddDays(int(*dc.Weekday)))
+			}
+		case FrequencyUnitMonthly:
+			if d, ok := dayOfMonth(ps, dc); ok {
+				days = append(days, d)
+			}
+		case FrequencyUnitYearly:
+			if dc.Month == nil {
+				continue
+			}
+			if d, ok := dayOfMonth(NewDate(ps.Year, time.Month(*dc.Month+1), 1), dc); ok {
+				days = append(days, d)
+			}
+		}
+	}
+	slices.SortFunc(days, Date.Compare)
+	return slices.Compact(days)
+}
+
+// dayOfMonth returns the day dc selects in the month starting at month, reporting false
+// if the month has no such day
+func dayOfMonth(month Date, dc RepeaterDetailConfiguration) (Date, bool) {
+	last := NewDate(month.Year, month.Month+1, 0)
+	switch {
+	case dc.Weekday != nil && dc.MonthOf != nil:
+		if *dc.MonthOf == -1 {
+			back := (int(weekday(last)) - int(*dc.Weekday) + 7) % 7
+			return last.AddDays(-back), true
+		}
+		offset := (int(*dc.Weekday) - int(weekday(month)) + 7) % 7
+		d := month.AddDays(offset + 7*int(*dc.MonthOf-1))
+		return d, *dc.MonthOf > 0 && d.Month == month.Month
+	case dc.Day != nil:
+		if *dc.Day == -1 {
+			return last, true
+		}
+		if *dc.Day < 0 || int(*dc.Day) >= last.Day {
+			return Date{}, false
+		}
+		return NewDate(month.Year, month.Month, int(*dc.Day)+1), true
+	}
+	return Date{}, false
+}
+
+// weekStart returns the Sunday starting the week of d
+func weekStart(d Date) Date {
+	return d.AddDays(-int(weekday(d)))
+}
+
+func weekday(d Date) time.Weekday {
+	return d.Time(time.UTC).Weekday()
+}
+
+func daysBetween(a, b Date) int {
+	return int((b.Unix() - a.Unix()) / (24 * 60 * 60))
+}
@@ -0,0 +1,86 @@
+package thingscloud
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRepeaterConfiguration_Occurrences(t *testing.T) {
+	testCases := []struct {
+		Title         string
+		Data          []byte
+		From, To      string
+		ExpectedDates []string
+	}{
+		{"Every day", rcEveryDay, "2017-09-01", "2017-09-05", []string{"2017-09-03", "2017-09-04", "2017-09-05"}},
+		{"Every 2nd day, years later", rcEvery2ndDay, "2026-03-01", "2026-03-06", []string{"2026-03-02", "2026-03-04", "2026-03-06"}},
+		{"Every day w/ date", rcEveryDayEndDate, "2018-02-01", "2018-03-31", []string{"2018-02-28", "2018-03-01"}},
+		{"Every day w/ count", rcEveryDayEndRepeat, "2018-02-01", "2018-03-31", []string{"2018-02-28", "2018-03-01"}},
+
+		{"Every week on monday and tuesday", rcEveryWeekOnMondayAndTuesday, "2017-09-01", "2017-09-12", []string{"2017-09-04", "2017-09-05", "2017-09-11", "2017-09-12"}},
+		{"Every 2nd week on monday and tuesday", rcEvery2ndWeekOnMondayAndTuesday, "2017-09-05", "2017-10-03", []string{"2017-09-05", "2017-09-18", "2017-09-19", "2017-10-02", "2017-10-03"}},
+		{"Every week on monday w/ date", rcEveryWeekOnMondayEndDate, "2018-01-01", "2018-12-31", []string{"2018-03-05", "2018-03-12"}},
+		{"Every week on monday w/ count", rcEveryWeekOnMondayEndRepeat, "2018-03-06", "2018-12-31", []string{"2018-03-12"}},
+
+		{"Every first day and 2nd monday of every month", rc1stDayAnd2ndMondayEveryMonth, "2017-09-01", "2017-10-31", []string{"2017-09-01", "2017-09-11", "2017-10-01", "2017-10-09"}},
+		{"Every first and last day of every month", rc1stAndLastDayEveryMonth, "2017-07-01", "2017-09-30", []string{"2017-07-31", "2017-08-01", "2017-08-31", "2017-09-01", "2017-09-30"}},
+		{"Every last day of every 2nd month", rcLastDayEvery2ndMonth, "2017-09-01", "2018-03-31", []string{"2017-09-30", "2017-11-30", "2018-01-31", "2018-03-31"}},
+		{"Every first Monday of every 2nd month", rcFirstMondayEvery2ndMonth, "2017-08-01", "2017-12-31", []string{"2017-08-07", "2017-10-02", "2017-12-04"}},
+		{"Every 31st and 5th monday", []byte(`{"ia":1514678400,"of":[{"dy":30},{"wdo":5,"wd":1}],"fu":8,"fa":1,"rc":0,"ed":64092211200}`), "2018-01-01", "2018-05-31", []string{"2018-01-29", "2018-01-31", "2018-03-31", "2018-04-30", "2018-05-31"}},
+
+		{"Every first day of january and last Wednesday of febuary", rc1stJanuaryAndLastWednesdayFebuaryEveryYear, "2018-01-01", "2020-02-26", []string{"2018-01-01", "2018-02-28", "2019-01-01", "2019-02-27", "2020-01-01", "2020-02-26"}},
+		{"Every last day of february", rcLastDayFebuaryEveryYear, "2019-06-01", "2021-12-31", []string{"2020-02-29", "2021-02-28"}},
+		{"Every last day of january w/ count", rcLastDayJanuaryEveryYearEndRepeat, "2017-01-01", "2030-12-31", []string{"2018-01-31", "2019-01-31"}},
+
+		{"Shifted every week on monday", []byte(`{"ia":1504483200,"of":[{"wd":1}],"fu":256,"fa":1,"rc":0,"ts":-2,"ed":64092211200}`), "2017-09-01", "2017-09-16", []string{"2017-09-02", "2017-09-09", "2017-09-16"}},
+		{"Shifted every day w/ date", []byte(`{"ia":1519776000,"of":[{"dy":0}],"fu":16,"fa":1,"ts":1,"ed":1519862400}`), "2018-02-01", "2018-03-31", []string{"2018-03-01", "2018-03-02"}},
+
+		{"Empty range", rcEveryDay, "2017-09-05", "2017-09-04", nil},
+		{"Before the first occurrence", rcEveryDay, "2017-01-01", "2017-09-02", nil},
+		{"Without details", []byte(`{"ia":1504396800,"of":[],"fu":8,"fa":1}`), "2017-01-01", "2017-12-31", nil},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Title, func(t *testing.T) {
+			var rc RepeaterConfiguration
+			if err := json.Unmarshal(testCase.Data, &rc); err != nil {
+				t.Fatalf("Failed to deserialize repeater configuration: %v", err)
+			}
+			from, _ := time.Parse("2006-01-02", testCase.From)
+			to, _ := time.Parse("2006-01-02", testCase.To)
+
+			var dates []string
+			for d := range rc.Occurrences(from, to) {
+				dates = append(dates, d.Format("2006-01-02"))
+			}
+			if !reflect.DeepEqual(dates, testCase.ExpectedDates) {
+				t.Errorf("Expected %v, got %v", testCase.ExpectedDates, dates)
+			}
+		})
+	}
+}
+
+func TestRepeaterConfiguration_OccurrencesLocation(t *testing.T) {
+	var rc RepeaterConfiguration
+	if err := json.Unmarshal(rcEveryWeekOnMonday, &rc); err != nil {
+		t.Fatal(err)
+	}
+	loc := time.FixedZone("UTC-5", -5*60*60)
+	from := time.Date(2017, time.September, 4, 23, 0, 0, 0, loc)
+
+	var dates []time.Time
+	for d := range rc.Occurrences(from, from.AddDate(1, 0, 0)) {
+		dates = append(dates, d)
+		if len(dates) == 2 {
+			break
+		}
+	}
+	expected := []time.Time{
+		time.Date(2017, time.September, 4, 0, 0, 0, 0, loc),
+		time.Date(2017, time.September, 11, 0, 0, 0, 0, loc),
+	}
+	if !reflect.DeepEqual(dates, expected) {
+		t.Errorf("Expected %v, got %v", expected, dates)
+	}
+}
@@ -0,0 +1,245 @@
+package thingscloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// taskFields are the payload keys Task has a field for. Everything else ends up in
+// Task.Extra.
+var taskFields = map[string]bool{
+	"ix": true, "cd": true, "md": true, "sr": true, "sp": true, "dd": true, "tir": true,
+	"ss": true, "tp": true, "tt": true, "nt": true, "ar": true, "pr": true, "tg": true,
+	"tr": true, "ti": true, "rt": true, "st": true, "agr": true, "do": true, "ato": true,
+	"dl": true,
+}
+
+// MergePayload applies changes onto base and returns the result. Keys of changes
+// replace those of base, except that objects present in both are merged recursively,
+// so e.g. keys inside the xx extension data which changes doesn't mention are kept.
+// If either side is not a JSON object, changes is returned as is.
+func MergePayload(base json.RawMessage, changes any) (json.RawMessage, error) {
+	cs, ok := changes.(json.RawMessage)
+	if !ok {
+		var err error
+		if cs, err = json.Marshal(changes); err != nil {
+			return nil, err
+		}
+	}
+	var b, c map[string]json.RawMessage
+	if !isObject(base) || !isObject(cs) {
+		return cs, nil
+	}
+	if err := json.Unmarshal(base, &b); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(cs, &c); err != nil {
+		return nil, err
+	}
+	for k, v := range c {
+		if old, ok := b[k]; ok && isObject(old) && isObject(v) {
+			merged, err := MergePayload(old, v)
+			if err != nil {
+				return nil, err
+			}
+			v = merged
+		}
+		b[k] = v
+	}
+	return json.Marshal(b)
+}
+
+func isObject(bs []byte) bool {
+	bs = bytes.TrimSpace(bs)
+	return len(bs) > 0 && bs[0] == '{'
+}
+
+// marshalPayload encodes a payload whose fields are already encoded as bs. Payloads
+// decoded from the server are merged onto their raw JSON, so fields the SDK doesn't
+// model survive a round trip. Modelled fields which were set in Raw but have been
+// cleared since are written as null, so that e.g. removing a deadline takes effect.
+func marshalPayload(raw json.RawMessage, p any, bs []byte, err error) ([]byte, error) {
+	if err != nil || raw == nil {
+		return bs, err
+	}
+	var base, fields map[string]json.RawMessage
+	if json.Unmarshal(raw, &base) != nil || json.Unmarshal(bs, &fields) != nil {
+		return MergePayload(raw, json.RawMessage(bs))
+	}
+	for _, key := range payloadKeys(reflect.TypeOf(p)) {
+		if _, ok := fields[key]; ok {
+			continue
+		}
+		if old, ok := base[key]; ok && !isEmptyJSON(old) {
+			fields[key] = json.RawMessage("null")
+		}
+	}
+	return MergePayload(raw, fields)
+}
+
+// payloadKeys returns the JSON keys of the fields of a payload struct
+func payloadKeys(t reflect.Type) []string {
+	keys := make([]string, 0, t.NumField())
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// isEmptyJSON reports whether bs decodes into a value omitempty drops, such as an
+// empty tag list, so that its field missing from the encoded payload doesn't mean it
+// was cleared
+func isEmptyJSON(bs json.RawMessage) bool {
+	switch string(bytes.TrimSpace(bs)) {
+	case "null", "[]", "{}":
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON decodes the payload and keeps the raw JSON in Raw
+func (p *TaskActionItemPayload) UnmarshalJSON(bs []byte) error {
+	type payload TaskActionItemPayload
+	if err := json.Unmarshal(bs, (*payload)(p)); err != nil {
+		return err
+	}
+	p.Raw = append(json.RawMessage(nil), bs...)
+	return nil
+}
+
+// MarshalJSON encodes the payload. If it was decoded from JSON, the fields are merged
+// onto Raw.
+func (p TaskActionItemPayload) MarshalJSON() ([]byte, error) {
+	type payload TaskActionItemPayload
+	bs, err := json.Marshal(payload(p))
+	return marshalPayload(p.Raw, p, bs, err)
+}
+
+// Unmodelled returns the payload fields Task has no field for, e.g. the xx extension
+// data or fields written by newer Things.app versions
+func (p TaskActionItemPayload) Unmodelled() map[string]json.RawMessage {
+	raw := p.Raw
+	if raw == nil {
+		raw, _ = json.Marshal(p)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+	for k := range fields {
+		if taskFields[k] {
+			delete(fields, k)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// MergeExtra stores unmodelled payload fields in Extra, replacing earlier values
+func (t *Task) MergeExtra(fields map[string]json.RawMessage) {
+	if len(fields) == 0 {
+		return
+	}
+	if t.Extra == nil {
+		t.Extra = make(map[string]json.RawMessage, len(fields))
+	}
+	for k, v := range fields {
+		t.Extra[k] = v
+	}
+}
+
+// Update returns an item modifying t with the given changes. Only the fields set in
+// changes are sent. If changes carries extension data, it is merged onto the xx of t,
+// so extension keys written by other clients are not clobbered.
+func (t *Task) Update(changes TaskActionItemPayload) (TaskActionItem, error) {
+	if changes.ExtensionData != nil && t.Extra["xx"] != nil {
+		xx, err := MergePayload(t.Extra["xx"], changes.ExtensionData)
+		if err != nil {
+			return TaskActionItem{}, err
+		}
+		changes.ExtensionData = xx
+	}
+	return TaskActionItem{
+		Item: Item{UUID: t.UUID, Kind: ItemKindTask, Action: ItemActionModified},
+		P:    changes,
+	}, nil
+}
+
+// UnmarshalJSON decodes the payload and keeps the raw JSON in Raw
+func (p *CheckListActionItemPayload) UnmarshalJSON(bs []byte) error {
+	type payload CheckListActionItemPayload
+	if err := json.Unmarshal(bs, (*payload)(p)); err != nil {
+		return err
+	}
+	p.Raw = append(json.RawMessage(nil), bs...)
+	return nil
+}
+
+// MarshalJSON encodes the payload. If it was decoded from JSON, the fields are merged
+// onto Raw.
+func (p CheckListActionItemPayload) MarshalJSON() ([]byte, error) {
+	type payload CheckListActionItemPayload
+	bs, err := json.Marshal(payload(p))
+	return marshalPayload(p.Raw, p, bs, err)
+}
+
+// UnmarshalJSON decodes the payload and keeps the raw JSON in Raw
+func (p *TagActionItemPayload) UnmarshalJSON(bs []byte) error {
+	type payload TagActionItemPayload
+	if err := json.Unmarshal(bs, (*payload)(p)); err != nil {
+		return err
+	}
+	p.Raw = append(json.RawMessage(nil), bs...)
+	return nil
+}
+
+// MarshalJSON encodes the payload. If it was decoded from JSON, the fields are merged
+// onto Raw.
+func (p TagActionItemPayload) MarshalJSON() ([]byte, error) {
+	type payload TagActionItemPayload
+	bs, err := json.Marshal(payload(p))
+	return marshalPayload(p.Raw, p, bs, err)
+}
+
+// UnmarshalJSON decodes the payload and keeps the raw JSON in Raw
+func (p *AreaActionItemPayload) UnmarshalJSON(bs []byte) error {
+	type payload AreaActionItemPayload
+	if err := json.Unmarshal(bs, (*payload)(p)); err != nil {
+		return err
+	}
+	p.Raw = append(json.RawMessage(nil), bs...)
+	return nil
+}
+
+// MarshalJSON encodes the payload. If it was decoded from JSON, the fields are merged
+// onto Raw.
+func (p AreaActionItemPayload) MarshalJSON() ([]byte, error) {
+	type payload AreaActionItemPayload
+	bs, err := json.Marshal(payload(p))
+	return marshalPayload(p.Raw, p, bs, err)
+}
+
+// UnmarshalJSON decodes the payload and keeps the raw JSON in Raw
+func (p *TombstoneActionItemPayload) UnmarshalJSON(bs []byte) error {
+	type payload TombstoneActionItemPayload
+	if err := json.Unmarshal(bs, (*payload)(p)); err != nil {
+		return err
+	}
+	p.Raw = append(json.RawMessage(nil), bs...)
+	return nil
+}
+
+// MarshalJSON encodes the payload. If it was decoded from JSON, the fields are merged
+// onto Raw.
+func (p TombstoneActionItemPayload) MarshalJSON() ([]byte, error) {
+	type payload TombstoneActionItemPayload
+	bs, err := json.Marshal(payload(p))
+	return marshalPayload(p.Raw, p, bs, err)
+}
@@ -0,0 +1,133 @@
+package thingscloud
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const rawTaskPayload = `{"tt":"Title","ss":0,"ai":{"sync":true},"xx":{"sn":{"a":1},"_t":"oo"}}`
+
+func TestTaskActionItemPayload_RoundTrip(t *testing.T) {
+	t.Parallel()
+	var p TaskActionItemPayload
+	if err := json.Unmarshal([]byte(rawTaskPayload), &p); err != nil {
+		t.Fatal(err)
+	}
+	if string(p.Raw) != rawTaskPayload {
+		t.Fatalf("Expected raw JSON to be kept, but got %s", p.Raw)
+	}
+
+	p.Title = String("Changed")
+	p.ExtensionData = json.RawMessage(`{"sn":{"b":2}}`)
+	bs, err := json.Marshal(TaskActionItem{Item: Item{Kind: ItemKindTask}, P: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		P map[string]json.RawMessage `json:"p"`
+	}
+	if err := json.Unmarshal(bs, &got); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"tt": `"Changed"`,
+		"ss": `0`,
+		"ai": `{"sync":true}`,
+		"xx": `{"_t":"oo","sn":{"a":1,"b":2}}`,
+	}
+	for k, v := range expected {
+		if string(got.P[k]) != v {
+			t.Errorf("Expected %s to be %s but got %s", k, v, got.P[k])
+		}
+	}
+}
+
+func TestTaskActionItemPayload_Clear(t *testing.T) {
+	t.Parallel()
+	var p TaskActionItemPayload
+	raw := `{"tt":"Title","dd":1772582400,"sr":1772582400,"tg":[],"ai":{"sync":true}}`
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		t.Fatal(err)
+	}
+	p.DeadlineDate = nil
+	bs, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(bs, &got); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"tt": `"Title"`,
+		"dd": `null`,
+		"sr": `1772582400`,
+		"tg": `[]`,
+		"ai": `{"sync":true}`,
+	}
+	for k, v := range expected {
+		if string(got[k]) != v {
+			t.Errorf("Expected %s to be %s but got %s", k, v, got[k])
+		}
+	}
+}
+
+func TestTaskActionItemPayload_Unmodelled(t *testing.T) {
+	t.Parallel()
+	var p TaskActionItemPayload
+	if err := json.Unmarshal([]byte(rawTaskPayload), &p); err != nil {
+		t.Fatal(err)
+	}
+	fields := p.Unmodelled()
+	if len(fields) != 2 || fields["ai"] == nil || fields["xx"] == nil {
+		t.Errorf("Expected ai and xx to be unmodelled, but got %v", fields)
+	}
+
+	built := TaskActionItemPayload{Title: String("Title")}
+	if fields := built.Unmodelled(); fields != nil {
+		t.Errorf("Expected no unmodelled fields on a payload built in code, but got %v", fields)
+	}
+}
+
+func TestTask_Update(t *testing.T) {
+	t.Parallel()
+	task := &Task{UUID: "task-1"}
+	task.MergeExtra(map[string]json.RawMessage{"xx": json.RawMessage(`{"sn":{"a":1},"_t":"oo"}`)})
+
+	item, err := task.Update(TaskActionItemPayload{
+		Title:         String("New"),
+		ExtensionData: json.RawMessage(`{"sn":{"b":2}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.UUID() != "task-1" || item.Action != ItemActionModified {
+		t.Errorf("Expected a modification of task-1, but got %+v", item.Item)
+	}
+	if string(item.P.ExtensionData) != `{"_t":"oo","sn":{"a":1,"b":2}}` {
+		t.Errorf("Expected extension data to be merged, but got %s", item.P.ExtensionData)
+	}
+}
+
+func TestMergePayload(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		Base     string
+		Changes  string
+		Expected string
+	}{
+		{`{"a":1,"b":{"c":1}}`, `{"b":{"d":2}}`, `{"a":1,"b":{"c":1,"d":2}}`},
+		{`{"a":{"c":1}}`, `{"a":null}`, `{"a":null}`},
+		{`null`, `{"a":1}`, `{"a":1}`},
+		{`{"a":1}`, `[1]`, `[1]`},
+	}
+	for _, testCase := range testCases {
+		got, err := MergePayload(json.RawMessage(testCase.Base), json.RawMessage(testCase.Changes))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != testCase.Expected {
+			t.Errorf("Expected %s but got %s", testCase.Expected, got)
+		}
+	}
+}
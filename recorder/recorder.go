@@ -0,0 +1,453 @@
+// Package recorder provides an http.RoundTripper which records thingscloud traffic
+// into tapes and replays it offline.
+//
+// A tape is a directory. Every response body is stored in its own file, in the same
+// format as the fixtures in tapes/, and tape.json indexes the interactions:
+//
+//	tapes/session/tape.json
+//	tapes/session/001-get-account.json
+//	tapes/session/002-get-history-items.json
+//
+// Credentials never reach the disk: the Authorization header is dropped, passwords and
+// push tokens are redacted, and the account email is replaced by Placeholder.
+//
+//	rec, err := recorder.New("tapes/session", recorder.Replay)
+//	client := thingscloud.NewWithOptions(thingscloud.APIEndpoint, email, password,
+//		thingscloud.WithTransport(rec))
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode selects what a Recorder does with requests
+type Mode int
+
+const (
+	// Replay answers requests from the tape, without touching the network
+	Replay Mode = iota
+	// Record sends requests upstream and appends them to the tape
+	Record
+	// Passthrough sends requests upstream without recording
+	Passthrough
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Replay:
+		return "replay"
+	case Record:
+		return "record"
+	case Passthrough:
+		return "passthrough"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// ParseMode parses "replay", "record" or "passthrough"
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(s) {
+	case "replay", "":
+		return Replay, nil
+	case "record":
+		return Record, nil
+	case "passthrough":
+		return Passthrough, nil
+	}
+	return Replay, fmt.Errorf("unknown recorder mode %q", s)
+}
+
+// Placeholder replaces the account email in recorded paths and bodies
+const Placeholder = "user@example.com"
+
+// indexFile is the name of the tape index within a tape directory
+const indexFile = "tape.json"
+
+// ErrNoInteraction is returned in replay mode for requests the tape has no answer for
+var ErrNoInteraction = errors.New("recorder: no recorded interaction")
+
+// Interaction is a recorded request and the response to it
+type Interaction struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	// RequestBody is the scrubbed request body, if it was JSON
+	RequestBody json.RawMessage `json:"request_body,omitempty"`
+	Status      int             `json:"status"`
+	Header      http.Header     `json:"header,omitempty"`
+	// File holds the response body, relative to the tape directory
+	File string `json:"file"`
+}
+
+type tape struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper recording to or replaying from a tape
+type Recorder struct {
+	// Transport sends requests upstream in Record and Passthrough mode,
+	// http.DefaultTransport if nil
+	Transport http.RoundTripper
+	// Email is the account email, which is replaced with Placeholder. It is taken
+	// from the request paths if empty.
+	Email string
+
+	mode Mode
+	dir  string
+
+	mu   sync.Mutex
+	tape tape
+	used map[int]bool
+}
+
+// New creates a recorder for the tape in dir. In replay mode the tape is loaded. In
+// record mode the directory is created and an existing tape is replaced by an empty
+// one: its index is rewritten and the body files it lists are removed. Other files
+// in dir are left alone.
+func New(dir string, mode Mode) (*Recorder, error) {
+	r := &Recorder{mode: mode, dir: dir, used: map[int]bool{}}
+	switch mode {
+	case Replay:
+		bs, err := os.ReadFile(filepath.Join(dir, indexFile))
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(bs, &r.tape); err != nil {
+			return nil, fmt.Errorf("parsing tape %s: %w", dir, err)
+		}
+	case Record:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		if err := removeTape(dir); err != nil {
+			return nil, err
+		}
+		if err := r.save(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// removeTape removes the body files of the tape in dir, if there is one
+func removeTape(dir string) error {
+	bs, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var old tape
+	if err := json.Unmarshal(bs, &old); err != nil {
+		return fmt.Errorf("parsing tape %s: %w", dir, err)
+	}
+	for _, in := range old.Interactions {
+		// only files within the tape directory belong to it
+		if in.File == "" || !filepath.IsLocal(in.File) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, in.File)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Mode returns the mode the recorder was created with
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Interactions returns the interactions of the tape
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.tape.Interactions...)
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch r.mode {
+	case Replay:
+		return r.replay(req)
+	case Record:
+		return r.record(req)
+	}
+	return r.transport().RoundTrip(req)
+}
+
+func (r *Recorder) transport() http.RoundTripper {
+	if r.Transport != nil {
+		return r.Transport
+	}
+	return http.DefaultTransport
+}
+
+// replay answers req with the first unused interaction matching method, path and query.
+// Once all matching interactions were used, the last one is repeated.
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	r.learnEmail(req.URL.Path)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, q := r.scrubString(req.URL.Path), r.scrubString(req.URL.RawQuery)
+	match := -1
+	for i, in := range r.tape.Interactions {
+		if in.Method != req.Method || in.Path != p || in.Query != q {
+			continue
+		}
+		match = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("%w for %s %s?%s", ErrNoInteraction, req.Method, p, q)
+	}
+	r.used[match] = true
+	in := r.tape.Interactions[match]
+
+	body, err := os.ReadFile(filepath.Join(r.dir, in.File))
+	if err != nil {
+		return nil, err
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	header := in.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+		StatusCode:    in.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// record sends req upstream and appends the interaction to the tape
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	resp, err := r.transport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Add(req, resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Add appends an exchange which did not pass through the recorder, e.g. one imported
+// from a capture, to the tape. The request body is taken from GetBody, the response
+// body is read and replaced, so resp can still be used. Add is only available in
+// Record mode.
+func (r *Recorder) Add(req *http.Request, resp *http.Response) error {
+	if r.mode != Record {
+		return fmt.Errorf("recorder: Add needs record mode, not %v", r.mode)
+	}
+	var reqBody []byte
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		reqBody, _ = io.ReadAll(body)
+		body.Close()
+	}
+	r.learnEmail(req.URL.Path)
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	in := Interaction{
+		Method: req.Method,
+		Path:   r.scrubString(req.URL.Path),
+		Query:  r.scrubString(req.URL.RawQuery),
+		Status: resp.StatusCode,
+		Header: recordedHeader(resp.Header),
+		File:   fmt.Sprintf("%03d-%s-%s.json", len(r.tape.Interactions)+1, strings.ToLower(req.Method), r.fileLabel(req.URL.Path)),
+	}
+	if len(reqBody) > 0 && json.Valid(reqBody) {
+		in.RequestBody = r.scrubJSON(reqBody, false)
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, in.File), r.scrubJSON(respBody, true), 0o644); err != nil {
+		return err
+	}
+	r.tape.Interactions = append(r.tape.Interactions, in)
+	return r.save()
+}
+
+// fileLabel names a body file after the endpoint, e.g. "history-items" for
+// /version/1/history/{id}/items. Emails and ids are left out.
+func (r *Recorder) fileLabel(p string) string {
+	var label []string
+	for i, part := range strings.Split(strings.Trim(p, "/"), "/") {
+		isID := len(part) == 36 && strings.Count(part, "-") == 4
+		if (i < 2 && (part == "version" || part == "1")) || isID || strings.Contains(part, "@") {
+			continue
+		}
+		label = append(label, part)
+	}
+	if len(label) == 0 {
+		return "root"
+	}
+	return strings.Join(label, "-")
+}
+
+// save writes the tape index
+func (r *Recorder) save() error {
+	bs, err := json.MarshalIndent(r.tape, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(r.dir, indexFile), append(bs, '\n'), 0o644)
+}
+
+// learnEmail takes the account email from an /account/{email} path
+func (r *Recorder) learnEmail(p string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Email != "" {
+		return
+	}
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "account" && strings.Contains(parts[i+1], "@") {
+			r.Email = parts[i+1]
+			return
+		}
+	}
+}
+
+func (r *Recorder) scrubString(s string) string {
+	if r.Email == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, r.Email, Placeholder)
+}
+
+// secretKeys are JSON keys whose values are never recorded
+var secretKeys = map[string]bool{"password": true, "apns-token": true}
+
+// scrubJSON redacts secrets and the email from a JSON body, which is indented if
+// pretty is set. Everything else is kept as sent, including the order of keys, so
+// replayed bodies match the recorded session.
+func (r *Recorder) scrubJSON(bs []byte, pretty bool) []byte {
+	out, err := redactSecrets(bs)
+	if err != nil {
+		out = bs
+	}
+	out = []byte(r.scrubString(string(out)))
+	var buf bytes.Buffer
+	if pretty {
+		err = json.Indent(&buf, out, "", "  ")
+	} else {
+		err = json.Compact(&buf, out)
+	}
+	if err != nil {
+		return out
+	}
+	return buf.Bytes()
+}
+
+// redacted replaces the values of secretKeys
+var redacted = []byte(`"[REDACTED]"`)
+
+// redactSecrets replaces the values of secretKeys in bs, copying the rest verbatim
+func redactSecrets(bs []byte) ([]byte, error) {
+	type span struct{ start, end int64 }
+	var spans []span
+	// the containers the decoder is in: '{' expecting a key, ':' expecting the
+	// value of a key, '[' within an array
+	var stack []byte
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := byte(0)
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if top == ':' {
+			// a value completes the key, nested containers are tracked on their own
+			stack[len(stack)-1] = '{'
+		}
+		switch tok := tok.(type) {
+		case json.Delim:
+			switch tok {
+			case '{', '[':
+				stack = append(stack, byte(tok))
+			default:
+				stack = stack[:len(stack)-1]
+			}
+		case string:
+			if top != '{' {
+				continue
+			}
+			if !secretKeys[tok] {
+				stack[len(stack)-1] = ':'
+				continue
+			}
+			start := dec.InputOffset()
+			for start < int64(len(bs)) && (bs[start] == ':' || isSpace(bs[start])) {
+				start++
+			}
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return nil, err
+			}
+			spans = append(spans, span{start, dec.InputOffset()})
+		}
+	}
+
+	var out []byte
+	last := int64(0)
+	for _, sp := range spans {
+		out = append(out, bs[last:sp.start]...)
+		out = append(out, redacted...)
+		last = sp.end
+	}
+	return append(out, bs[last:]...), nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// recordedHeader keeps the response headers which matter to the client
+func recordedHeader(h http.Header) http.Header {
+	out := http.Header{}
+	for _, k := range []string{"Content-Type", "Retry-After", "X-Request-Id"} {
+		if v := h.Values(k); len(v) > 0 {
+			out[k] = v
+		}
+	}
+	return out
+}
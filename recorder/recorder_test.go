@@ -0,0 +1,160 @@
+package recorder
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	thingscloud "github.com/arthursoares/things-cloud-sdk"
+	"github.com/arthursoares/things-cloud-sdk/thingscloudtest"
+)
+
+func newTask(uuid, title string) thingscloud.TaskActionItem {
+	return thingscloud.TaskActionItem{
+		Item: thingscloud.Item{UUID: uuid, Kind: thingscloud.ItemKindTask, Action: thingscloud.ItemActionCreated},
+		P:    thingscloud.TaskActionItemPayload{Title: thingscloud.String(title)},
+	}
+}
+
+// session runs the calls recorded and replayed by the tests
+func session(t *testing.T, c *thingscloud.Client) ([]thingscloud.Item, *thingscloud.CommitResult) {
+	t.Helper()
+	if _, err := c.Verify(); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	h, err := c.OwnHistory()
+	if err != nil {
+		t.Fatalf("OwnHistory failed: %v", err)
+	}
+	items, _, err := h.Items(thingscloud.ItemsOptions{})
+	if err != nil {
+		t.Fatalf("Items failed: %v", err)
+	}
+	res, err := h.Write(newTask("task-2", "Second"))
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	return items, res
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(t.TempDir(), "session")
+
+	s := thingscloudtest.NewServer("martin@example.com", "secret")
+	p, _ := json.Marshal(thingscloud.TaskActionItemPayload{Title: thingscloud.String("First")})
+	if _, err := s.Seed(s.OwnHistoryKey(), thingscloud.Item{UUID: "task-1", Kind: thingscloud.ItemKindTask, P: p}); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := New(dir, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := s.Client(thingscloud.WithTransport(rec), thingscloud.WithoutValidation())
+	wantItems, wantRes := session(t, recorded)
+	if _, err := recorded.Accounts.ChangePassword("rotated"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	t.Run("Scrubbed", func(t *testing.T) {
+		entries, _ := os.ReadDir(dir)
+		if len(entries) != len(rec.Interactions())+1 {
+			t.Errorf("Expected a body file per interaction and the index, got %d files", len(entries))
+		}
+		for _, e := range entries {
+			bs, _ := os.ReadFile(filepath.Join(dir, e.Name()))
+			for _, secret := range []string{"martin@example.com", "secret", "rotated"} {
+				if strings.Contains(string(bs), secret) {
+					t.Errorf("Expected %s to be scrubbed from %s, got %s", secret, e.Name(), bs)
+				}
+			}
+		}
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		rec, err := New(dir, Replay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := thingscloud.NewWithOptions(s.URL, "martin@example.com", "secret",
+			thingscloud.WithTransport(rec), thingscloud.WithRetryPolicy(thingscloud.NoRetry()), thingscloud.WithoutValidation())
+		items, res := session(t, c)
+		if len(items) != len(wantItems) || items[0].UUID != wantItems[0].UUID {
+			t.Errorf("Expected replayed items %v, got %v", wantItems, items)
+		}
+		if res.ServerHeadIndex != wantRes.ServerHeadIndex {
+			t.Errorf("Expected replayed head %d, got %d", wantRes.ServerHeadIndex, res.ServerHeadIndex)
+		}
+
+		h := c.HistoryWithID("unknown")
+		if _, _, err := h.Items(thingscloud.ItemsOptions{}); !errors.Is(err, ErrNoInteraction) {
+			t.Errorf("Expected ErrNoInteraction, got %v", err)
+		}
+	})
+}
+
+func TestRecorder_ScrubJSON(t *testing.T) {
+	t.Parallel()
+	r := &Recorder{Email: "martin@example.com"}
+	testCases := []struct {
+		Title    string
+		Body     string
+		Expected string
+	}{
+		{"Order", `{"b":{"z":1,"a":2},"a":"<b>&</b>"}`, `{"b":{"z":1,"a":2},"a":"<b>&</b>"}`},
+		{"Email", `{"z":"martin@example.com","a":["martin@example.com"]}`, `{"z":"user@example.com","a":["user@example.com"]}`},
+		{"Secrets", `{"z":1, "password" : "secret","apns-token":{"a":[1,"x"]},"a":{"password":null,"b":"password"}}`, `{"z":1,"password":"[REDACTED]","apns-token":"[REDACTED]","a":{"password":"[REDACTED]","b":"password"}}`},
+		{"Invalid", `{"password":`, `{"password":`},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Title, func(t *testing.T) {
+			if got := string(r.scrubJSON([]byte(testCase.Body), false)); got != testCase.Expected {
+				t.Errorf("Expected %s, got %s", testCase.Expected, got)
+			}
+		})
+	}
+}
+
+func TestNew_RecordReplacesTape(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	index := `{"interactions":[{"method":"GET","path":"/","status":200,"file":"001-get-root.json"}]}`
+	for name, body := range map[string]string{indexFile: index, "001-get-root.json": "{}", "notes.txt": "mine"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := New(dir, Record); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "001-get-root.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the old body file to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Errorf("Expected other files to be kept, got %v", err)
+	}
+	rec, err := New(dir, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Interactions(); len(got) != 0 {
+		t.Errorf("Expected an empty tape, got %v", got)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	t.Parallel()
+	for _, m := range []Mode{Replay, Record, Passthrough} {
+		got, err := ParseMode(m.String())
+		if err != nil || got != m {
+			t.Errorf("Expected %v, got %v (%v)", m, got, err)
+		}
+	}
+	if _, err := ParseMode("rewind"); err == nil {
+		t.Errorf("Expected an error for an unknown mode")
+	}
+}
@@ -302,6 +302,7 @@ func (c RepeaterConfiguration) nextDailyScheduledAt(repeat int) time.Time {
 
 // NextScheduledAt returns the next Nth date a rule should occur.
 // Note that things generates these ToDos as necessary.
+// Use Occurrences to list the occurrences within a date range.
 func (c RepeaterConfiguration) NextScheduledAt(repeat int) time.Time {
 	if c.FrequencyUnit == FrequencyUnitDaily {
 		return c.nextDailyScheduledAt(repeat)
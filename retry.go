@@ -0,0 +1,181 @@
+package thingscloud
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryEvent describes a failed attempt which is about to be retried
+type RetryEvent struct {
+	Method string
+	Path   string
+	// Attempt is the number of the attempt which failed, starting at 1
+	Attempt int
+	// StatusCode is the response status of the failed attempt, 0 on transport errors
+	StatusCode int
+	// Err is the transport error of the failed attempt, if any
+	Err error
+	// Delay is the time waited before the next attempt
+	Delay time.Duration
+}
+
+// RetryPolicy configures how the client retries requests which failed temporarily.
+//
+// Idempotent requests (GET, HEAD, PUT, DELETE) are retried on transport errors and on
+// 429, 500, 502, 503 and 504 responses. Commits are not idempotent: they are only
+// retried when the server signalled that it did not process the request (429 and 503),
+// so an item is never written twice.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry. It doubles with every attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between attempts, including waits requested via Retry-After
+	MaxDelay time.Duration
+	// Jitter is the fraction (0-1) of every wait which is randomised
+	Jitter float64
+	// OnRetry is called before waiting for the next attempt
+	OnRetry func(RetryEvent)
+}
+
+// DefaultRetryPolicy returns the retry policy used by clients created with New
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   2 * time.Second,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// NoRetry returns a retry policy which sends every request exactly once
+func NoRetry() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// WithRetryPolicy sets the retry policy, which defaults to DefaultRetryPolicy
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// backoff returns the wait before the attempt following the given failed attempt
+func (p RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	if d, ok := retryAfter(resp); ok {
+		if p.MaxDelay > 0 && d > p.MaxDelay {
+			d = p.MaxDelay
+		}
+		return d
+	}
+	d := p.BaseDelay << (attempt - 1)
+	if d < 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 && d > 0 {
+		d -= time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// shouldRetry decides whether a request should be sent again after the given outcome
+func (p RetryPolicy) shouldRetry(req *http.Request, resp *http.Response, err error, attempt int) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		return isIdempotent(req.Method)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return isIdempotent(req.Method)
+	}
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses the Retry-After header, which holds either seconds or an http date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// doWithRetry sends req, retrying according to the client's retry policy
+func (c *Client) doWithRetry(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.send(req)
+		if !c.retry.shouldRetry(req, resp, err, attempt) {
+			return resp, err
+		}
+
+		delay := c.retry.backoff(attempt, resp)
+		ev := RetryEvent{
+			Method:  req.Method,
+			Path:    req.URL.Path,
+			Attempt: attempt,
+			Err:     err,
+			Delay:   delay,
+		}
+		if resp != nil {
+			ev.StatusCode = resp.StatusCode
+			io.Copy(io.Discard, resp.Body) //nolint:errcheck
+			resp.Body.Close()
+		}
+		if c.retry.OnRetry != nil {
+			c.retry.OnRetry(ev)
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, req.Context().Err()
+		case <-t.C:
+		}
+	}
+}
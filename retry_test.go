@@ -0,0 +1,173 @@
+package thingscloud
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testRetryPolicy(events *[]RetryEvent) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		OnRetry: func(ev RetryEvent) {
+			*events = append(*events, ev)
+		},
+	}
+}
+
+func TestClient_Retry(t *testing.T) {
+	t.Run("RetriesIdempotentRequests", func(t *testing.T) {
+		t.Parallel()
+		var calls int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.Write([]byte(`{"latest-server-index":27}`))
+		}))
+		defer ts.Close()
+
+		var events []RetryEvent
+		c := NewWithOptions(ts.URL, "martin@example.com", "", WithRetryPolicy(testRetryPolicy(&events)))
+		h, err := c.History("33333abb-bfe4-4b03-a5c9-106d42220c72")
+		if err != nil {
+			t.Fatalf("Expected request to succeed after retries, but didn't: %v", err)
+		}
+		if h.LatestServerIndex != 27 {
+			t.Errorf("Expected LatestServerIndex of %d, but got %d", 27, h.LatestServerIndex)
+		}
+		if len(events) != 2 {
+			t.Fatalf("Expected 2 retry events, got %d", len(events))
+		}
+		if events[0].Attempt != 1 || events[0].StatusCode != http.StatusBadGateway || events[0].Method != "GET" {
+			t.Errorf("Unexpected retry event: %#v", events[0])
+		}
+	})
+
+	t.Run("GivesUpAfterMaxAttempts", func(t *testing.T) {
+		t.Parallel()
+		var calls int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer ts.Close()
+
+		var events []RetryEvent
+		c := NewWithOptions(ts.URL, "martin@example.com", "", WithRetryPolicy(testRetryPolicy(&events)))
+		_, err := c.History("33333abb-bfe4-4b03-a5c9-106d42220c72")
+		if !IsRetryable(err) {
+			t.Fatalf("Expected retryable error, got %v", err)
+		}
+		if calls != 3 {
+			t.Errorf("Expected 3 attempts, got %d", calls)
+		}
+	})
+
+	t.Run("CommitOnlyRetriedWhenSafe", func(t *testing.T) {
+		t.Parallel()
+		var status int32 = http.StatusInternalServerError
+		var calls int32
+		var bodies []string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bs, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, string(bs))
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(int(atomic.LoadInt32(&status)))
+				return
+			}
+			w.Write([]byte(`{"server-head-index":5}`))
+		}))
+		defer ts.Close()
+
+		var events []RetryEvent
+		c := NewWithOptions(ts.URL, "martin@example.com", "", WithRetryPolicy(testRetryPolicy(&events)))
+		h := c.HistoryWithID("33333abb-bfe4-4b03-a5c9-106d42220c72")
+		if _, err := h.Write(); err == nil {
+			t.Fatal("Expected commit failing with 500 not to be retried")
+		}
+		if calls != 1 {
+			t.Fatalf("Expected a single attempt, got %d", calls)
+		}
+
+		atomic.StoreInt32(&calls, 0)
+		atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+		bodies = nil
+		if _, err := h.Write(); err != nil {
+			t.Fatalf("Expected commit failing with 503 to be retried, got %v", err)
+		}
+		if calls != 2 {
+			t.Fatalf("Expected 2 attempts, got %d", calls)
+		}
+		if bodies[0] != bodies[1] || bodies[1] == "" {
+			t.Errorf("Expected retried commit to resend the body, got %q and %q", bodies[0], bodies[1])
+		}
+		if h.LatestServerIndex != 5 {
+			t.Errorf("Expected LatestServerIndex of %d, but got %d", 5, h.LatestServerIndex)
+		}
+	})
+
+	t.Run("HonoursRetryAfter", func(t *testing.T) {
+		t.Parallel()
+		var calls int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{}`))
+		}))
+		defer ts.Close()
+
+		var events []RetryEvent
+		c := NewWithOptions(ts.URL, "martin@example.com", "", WithRetryPolicy(testRetryPolicy(&events)))
+		if _, err := c.History("33333abb-bfe4-4b03-a5c9-106d42220c72"); err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || events[0].Delay != time.Second {
+			t.Errorf("Expected a single retry after 1s, got %#v", events)
+		}
+	})
+
+	t.Run("CanceledDuringWait", func(t *testing.T) {
+		t.Parallel()
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+
+		c := NewWithOptions(ts.URL, "martin@example.com", "",
+			WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}))
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := c.HistoryContext(ctx, "33333abb-bfe4-4b03-a5c9-106d42220c72")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	t.Parallel()
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		if got := p.backoff(attempt, nil); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.backoff(1, nil); got < 500*time.Millisecond || got > time.Second {
+			t.Fatalf("backoff with jitter out of range: %s", got)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package thingscloud
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnrepresentableRule is matched by errors.Is when a recurrence can't be converted
+// between Things' repeater configuration and an RFC 5545 RRULE
+var ErrUnrepresentableRule = errors.New("recurrence rule not representable")
+
+// neverEnding is the ed value Things writes for recurrences without end
+var neverEnding = time.Date(4001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// rruleDateLayout is the RFC 5545 DATE format used for UNTIL
+const rruleDateLayout = "20060102"
+
+var rruleWeekdays = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+func unrepresentable(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrUnrepresentableRule, fmt.Sprintf(format, args...))
+}
+
+// ToRRULE returns the recurrence as RFC 5545 RRULE value, without the "RRULE:" prefix,
+// e.g. "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TU". The start of the recurrence (ia) is not
+// part of an RRULE. Repeating after completion, and details RRULE can only express as
+// cross product (e.g. Jan 1st and Mar 3rd) are reported as ErrUnrepresentableRule.
+func (c RepeaterConfiguration) ToRRULE() (string, error) {
+	if c.Type != 0 {
+		return "", unrepresentable("repeating after completion (tp=%d)", c.Type)
+	}
+	if c.FrequencyAmplitude < 1 {
+		return "", unrepresentable("interval %d", c.FrequencyAmplitude)
+	}
+
+	parts := make([]string, 0, 5)
+	switch c.FrequencyUnit {
+	case FrequencyUnitDaily:
+		parts = append(parts, "FREQ=DAILY")
+	case FrequencyUnitWeekly:
+		parts = append(parts, "FREQ=WEEKLY")
+	case FrequencyUnitMonthly:
+		parts = append(parts, "FREQ=MONTHLY")
+	case FrequencyUnitYearly:
+		parts = append(parts, "FREQ=YEARLY")
+	default:
+		return "", unrepresentable("frequency unit %d", c.FrequencyUnit)
+	}
+	if c.FrequencyAmplitude != 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", c.FrequencyAmplitude))
+	}
+
+	by, err := c.rruleDetails()
+	if err != nil {
+		return "", err
+	}
+	parts = append(parts, by...)
+
+	count := c.RepeatCount != nil && *c.RepeatCount > 0
+	until := c.LastScheduledAt != nil && !c.IsNeverending()
+	switch {
+	case count && until:
+		return "", unrepresentable("both a repeat count and an end date")
+	case count:
+		parts = append(parts, fmt.Sprintf("COUNT=%d", *c.RepeatCount))
+	case until:
+		parts = append(parts, "UNTIL="+c.LastScheduledAt.Time().UTC().Format(rruleDateLayout))
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// rruleDetails converts the detail configuration to BY* parts
+func (c RepeaterConfiguration) rruleDetails() ([]string, error) {
+	if len(c.DetailConfiguration) == 0 && c.FrequencyUnit != FrequencyUnitDaily {
+		return nil, unrepresentable("no details for frequency unit %d", c.FrequencyUnit)
+	}
+	switch c.FrequencyUnit {
+	case FrequencyUnitWeekly:
+		days := make([]string, len(c.DetailConfiguration))
+		for i, dc := range c.DetailConfiguration {
+			if dc.Weekday == nil || dc.MonthOf != nil {
+				return nil, unrepresentable("weekly detail without plain weekday")
+			}
+			day, err := rruleWeekday(*dc.Weekday, nil)
+			if err != nil {
+				return nil, err
+			}
+			days[i] = day
+		}
+		return []string{"BYDAY=" + strings.Join(days, ",")}, nil
+
+	case FrequencyUnitMonthly, FrequencyUnitYearly:
+		yearly := c.FrequencyUnit == FrequencyUnitYearly
+		var months, days []string
+		byWeekday := c.DetailConfiguration[0].Weekday != nil
+		pairs := map[[2]string]bool{}
+		for _, dc := range c.DetailConfiguration {
+			var day string
+			var err error
+			switch {
+			case (dc.Weekday != nil) != byWeekday:
+				return nil, unrepresentable("both days of month and weekdays")
+			case byWeekday:
+				if dc.MonthOf == nil {
+					return nil, unrepresentable("weekday without occurrence in month")
+				}
+				day, err = rruleWeekday(*dc.Weekday, dc.MonthOf)
+			case dc.Day != nil:
+				day, err = rruleMonthDay(*dc.Day)
+			default:
+				return nil, unrepresentable("detail without day")
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(days, day) {
+				days = append(days, day)
+			}
+
+			var month string
+			if yearly {
+				if dc.Month == nil || *dc.Month < 0 || *dc.Month > 11 {
+					return nil, unrepresentable("yearly detail without month")
+				}
+				month = strconv.FormatInt(*dc.Month+1, 10)
+				if !slices.Contains(months, month) {
+					months = append(months, month)
+				}
+			}
+			pairs[[2]string{month, day}] = true
+		}
+		if yearly && len(pairs) != len(months)*len(days) {
+			return nil, unrepresentable("days which differ between months")
+		}
+
+		var parts []string
+		if yearly {
+			parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+		}
+		if byWeekday {
+			return append(parts, "BYDAY="+strings.Join(days, ",")), nil
+		}
+		return append(parts, "BYMONTHDAY="+strings.Join(days, ",")), nil
+	}
+	return nil, nil
+}
+
+func rruleWeekday(wd time.Weekday, n *int64) (string, error) {
+	if wd < time.Sunday || wd > time.Saturday {
+		return "", unrepresentable("weekday %d", wd)
+	}
+	if n == nil {
+		return rruleWeekdays[wd], nil
+	}
+	if *n == 0 || *n < -1 || *n > 5 {
+		return "", unrepresentable("occurrence %d of weekday in month", *n)
+	}
+	return strconv.FormatInt(*n, 10) + rruleWeekdays[wd], nil
+}
+
+func rruleMonthDay(dy int64) (string, error) {
+	switch {
+	case dy == -1:
+		return "-1", nil
+	case dy >= 0 && dy <= 30:
+		return strconv.FormatInt(dy+1, 10), nil
+	}
+	return "", unrepresentable("day of month %d", dy)
+}
+
+// RepeaterFromRRULE parses an RFC 5545 RRULE value, with or without "RRULE:" prefix,
+// into a repeater configuration. DAILY, WEEKLY with BYDAY, MONTHLY with BYMONTHDAY or
+// with BYDAY of the nth (or -1 for last) weekday, YEARLY with BYMONTH and either of
+// these, INTERVAL, COUNT and UNTIL are supported. Other parts, and rules which rely on
+// DTSTART for their days, are reported as ErrUnrepresentableRule.
+//
+// The first occurrence (ia) and start reference (sr) are not set, see
+// ComputeFirstScheduledAt.
+func RepeaterFromRRULE(rule string) (*RepeaterConfiguration, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	parts := map[string]string{}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		if _, dup := parts[key]; dup {
+			return nil, fmt.Errorf("duplicate RRULE part %s", key)
+		}
+		parts[key] = strings.ToUpper(strings.TrimSpace(value))
+	}
+	for key := range parts {
+		switch key {
+		case "FREQ", "INTERVAL", "BYDAY", "BYMONTHDAY", "BYMONTH", "COUNT", "UNTIL", "WKST":
+		default:
+			return nil, unrepresentable("RRULE part %s", key)
+		}
+	}
+
+	if parts["FREQ"] == "" {
+		return nil, errors.New("RRULE without FREQ")
+	}
+
+	c := &RepeaterConfiguration{FrequencyAmplitude: 1, Version: 4, RepeatCount: new(int64)}
+	if v, ok := parts["INTERVAL"]; ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid INTERVAL %q", v)
+		}
+		c.FrequencyAmplitude = n
+	}
+
+	days, err := parseRRULEDays(parts)
+	if err != nil {
+		return nil, err
+	}
+	switch parts["FREQ"] {
+	case "DAILY":
+		if len(days) > 0 || parts["BYMONTH"] != "" {
+			return nil, unrepresentable("daily rule with BY parts")
+		}
+		c.FrequencyUnit = FrequencyUnitDaily
+		c.DetailConfiguration = []RepeaterDetailConfiguration{{Day: new(int64)}}
+	case "WEEKLY":
+		if parts["BYMONTH"] != "" {
+			return nil, unrepresentable("weekly rule with BYMONTH")
+		}
+		for _, d := range days {
+			if d.Weekday == nil || d.MonthOf != nil {
+				return nil, unrepresentable("weekly rule without plain BYDAY weekdays")
+			}
+		}
+		c.FrequencyUnit = FrequencyUnitWeekly
+		c.DetailConfiguration = days
+	case "MONTHLY":
+		if parts["BYMONTH"] != "" {
+			return nil, unrepresentable("monthly rule with BYMONTH")
+		}
+		c.FrequencyUnit = FrequencyUnitMonthly
+		c.DetailConfiguration = days
+	case "YEARLY":
+		months, err := parseRRULEInts(parts["BYMONTH"], 1, 12)
+		if err != nil {
+			return nil, err
+		}
+		if len(months) == 0 {
+			return nil, unrepresentable("yearly rule without BYMONTH")
+		}
+		c.FrequencyUnit = FrequencyUnitYearly
+		for _, m := range months {
+			for _, d := range days {
+				d.Month = Int64(m - 1)
+				c.DetailConfiguration = append(c.DetailConfiguration, d)
+			}
+		}
+	default:
+		return nil, unrepresentable("frequency %s", parts["FREQ"])
+	}
+	if len(c.DetailConfiguration) == 0 {
+		return nil, unrepresentable("%s rule without days, which would depend on DTSTART", parts["FREQ"])
+	}
+
+	count, hasCount := parts["COUNT"]
+	until, hasUntil := parts["UNTIL"]
+	switch {
+	case hasCount && hasUntil:
+		return nil, errors.New("RRULE with both COUNT and UNTIL")
+	case hasCount:
+		n, err := strconv.ParseInt(count, 10, 64)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid COUNT %q", count)
+		}
+		c.RepeatCount = &n
+	case hasUntil:
+		if len(until) < len(rruleDateLayout) {
+			return nil, fmt.Errorf("invalid UNTIL %q", until)
+		}
+		t, err := time.Parse(rruleDateLayout, until[:len(rruleDateLayout)])
+		if err != nil {
+			return nil, fmt.Errorf("invalid UNTIL %q: %w", until, err)
+		}
+		c.LastScheduledAt = Time(t)
+	default:
+		c.LastScheduledAt = Time(neverEnding)
+	}
+	return c, nil
+}
+
+// parseRRULEDays converts BYDAY or BYMONTHDAY to detail configurations
+func parseRRULEDays(parts map[string]string) ([]RepeaterDetailConfiguration, error) {
+	byDay, byMonthDay := parts["BYDAY"], parts["BYMONTHDAY"]
+	if byDay != "" && byMonthDay != "" {
+		return nil, unrepresentable("BYDAY combined with BYMONTHDAY")
+	}
+
+	var days []RepeaterDetailConfiguration
+	if byMonthDay != "" {
+		ns, err := parseRRULEInts(byMonthDay, -31, 31)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range ns {
+			switch {
+			case n == -1:
+				days = append(days, RepeaterDetailConfiguration{Day: Int64(-1)})
+			case n > 0:
+				days = append(days, RepeaterDetailConfiguration{Day: Int64(n - 1)})
+			default:
+				return nil, unrepresentable("BYMONTHDAY %d", n)
+			}
+		}
+	}
+	if byDay != "" {
+		for _, v := range strings.Split(byDay, ",") {
+			if len(v) < 2 {
+				return nil, fmt.Errorf("invalid BYDAY %q", v)
+			}
+			wd := slices.Index(rruleWeekdays[:], v[len(v)-2:])
+			if wd < 0 {
+				return nil, fmt.Errorf("invalid BYDAY %q", v)
+			}
+			weekday := time.Weekday(wd)
+			d := RepeaterDetailConfiguration{Weekday: &weekday}
+			if prefix := v[:len(v)-2]; prefix != "" {
+				n, err := strconv.ParseInt(strings.TrimPrefix(prefix, "+"), 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid BYDAY %q", v)
+				}
+				if n == 0 || n < -1 || n > 5 {
+					return nil, unrepresentable("BYDAY %s", v)
+				}
+				d.MonthOf = &n
+			} else if parts["FREQ"] != "WEEKLY" {
+				return nil, unrepresentable("BYDAY %s without occurrence in month", v)
+			}
+			days = append(days, d)
+		}
+	}
+	return days, nil
+}
+
+// parseRRULEInts parses a comma separated list of integers within [min, max]
+func parseRRULEInts(s string, min, max int64) ([]int64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var ns []int64
+	for _, v := range strings.Split(s, ",") {
+		n, err := strconv.ParseInt(strings.TrimPrefix(v, "+"), 10, 64)
+		if err != nil || n < min || n > max {
+			return nil, fmt.Errorf("invalid RRULE value %q", v)
+		}
+		ns = append(ns, n)
+	}
+	return ns, nil
+}
@@ -0,0 +1,126 @@
+package thingscloud
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRepeaterConfiguration_ToRRULE(t *testing.T) {
+	testCases := []struct {
+		Title    string
+		Data     []byte
+		Expected string
+	}{
+		{"Every day", rcEveryDay, "FREQ=DAILY"},
+		{"Every day until", rcEveryDayEndDate, "FREQ=DAILY;UNTIL=20180301"},
+		{"Every day twice", rcEveryDayEndRepeat, "FREQ=DAILY;COUNT=2"},
+		{"Every 2nd day", rcEvery2ndDay, "FREQ=DAILY;INTERVAL=2"},
+		{"Every 2nd week on monday and tuesday", rcEvery2ndWeekOnMondayAndTuesday, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TU"},
+		{"Every week on monday until", rcEveryWeekOnMondayEndDate, "FREQ=WEEKLY;BYDAY=MO;UNTIL=20180318"},
+		{"Every first and third day of every month", rc1stDayAnd3rdDayEveryMonth, "FREQ=MONTHLY;BYMONTHDAY=1,3"},
+		{"Every first and last day of every month", rc1stAndLastDayEveryMonth, "FREQ=MONTHLY;BYMONTHDAY=1,-1"},
+		{"Every last Monday of every 2nd month", rcLastMondayEvery2ndMonth, "FREQ=MONTHLY;INTERVAL=2;BYDAY=-1MO"},
+		{"Every first Monday of every 2nd month", rcFirstMondayEvery2ndMonth, "FREQ=MONTHLY;INTERVAL=2;BYDAY=1MO"},
+		{"Every first and last day of february", rc1stAndLastDayFebuaryEveryYear, "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=1,-1"},
+		{"Every first day of january and march", rc1stJanuaryAnd1stMarchEveryYear, "FREQ=YEARLY;BYMONTH=1,3;BYMONTHDAY=1"},
+		{"Every last Wednesday of february", rcLastWednesdayFebuaryEveryYear, "FREQ=YEARLY;BYMONTH=2;BYDAY=-1WE"},
+		{"Last day of january twice", rcLastDayJanuaryEveryYearEndRepeat, "FREQ=YEARLY;BYMONTH=1;BYMONTHDAY=-1;COUNT=2"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Title, func(t *testing.T) {
+			var rc RepeaterConfiguration
+			if err := json.Unmarshal(testCase.Data, &rc); err != nil {
+				t.Fatal(err)
+			}
+			got, err := rc.ToRRULE()
+			if err != nil {
+				t.Fatalf("Expected %s, got error %v", testCase.Expected, err)
+			}
+			if got != testCase.Expected {
+				t.Errorf("Expected %s, got %s", testCase.Expected, got)
+			}
+
+			// parsing the rule again yields the same recurrence
+			parsed, err := RepeaterFromRRULE("RRULE:" + got)
+			if err != nil {
+				t.Fatalf("Expected %s to parse, got %v", got, err)
+			}
+			if parsed.FrequencyUnit != rc.FrequencyUnit || parsed.FrequencyAmplitude != rc.FrequencyAmplitude ||
+				!reflect.DeepEqual(parsed.DetailConfiguration, rc.DetailConfiguration) {
+				t.Errorf("Expected %+v to round trip, got %+v", rc, parsed)
+			}
+			if again, _ := parsed.ToRRULE(); again != got {
+				t.Errorf("Expected %s to round trip, got %s", got, again)
+			}
+		})
+	}
+}
+
+func TestRepeaterConfiguration_ToRRULEUnrepresentable(t *testing.T) {
+	testCases := []struct {
+		Title string
+		Data  []byte
+	}{
+		{"Days differing between months", rc1stJanuaryAndLastWednesdayFebuaryEveryYear},
+		{"Days and weekdays", rc1stDayAnd2ndMondayEveryMonth},
+		{"After completion", []byte(`{"rrv":4,"tp":1,"of":[{"wd":2}],"fu":256,"fa":1,"rc":0,"ed":64092211200}`)},
+		{"Count and end date", []byte(`{"rrv":4,"of":[{"dy":0}],"fu":16,"fa":1,"rc":2,"ed":1519862400}`)},
+		{"Unknown unit", []byte(`{"rrv":4,"of":[{"dy":0}],"fu":2,"fa":1}`)},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Title, func(t *testing.T) {
+			var rc RepeaterConfiguration
+			if err := json.Unmarshal(testCase.Data, &rc); err != nil {
+				t.Fatal(err)
+			}
+			if rule, err := rc.ToRRULE(); !errors.Is(err, ErrUnrepresentableRule) {
+				t.Errorf("Expected ErrUnrepresentableRule, got %q, %v", rule, err)
+			}
+		})
+	}
+}
+
+func TestRepeaterFromRRULE(t *testing.T) {
+	rc, err := RepeaterFromRRULE("freq=weekly;byday=TU,TH;wkst=MO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rc.IsNeverending() || *rc.RepeatCount != 0 || rc.Version != 4 {
+		t.Errorf("Expected a never ending rule, got %+v", rc)
+	}
+	if rc.FirstScheduledAt != nil {
+		t.Errorf("Expected the first occurrence to be left to ComputeFirstScheduledAt")
+	}
+
+	rc, err = RepeaterFromRRULE("FREQ=MONTHLY;BYMONTHDAY=15;UNTIL=20260630T235959Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rc.LastScheduledAt.Format("2006-01-02"); got != "2026-06-30" {
+		t.Errorf("Expected UNTIL as end date, got %s", got)
+	}
+
+	for _, rule := range []string{
+		"FREQ=HOURLY",
+		"FREQ=WEEKLY",
+		"FREQ=MONTHLY;BYDAY=MO",
+		"FREQ=MONTHLY;BYDAY=3MO;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYDAY=-2FR",
+		"FREQ=MONTHLY;BYMONTHDAY=-2",
+		"FREQ=MONTHLY;BYSETPOS=-1;BYDAY=MO,TU,WE,TH,FR",
+		"FREQ=YEARLY;BYMONTHDAY=1",
+		"FREQ=DAILY;BYMONTH=1",
+	} {
+		if _, err := RepeaterFromRRULE(rule); !errors.Is(err, ErrUnrepresentableRule) {
+			t.Errorf("Expected %s to be unrepresentable, got %v", rule, err)
+		}
+	}
+
+	for _, rule := range []string{"", "FREQ", "BYDAY=MO", "FREQ=DAILY;COUNT=0", "FREQ=DAILY;COUNT=2;UNTIL=20260101", "FREQ=WEEKLY;BYDAY=XX"} {
+		if _, err := RepeaterFromRRULE(rule); err == nil || errors.Is(err, ErrUnrepresentableRule) {
+			t.Errorf("Expected %q to be invalid, got %v", rule, err)
+		}
+	}
+}
@@ -57,13 +57,13 @@ func (s *State) updateTask(item things.TaskActionItem) *things.Task {
 		t.Schedule = *item.P.Schedule
 	}
 	if item.P.ScheduledDate != nil {
-		t.ScheduledDate = item.P.ScheduledDate.Time()
+		t.ScheduledDate = things.DatePtr(*item.P.ScheduledDate)
 	}
 	if item.P.CompletionDate != nil {
 		t.CompletionDate = item.P.CompletionDate.Time()
 	}
 	if item.P.DeadlineDate != nil {
-		t.DeadlineDate = item.P.DeadlineDate.Time()
+		t.DeadlineDate = things.DatePtr(*item.P.DeadlineDate)
 	}
 	if item.P.CreationDate != nil {
 		cd := item.P.CreationDate.Time()
@@ -121,6 +121,7 @@ func (s *State) updateTask(item things.TaskActionItem) *things.Task {
 	if item.P.RecurrenceTaskIDs != nil {
 		t.RecurrenceIDs = *item.P.RecurrenceTaskIDs
 	}
+	t.MergeExtra(item.P.Unmodelled())
 
 	return t
 }
@@ -362,6 +363,21 @@ func (s *State) TasksWithoutArea() []*things.Task {
 	return tasks
 }
 
+// HasTask reports whether a task with the given UUID exists
+func (s *State) HasTask(uuid string) bool {
+	return s.Tasks[uuid] != nil
+}
+
+// HasArea reports whether an area with the given UUID exists
+func (s *State) HasArea(uuid string) bool {
+	return s.Areas[uuid] != nil
+}
+
+// HasTag reports whether a tag with the given UUID exists
+func (s *State) HasTag(uuid string) bool {
+	return s.Tags[uuid] != nil
+}
+
 // AreaByName returns an Area if the name matches
 func (s *State) AreaByName(name string) *things.Area {
 	for _, area := range s.Areas {
@@ -476,3 +476,35 @@ func TestState_TasksWithoutArea_DeletedParent(t *testing.T) {
 	result := s.TasksWithoutArea()
 	_ = result // just verify no panic
 }
+
+func TestState_TaskExtra(t *testing.T) {
+	t.Parallel()
+	s := NewState()
+	if err := s.Update(things.Item{
+		UUID:   "TASK-UUID",
+		Action: things.ItemActionCreated,
+		Kind:   things.ItemKindTask,
+		P:      json.RawMessage(newTaskPayload),
+	}); err != nil {
+		t.Fatal(err.Error())
+	}
+	task := s.Tasks["TASK-UUID"]
+	if string(task.Extra["sb"]) != "0" || string(task.Extra["rr"]) != "null" {
+		t.Fatalf("Expected unmodelled fields sb and rr in Extra, but got %s", task.Extra)
+	}
+	if _, ok := task.Extra["tt"]; ok {
+		t.Error("Expected modelled field tt not to be in Extra")
+	}
+
+	if err := s.Update(things.Item{
+		UUID:   "TASK-UUID",
+		Action: things.ItemActionModified,
+		Kind:   things.ItemKindTask,
+		P:      json.RawMessage(`{"sb":1}`),
+	}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(task.Extra["sb"]) != "1" || string(task.Extra["rr"]) != "null" {
+		t.Errorf("Expected sb to be replaced and rr to be kept, but got %s", task.Extra)
+	}
+}
@@ -208,7 +208,7 @@ func (c TaskMovedToSomeday) ChangeType() string {
 type TaskMovedToUpcoming struct {
 	taskChange
 	From         TaskLocation
-	ScheduledFor time.Time
+	ScheduledFor things.Date
 }
 
 // ChangeType returns "TaskMovedToUpcoming"
@@ -219,7 +219,7 @@ func (c TaskMovedToUpcoming) ChangeType() string {
 // TaskDeadlineChanged indicates a task's deadline was modified
 type TaskDeadlineChanged struct {
 	taskChange
-	OldDeadline *time.Time
+	OldDeadline *things.Date
 }
 
 // ChangeType returns "TaskDeadlineChanged"
@@ -6,7 +6,8 @@ import (
 	things "github.com/arthursoares/things-cloud-sdk"
 )
 
-// detectTaskChanges compares old and new task state and returns semantic changes
+// detectTaskChanges compares old and new task state and returns semantic changes.
+// ts is the time of detection in the user's location, its calendar day is today.
 func detectTaskChanges(old, new *things.Task, serverIndex int, ts time.Time) []Change {
 	var changes []Change
 	base := baseChange{serverIndex: serverIndex, timestamp: ts}
@@ -93,8 +94,9 @@ func detectTaskChanges(old, new *things.Task, serverIndex int, ts time.Time) []C
 
 	// Schedule/location changed (only for regular tasks)
 	if new.Type == things.TaskTypeTask {
-		oldLoc := taskLocation(old)
-		newLoc := taskLocation(new)
+		today := things.DateOf(ts)
+		oldLoc := taskLocation(old, today)
+		newLoc := taskLocation(new, today)
 		if oldLoc != newLoc {
 			tc := taskChange{baseChange: base, Task: new}
 			switch newLoc {
@@ -107,7 +109,7 @@ func detectTaskChanges(old, new *things.Task, serverIndex int, ts time.Time) []C
 			case LocationSomeday:
 				changes = append(changes, TaskMovedToSomeday{taskChange: tc, From: oldLoc})
 			case LocationUpcoming:
-				var scheduledFor time.Time
+				var scheduledFor things.Date
 				if new.ScheduledDate != nil {
 					scheduledFor = *new.ScheduledDate
 				}
@@ -117,7 +119,7 @@ func detectTaskChanges(old, new *things.Task, serverIndex int, ts time.Time) []C
 	}
 
 	// Deadline changed
-	if !timeEqual(old.DeadlineDate, new.DeadlineDate) {
+	if !dateEqual(old.DeadlineDate, new.DeadlineDate) {
 		changes = append(changes, TaskDeadlineChanged{taskChange: taskChange{baseChange: base, Task: new}, OldDeadline: old.DeadlineDate})
 	}
 
@@ -131,7 +133,7 @@ func detectTaskChanges(old, new *things.Task, serverIndex int, ts time.Time) []C
 }
 
 // taskLocation determines where a task lives based on schedule and dates
-func taskLocation(t *things.Task) TaskLocation {
+func taskLocation(t *things.Task, today things.Date) TaskLocation {
 	if t == nil {
 		return LocationUnknown
 	}
@@ -139,12 +141,12 @@ func taskLocation(t *things.Task) TaskLocation {
 	case things.TaskScheduleInbox:
 		return LocationInbox
 	case things.TaskScheduleAnytime:
-		if t.ScheduledDate != nil && isToday(t.ScheduledDate) {
+		if isToday(t.ScheduledDate, today) {
 			return LocationToday
 		}
 		return LocationAnytime
 	case things.TaskScheduleSomeday:
-		if t.ScheduledDate != nil && t.ScheduledDate.After(time.Now()) {
+		if t.ScheduledDate != nil && t.ScheduledDate.After(today) {
 			return LocationUpcoming
 		}
 		return LocationSomeday
@@ -152,22 +154,15 @@ func taskLocation(t *things.Task) TaskLocation {
 	return LocationUnknown
 }
 
-func isToday(t *time.Time) bool {
-	if t == nil {
-		return false
-	}
-	now := time.Now()
-	return t.Year() == now.Year() && t.YearDay() == now.YearDay()
+func isToday(d *things.Date, today things.Date) bool {
+	return d != nil && *d == today
 }
 
-func timeEqual(a, b *time.Time) bool {
-	if a == nil && b == nil {
-		return true
-	}
+func dateEqual(a, b *things.Date) bool {
 	if a == nil || b == nil {
-		return false
+		return a == b
 	}
-	return a.Equal(*b)
+	return *a == *b
 }
 
 func diffStringSlices(old, new []string) (added, removed []string) {
@@ -286,7 +286,7 @@ func TestDetectTaskChanges(t *testing.T) {
 
 	t.Run("task moved to today", func(t *testing.T) {
 		t.Parallel()
-		today := time.Now()
+		today := things.DateOf(now)
 		old := &things.Task{UUID: "t1", Title: "Task", Schedule: things.TaskScheduleInbox}
 		new := &things.Task{UUID: "t1", Title: "Task", Schedule: things.TaskScheduleAnytime, ScheduledDate: &today}
 		changes := detectTaskChanges(old, new, 1, now)
@@ -366,9 +366,30 @@ func TestDetectTaskChanges(t *testing.T) {
 		}
 	})
 
+	t.Run("task moved to today in the user's location", func(t *testing.T) {
+		t.Parallel()
+		// 23:30 in Los Angeles is already the next day in UTC
+		la := time.FixedZone("PST", -8*60*60)
+		late := time.Date(2026, time.March, 4, 23, 30, 0, 0, la)
+		scheduled := things.NewDate(2026, time.March, 4)
+		old := &things.Task{UUID: "t1", Title: "Task", Schedule: things.TaskScheduleInbox}
+		new := &things.Task{UUID: "t1", Title: "Task", Schedule: things.TaskScheduleAnytime, ScheduledDate: &scheduled}
+
+		changes := detectTaskChanges(old, new, 1, late)
+		if len(changes) != 1 {
+			t.Fatalf("expected 1 change, got %d", len(changes))
+		}
+		if _, ok := changes[0].(TaskMovedToToday); !ok {
+			t.Errorf("expected TaskMovedToToday, got %T", changes[0])
+		}
+		if _, ok := detectTaskChanges(old, new, 1, late.UTC())[0].(TaskMovedToAnytime); !ok {
+			t.Errorf("expected TaskMovedToAnytime once the day is over")
+		}
+	})
+
 	t.Run("task moved to upcoming", func(t *testing.T) {
 		t.Parallel()
-		futureDate := time.Now().AddDate(0, 0, 7) // one week in future
+		futureDate := things.DateOf(now).AddDays(7) // one week in future
 		old := &things.Task{UUID: "t1", Title: "Task", Schedule: things.TaskScheduleAnytime}
 		new := &things.Task{UUID: "t1", Title: "Task", Schedule: things.TaskScheduleSomeday, ScheduledDate: &futureDate}
 		changes := detectTaskChanges(old, new, 1, now)
@@ -380,7 +401,7 @@ func TestDetectTaskChanges(t *testing.T) {
 				if mc.From != LocationAnytime {
 					t.Errorf("expected From LocationAnytime, got %v", mc.From)
 				}
-				if !mc.ScheduledFor.Equal(futureDate) {
+				if mc.ScheduledFor != futureDate {
 					t.Errorf("expected ScheduledFor %v, got %v", futureDate, mc.ScheduledFor)
 				}
 				break
@@ -393,8 +414,8 @@ func TestDetectTaskChanges(t *testing.T) {
 
 	t.Run("task deadline changed", func(t *testing.T) {
 		t.Parallel()
-		oldDeadline := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
-		newDeadline := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+		oldDeadline := things.NewDate(2025, 1, 1)
+		newDeadline := things.NewDate(2025, 2, 1)
 		old := &things.Task{UUID: "t1", Title: "Task", DeadlineDate: &oldDeadline}
 		new := &things.Task{UUID: "t1", Title: "Task", DeadlineDate: &newDeadline}
 		changes := detectTaskChanges(old, new, 1, now)
@@ -406,14 +427,14 @@ func TestDetectTaskChanges(t *testing.T) {
 		if !ok {
 			t.Fatalf("expected TaskDeadlineChanged, got %T", changes[0])
 		}
-		if dc.OldDeadline == nil || !dc.OldDeadline.Equal(oldDeadline) {
+		if dc.OldDeadline == nil || *dc.OldDeadline != oldDeadline {
 			t.Errorf("expected OldDeadline %v, got %v", oldDeadline, dc.OldDeadline)
 		}
 	})
 
 	t.Run("task deadline added", func(t *testing.T) {
 		t.Parallel()
-		newDeadline := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+		newDeadline := things.NewDate(2025, 2, 1)
 		old := &things.Task{UUID: "t1", Title: "Task", DeadlineDate: nil}
 		new := &things.Task{UUID: "t1", Title: "Task", DeadlineDate: &newDeadline}
 		changes := detectTaskChanges(old, new, 1, now)
@@ -432,7 +453,7 @@ func TestDetectTaskChanges(t *testing.T) {
 
 	t.Run("task deadline removed", func(t *testing.T) {
 		t.Parallel()
-		oldDeadline := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		oldDeadline := things.NewDate(2025, 1, 1)
 		old := &things.Task{UUID: "t1", Title: "Task", DeadlineDate: &oldDeadline}
 		new := &things.Task{UUID: "t1", Title: "Task", DeadlineDate: nil}
 		changes := detectTaskChanges(old, new, 1, now)
@@ -444,7 +465,7 @@ func TestDetectTaskChanges(t *testing.T) {
 		if !ok {
 			t.Fatalf("expected TaskDeadlineChanged, got %T", changes[0])
 		}
-		if dc.OldDeadline == nil || !dc.OldDeadline.Equal(oldDeadline) {
+		if dc.OldDeadline == nil || *dc.OldDeadline != oldDeadline {
 			t.Errorf("expected OldDeadline %v, got %v", oldDeadline, dc.OldDeadline)
 		}
 	})
@@ -1012,10 +1033,11 @@ func TestDetectChecklistChanges(t *testing.T) {
 
 func TestTaskLocation(t *testing.T) {
 	t.Parallel()
+	today := things.Today(time.Local)
 
 	t.Run("nil task returns unknown", func(t *testing.T) {
 		t.Parallel()
-		loc := taskLocation(nil)
+		loc := taskLocation(nil, today)
 		if loc != LocationUnknown {
 			t.Errorf("expected LocationUnknown, got %v", loc)
 		}
@@ -1024,7 +1046,7 @@ func TestTaskLocation(t *testing.T) {
 	t.Run("inbox schedule", func(t *testing.T) {
 		t.Parallel()
 		task := &things.Task{Schedule: things.TaskScheduleInbox}
-		loc := taskLocation(task)
+		loc := taskLocation(task, today)
 		if loc != LocationInbox {
 			t.Errorf("expected LocationInbox, got %v", loc)
 		}
@@ -1033,7 +1055,7 @@ func TestTaskLocation(t *testing.T) {
 	t.Run("anytime schedule without date", func(t *testing.T) {
 		t.Parallel()
 		task := &things.Task{Schedule: things.TaskScheduleAnytime}
-		loc := taskLocation(task)
+		loc := taskLocation(task, today)
 		if loc != LocationAnytime {
 			t.Errorf("expected LocationAnytime, got %v", loc)
 		}
@@ -1041,9 +1063,8 @@ func TestTaskLocation(t *testing.T) {
 
 	t.Run("anytime schedule with today date", func(t *testing.T) {
 		t.Parallel()
-		today := time.Now()
 		task := &things.Task{Schedule: things.TaskScheduleAnytime, ScheduledDate: &today}
-		loc := taskLocation(task)
+		loc := taskLocation(task, today)
 		if loc != LocationToday {
 			t.Errorf("expected LocationToday, got %v", loc)
 		}
@@ -1052,7 +1073,7 @@ func TestTaskLocation(t *testing.T) {
 	t.Run("someday schedule without date", func(t *testing.T) {
 		t.Parallel()
 		task := &things.Task{Schedule: things.TaskScheduleSomeday}
-		loc := taskLocation(task)
+		loc := taskLocation(task, today)
 		if loc != LocationSomeday {
 			t.Errorf("expected LocationSomeday, got %v", loc)
 		}
@@ -1060,9 +1081,9 @@ func TestTaskLocation(t *testing.T) {
 
 	t.Run("someday schedule with future date", func(t *testing.T) {
 		t.Parallel()
-		futureDate := time.Now().AddDate(0, 0, 7)
+		futureDate := today.AddDays(7)
 		task := &things.Task{Schedule: things.TaskScheduleSomeday, ScheduledDate: &futureDate}
-		loc := taskLocation(task)
+		loc := taskLocation(task, today)
 		if loc != LocationUpcoming {
 			t.Errorf("expected LocationUpcoming, got %v", loc)
 		}
@@ -1070,9 +1091,9 @@ func TestTaskLocation(t *testing.T) {
 
 	t.Run("someday schedule with past date", func(t *testing.T) {
 		t.Parallel()
-		pastDate := time.Now().AddDate(0, 0, -7)
+		pastDate := today.AddDays(-7)
 		task := &things.Task{Schedule: things.TaskScheduleSomeday, ScheduledDate: &pastDate}
-		loc := taskLocation(task)
+		loc := taskLocation(task, today)
 		if loc != LocationSomeday {
 			t.Errorf("expected LocationSomeday (past date), got %v", loc)
 		}
@@ -1106,47 +1127,47 @@ func TestTaskLocationString(t *testing.T) {
 	}
 }
 
-func TestTimeEqual(t *testing.T) {
+func TestDateEqual(t *testing.T) {
 	t.Parallel()
 
 	t.Run("both nil", func(t *testing.T) {
 		t.Parallel()
-		if !timeEqual(nil, nil) {
+		if !dateEqual(nil, nil) {
 			t.Error("expected nil == nil to be true")
 		}
 	})
 
 	t.Run("first nil", func(t *testing.T) {
 		t.Parallel()
-		now := time.Now()
-		if timeEqual(nil, &now) {
-			t.Error("expected nil != time to be false")
+		d := things.NewDate(2025, 1, 1)
+		if dateEqual(nil, &d) {
+			t.Error("expected nil != date to be false")
 		}
 	})
 
 	t.Run("second nil", func(t *testing.T) {
 		t.Parallel()
-		now := time.Now()
-		if timeEqual(&now, nil) {
-			t.Error("expected time != nil to be false")
+		d := things.NewDate(2025, 1, 1)
+		if dateEqual(&d, nil) {
+			t.Error("expected date != nil to be false")
 		}
 	})
 
-	t.Run("equal times", func(t *testing.T) {
+	t.Run("equal dates", func(t *testing.T) {
 		t.Parallel()
-		now := time.Now()
-		same := now
-		if !timeEqual(&now, &same) {
-			t.Error("expected equal times to be true")
+		d := things.NewDate(2025, 1, 1)
+		same := things.NewDate(2024, 12, 32)
+		if !dateEqual(&d, &same) {
+			t.Error("expected equal dates to be true")
 		}
 	})
 
-	t.Run("different times", func(t *testing.T) {
+	t.Run("different dates", func(t *testing.T) {
 		t.Parallel()
-		t1 := time.Now()
-		t2 := t1.Add(time.Hour)
-		if timeEqual(&t1, &t2) {
-			t.Error("expected different times to be false")
+		d1 := things.NewDate(2025, 1, 1)
+		d2 := d1.AddDays(1)
+		if dateEqual(&d1, &d2) {
+			t.Error("expected different dates to be false")
 		}
 	})
 }
@@ -1208,34 +1229,35 @@ func TestDiffStringSlices(t *testing.T) {
 
 func TestIsToday(t *testing.T) {
 	t.Parallel()
+	today := things.NewDate(2026, 3, 4)
 
 	t.Run("nil returns false", func(t *testing.T) {
 		t.Parallel()
-		if isToday(nil) {
+		if isToday(nil, today) {
 			t.Error("expected nil to return false")
 		}
 	})
 
 	t.Run("today returns true", func(t *testing.T) {
 		t.Parallel()
-		now := time.Now()
-		if !isToday(&now) {
+		d := things.NewDate(2026, 3, 4)
+		if !isToday(&d, today) {
 			t.Error("expected today to return true")
 		}
 	})
 
 	t.Run("yesterday returns false", func(t *testing.T) {
 		t.Parallel()
-		yesterday := time.Now().AddDate(0, 0, -1)
-		if isToday(&yesterday) {
+		yesterday := today.AddDays(-1)
+		if isToday(&yesterday, today) {
 			t.Error("expected yesterday to return false")
 		}
 	})
 
 	t.Run("tomorrow returns false", func(t *testing.T) {
 		t.Parallel()
-		tomorrow := time.Now().AddDate(0, 0, 1)
-		if isToday(&tomorrow) {
+		tomorrow := today.AddDays(1)
+		if isToday(&tomorrow, today) {
 			t.Error("expected tomorrow to return false")
 		}
 	})
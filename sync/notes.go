@@ -0,0 +1,155 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	things "github.com/arthursoares/things-cloud-sdk"
+)
+
+// StaleNotes returns the UUIDs of tasks whose note patches did not apply during sync.
+// Their notes keep the last text which did; RefetchNotes rebuilds them.
+func (s *Syncer) StaleNotes() ([]string, error) {
+	rows, err := s.db.Query(`SELECT task_uuid FROM stale_notes ORDER BY server_index, task_uuid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uuids []string
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, err
+		}
+		uuids = append(uuids, uuid)
+	}
+	return uuids, rows.Err()
+}
+
+// RefetchNotes rebuilds the notes returned by StaleNotes by replaying their history
+// from the server, up to the sync cursor. It returns a TaskNoteChanged for every
+// note which differs from the stored one. Notes whose history still doesn't apply
+// stay stale.
+func (s *Syncer) RefetchNotes() ([]Change, error) {
+	return s.RefetchNotesContext(context.Background())
+}
+
+// RefetchNotesContext is like RefetchNotes but carries a context
+func (s *Syncer) RefetchNotesContext(ctx context.Context) ([]Change, error) {
+	stale, err := s.StaleNotes()
+	if err != nil || len(stale) == 0 {
+		return nil, err
+	}
+	historyID, cursor, err := s.getSyncState()
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := s.replayNotes(ctx, historyID, cursor, stale)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.rawDB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op if committed
+
+	origDB := s.db
+	s.db = tx
+	defer func() { s.db = origDB }()
+
+	var changes []Change
+	ts := time.Now().In(s.Location())
+	for _, uuid := range stale {
+		n := notes[uuid]
+		if n.err != nil {
+			continue
+		}
+		old, err := s.getTask(uuid)
+		if err != nil {
+			return nil, fmt.Errorf("getting task %s: %w", uuid, err)
+		}
+		if old != nil && old.Note != n.text {
+			t := *old
+			t.Note = n.text
+			if err := s.saveTask(&t); err != nil {
+				return nil, fmt.Errorf("saving task: %w", err)
+			}
+			payload, _ := json.Marshal(map[string]string{"nt": n.text})
+			for _, change := range detectTaskChanges(old, &t, cursor, ts) {
+				if err := s.logChange(cursor, change, string(payload)); err != nil {
+					return nil, fmt.Errorf("logging change: %w", err)
+				}
+				changes = append(changes, change)
+			}
+		}
+		if err := s.clearNoteStale(uuid); err != nil {
+			return nil, fmt.Errorf("clearing stale note: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+	return changes, nil
+}
+
+// replayedNote is the note of a task rebuilt from its history
+type replayedNote struct {
+	text string
+	err  error
+}
+
+// replayNotes fetches the history up to cursor and applies the note payloads of the
+// given tasks in order, verifying every patch. Once a note diverged, only a full text
+// note or a re-creation resets it.
+func (s *Syncer) replayNotes(ctx context.Context, historyID string, cursor int, uuids []string) (map[string]*replayedNote, error) {
+	notes := make(map[string]*replayedNote, len(uuids))
+	for _, uuid := range uuids {
+		notes[uuid] = &replayedNote{}
+	}
+
+	h := s.client.HistoryWithID(historyID)
+	for start := 0; start < cursor; {
+		commits, more, err := h.CommitsContext(ctx, things.ItemsOptions{StartIndex: start})
+		if err != nil {
+			return nil, err
+		}
+		for _, commit := range commits {
+			if commit.Index >= cursor {
+				return notes, nil
+			}
+			for _, item := range commit.Items {
+				n, ok := notes[item.UUID]
+				if !ok {
+					continue
+				}
+				if item.Action != things.ItemActionModified {
+					// creations start from an empty note, deletions end it
+					*n = replayedNote{}
+				}
+				var p struct {
+					Note json.RawMessage `json:"nt"`
+				}
+				if err := json.Unmarshal(item.P, &p); err != nil || len(p.Note) == 0 {
+					continue
+				}
+				if n.err != nil && !isFullTextNote(p.Note) {
+					// patches can't apply to a diverged note
+					continue
+				}
+				n.text, n.err = parseNotePayload(n.text, p.Note)
+			}
+		}
+		if len(commits) == 0 || !more {
+			break
+		}
+		start = h.LoadedServerIndex
+	}
+	return notes, nil
+}
@@ -2,7 +2,9 @@ package sync
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"time"
 
 	things "github.com/arthursoares/things-cloud-sdk"
@@ -11,9 +13,20 @@ import (
 // Note: database/sql types are used via the dbExecutor interface defined in sync.go
 
 // processItems processes a batch of Things Cloud items into semantic changes.
-// The baseIndex is the starting server index for this batch.
+// The baseIndex is the starting server index for this batch, every item is
+// treated as a commit of its own.
 func (s *Syncer) processItems(items []things.Item, baseIndex int) ([]Change, error) {
-	if len(items) == 0 {
+	commits := make([]things.Commit, len(items))
+	for i, item := range items {
+		commits[i] = things.Commit{Index: baseIndex + i, Items: []things.Item{item}}
+	}
+	return s.processCommits(commits)
+}
+
+// processCommits processes a batch of commits into semantic changes.
+// Every change is logged with the server index of the commit it belongs to.
+func (s *Syncer) processCommits(commits []things.Commit) ([]Change, error) {
+	if len(commits) == 0 {
 		return nil, nil
 	}
 
@@ -31,24 +44,26 @@ func (s *Syncer) processItems(items []things.Item, baseIndex int) ([]Change, err
 
 	var allChanges []Change
 
-	for i, item := range items {
-		serverIndex := baseIndex + i
-		ts := time.Now()
+	for _, commit := range commits {
+		serverIndex := commit.Index
+		for _, item := range commit.Items {
+			ts := time.Now().In(s.Location())
 
-		changes, err := s.processItem(item, serverIndex, ts)
-		if err != nil {
-			return nil, fmt.Errorf("processing item %s: %w", item.UUID, err)
-		}
+			changes, err := s.processItem(item, serverIndex, ts)
+			if err != nil {
+				return nil, fmt.Errorf("processing item %s: %w", item.UUID, err)
+			}
 
-		// Log each change
-		for _, change := range changes {
-			payload, _ := json.Marshal(item.P)
-			if err := s.logChange(serverIndex, change, string(payload)); err != nil {
-				return nil, fmt.Errorf("logging change: %w", err)
+			// Log each change
+			for _, change := range changes {
+				payload, _ := json.Marshal(item.P)
+				if err := s.logChange(serverIndex, change, string(payload)); err != nil {
+					return nil, fmt.Errorf("logging change: %w", err)
+				}
 			}
-		}
 
-		allChanges = append(allChanges, changes...)
+			allChanges = append(allChanges, changes...)
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -109,8 +124,33 @@ func (s *Syncer) processTaskItem(item things.Item, serverIndex int, ts time.Time
 		return nil, fmt.Errorf("unmarshaling task payload: %w", err)
 	}
 
-	// Apply payload to build new state
-	newTask := applyTaskPayload(old, item.UUID, payload)
+	// A stale note misses patches, later ones can't be applied to it until
+	// RefetchNotes or a full text note replaces it
+	if len(payload.Note) > 0 && item.Action == things.ItemActionModified && !isFullTextNote(payload.Note) {
+		stale, err := s.isNoteStale(item.UUID)
+		if err != nil {
+			return nil, fmt.Errorf("checking stale note: %w", err)
+		}
+		if stale {
+			payload.Note = nil
+		}
+	}
+
+	// Apply payload to build new state. A note whose patches don't apply keeps its
+	// last good text and is marked for RefetchNotes instead.
+	newTask, err := applyTaskPayload(old, item.UUID, payload)
+	switch {
+	case errors.Is(err, things.ErrNoteDiverged):
+		if err := s.markNoteStale(item.UUID, serverIndex); err != nil {
+			return nil, fmt.Errorf("marking note stale: %w", err)
+		}
+	case err != nil:
+		return nil, err
+	case len(payload.Note) > 0:
+		if err := s.clearNoteStale(item.UUID); err != nil {
+			return nil, fmt.Errorf("clearing stale note: %w", err)
+		}
+	}
 
 	// Save the new state
 	if err := s.saveTask(newTask); err != nil {
@@ -348,7 +388,9 @@ func (s *Syncer) processTombstone(item things.Item, serverIndex int, ts time.Tim
 }
 
 // applyTaskPayload applies a task payload to an existing task state (or creates a new one).
-func applyTaskPayload(old *things.Task, uuid string, p things.TaskActionItemPayload) *things.Task {
+// If the note patches don't apply, the note is kept and an ErrNoteDiverged error is
+// returned along with the otherwise updated task.
+func applyTaskPayload(old *things.Task, uuid string, p things.TaskActionItemPayload) (*things.Task, error) {
 	// Start with old state or create new with defaults
 	t := &things.Task{
 		UUID:     uuid,
@@ -378,6 +420,7 @@ func applyTaskPayload(old *things.Task, uuid string, p things.TaskActionItemPayl
 		t.TagIDs = old.TagIDs
 		t.RecurrenceIDs = old.RecurrenceIDs
 		t.DelegateIDs = old.DelegateIDs
+		t.Extra = maps.Clone(old.Extra)
 	}
 
 	// Apply each non-nil field from payload
@@ -394,17 +437,17 @@ func applyTaskPayload(old *things.Task, uuid string, p things.TaskActionItemPayl
 		t.Title = *p.Title
 	}
 	if p.ScheduledDate != nil {
-		t.ScheduledDate = p.ScheduledDate.Time()
+		t.ScheduledDate = things.DatePtr(*p.ScheduledDate)
 	}
 	if p.TaskIR != nil {
 		// TaskIR (tir) is an alternative scheduled date field
-		t.ScheduledDate = p.TaskIR.Time()
+		t.ScheduledDate = things.DatePtr(*p.TaskIR)
 	}
 	if p.CompletionDate != nil {
 		t.CompletionDate = p.CompletionDate.Time()
 	}
 	if p.DeadlineDate != nil {
-		t.DeadlineDate = p.DeadlineDate.Time()
+		t.DeadlineDate = things.DatePtr(*p.DeadlineDate)
 	}
 	if p.Index != nil {
 		t.Index = *p.Index
@@ -446,43 +489,73 @@ func applyTaskPayload(old *things.Task, uuid string, p things.TaskActionItemPayl
 		t.DelegateIDs = *p.DelegateIDs
 	}
 
+	t.MergeExtra(p.Unmodelled())
+
 	// Handle Note specially: can be string or Note struct with patches
 	if len(p.Note) > 0 {
-		t.Note = parseNotePayload(t.Note, p.Note)
+		note, err := parseNotePayload(t.Note, p.Note)
+		if err != nil {
+			return t, fmt.Errorf("task %s: %w", uuid, err)
+		}
+		t.Note = note
 	}
 
-	return t
+	return t, nil
 }
 
 // parseNotePayload parses the note field from a task payload.
 // The note can be either a plain string or a structured Note object with patches.
-func parseNotePayload(currentNote string, raw json.RawMessage) string {
+// Patches are verified against their checksums, and the resulting text against the
+// note's checksum; if they don't fit the current note, the current note is returned
+// with an ErrNoteDiverged error.
+func parseNotePayload(currentNote string, raw json.RawMessage) (string, error) {
 	// First, try to unmarshal as a string (most common case)
 	var noteStr string
 	if err := json.Unmarshal(raw, &noteStr); err == nil {
-		return noteStr
+		return noteStr, nil
 	}
 
 	// Try to unmarshal as a structured Note
 	var note things.Note
 	if err := json.Unmarshal(raw, &note); err != nil {
 		// If both fail, return the current note unchanged
-		return currentNote
+		return currentNote, nil
 	}
 
 	// Handle based on note type
+	var text string
 	switch note.Type {
 	case things.NoteTypeFullText:
 		// Full text replacement
-		return note.Value
+		text = note.Value
 	case things.NoteTypeDelta:
 		// Apply patches to current note
-		return things.ApplyPatches(currentNote, note.Patches)
+		patched, err := things.ApplyPatchesVerified(currentNote, note.Patches)
+		if err != nil {
+			return currentNote, err
+		}
+		text = patched
 	default:
 		// Unknown type, return value if present
 		if note.Value != "" {
-			return note.Value
+			return note.Value, nil
 		}
-		return currentNote
+		return currentNote, nil
+	}
+	if note.Checksum != 0 {
+		if actual := things.NoteChecksum(text); actual != note.Checksum {
+			return currentNote, &things.NoteDivergedError{Patch: -1, Checksum: note.Checksum, Actual: actual}
+		}
+	}
+	return text, nil
+}
+
+// isFullTextNote reports whether a note payload replaces the note instead of patching
+// it, so it applies even to a note which diverged.
+func isFullTextNote(raw json.RawMessage) bool {
+	var note things.Note
+	if err := json.Unmarshal(raw, &note); err != nil {
+		return true
 	}
+	return note.Type != things.NoteTypeDelta
 }
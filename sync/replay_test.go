@@ -0,0 +1,84 @@
+package sync
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	things "github.com/arthursoares/things-cloud-sdk"
+	"github.com/arthursoares/things-cloud-sdk/recorder"
+	"github.com/arthursoares/things-cloud-sdk/thingscloudtest"
+)
+
+// TestSync_Replay replays the session in tapes/sync-session. Run it with
+// THINGS_RECORD=1 to record the session again from a fake server.
+func TestSync_Replay(t *testing.T) {
+	t.Parallel()
+
+	const tapeDir = "../tapes/sync-session"
+	mode := recorder.Replay
+	endpoint := "http://replay.invalid"
+	if os.Getenv("THINGS_RECORD") != "" {
+		mode = recorder.Record
+		server := thingscloudtest.NewServer("test@example.com", "password")
+		defer server.Close()
+		for _, title := range []string{"One", "Two"} {
+			p, _ := json.Marshal(things.TaskActionItemPayload{Title: things.String(title), Type: things.TaskTypePtr(things.TaskTypeTask)})
+			if _, err := server.Seed(server.OwnHistoryKey(), things.Item{UUID: "task-" + title, Kind: things.ItemKindTask, Action: things.ItemActionCreated, P: p}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		endpoint = server.URL
+		os.RemoveAll(tapeDir)
+	}
+	rec, err := recorder.New(tapeDir, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := things.NewWithOptions(endpoint, "test@example.com", "password",
+		things.WithTransport(rec), things.WithRetryPolicy(things.NoRetry()), things.WithStrictDecoding())
+
+	syncer, err := Open(filepath.Join(t.TempDir(), "test.db"), client)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer syncer.Close()
+
+	changes, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(changes))
+	}
+	if r := client.DriftReport(); r.HasDrift() || r.Items[things.ItemKindTask] != 2 {
+		t.Errorf("expected 2 tasks checked without drift, got %+v", r)
+	}
+
+	h, err := client.OwnHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	status := things.TaskStatusCompleted
+	if _, err := h.Write(things.TaskActionItem{
+		Item: things.Item{UUID: "task-One", Kind: things.ItemKindTask, Action: things.ItemActionModified},
+		P:    things.TaskActionItemPayload{Status: &status},
+	}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	changes, err = syncer.Sync()
+	if err != nil {
+		t.Fatalf("second Sync() failed: %v", err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d", len(changes))
+	}
+	if _, ok := changes[0].(TaskCompleted); !ok {
+		t.Errorf("expected TaskCompleted, got %T", changes[0])
+	}
+}
@@ -1,6 +1,6 @@
 package sync
 
-const schemaVersion = 2
+const schemaVersion = 4
 
 const schema = `
 -- Schema version tracking
@@ -53,6 +53,7 @@ CREATE TABLE IF NOT EXISTS tasks (
     heading_uuid TEXT,
     alarm_time_offset INTEGER,
     recurrence_rule TEXT,
+    extra TEXT,
     deleted INTEGER DEFAULT 0
 );
 
@@ -80,6 +81,12 @@ CREATE TABLE IF NOT EXISTS area_tags (
     PRIMARY KEY (area_uuid, tag_uuid)
 );
 
+-- Tasks whose note diverged from the server's and needs to be replayed
+CREATE TABLE IF NOT EXISTS stale_notes (
+    task_uuid TEXT PRIMARY KEY,
+    server_index INTEGER NOT NULL
+);
+
 -- Change log
 CREATE TABLE IF NOT EXISTS change_log (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -123,6 +130,19 @@ CREATE INDEX IF NOT EXISTS idx_tasks_project_uuid ON tasks(project_uuid);
 CREATE INDEX IF NOT EXISTS idx_checklist_items_task_uuid ON checklist_items(task_uuid);
 `
 
+// migration3 stores the payload fields a task has no column for, as a JSON object
+const migration3 = `
+ALTER TABLE tasks ADD COLUMN extra TEXT;
+`
+
+// migration4 tracks tasks whose note patches did not apply
+const migration4 = `
+CREATE TABLE IF NOT EXISTS stale_notes (
+    task_uuid TEXT PRIMARY KEY,
+    server_index INTEGER NOT NULL
+);
+`
+
 func (s *Syncer) migrate() error {
 	// Check current version
 	var version int
@@ -148,6 +168,18 @@ func (s *Syncer) migrate() error {
 		}
 	}
 
+	if version < 3 {
+		if _, err := s.db.Exec(migration3); err != nil {
+			return err
+		}
+	}
+
+	if version < 4 {
+		if _, err := s.db.Exec(migration4); err != nil {
+			return err
+		}
+	}
+
 	// Update schema version
 	_, err = s.db.Exec("UPDATE schema_version SET version = ?", schemaVersion)
 	return err
@@ -9,12 +9,13 @@ import (
 
 // State provides read-only access to the synced Things state
 type State struct {
-	db dbExecutor
+	db  dbExecutor
+	loc *time.Location
 }
 
 // State returns a read-only view of the current aggregated state
 func (s *Syncer) State() *State {
-	return &State{db: s.rawDB}
+	return &State{db: s.rawDB, loc: s.Location()}
 }
 
 // QueryOpts controls filtering for state queries
@@ -115,10 +116,10 @@ func (st *State) TasksInInbox(opts QueryOpts) ([]*things.Task, error) {
 	return st.queryTasks(query)
 }
 
-// TasksInToday returns tasks scheduled for today
+// TasksInToday returns tasks scheduled for today in the syncer's location
 func (st *State) TasksInToday(opts QueryOpts) ([]*things.Task, error) {
-	today := time.Now().Truncate(24 * time.Hour)
-	tomorrow := today.Add(24 * time.Hour)
+	today := things.Today(st.loc)
+	tomorrow := today.AddDays(1)
 
 	query := `SELECT uuid FROM tasks WHERE type = 0 AND schedule = 1
 		AND scheduled_date >= ? AND scheduled_date < ? AND deleted = 0`
@@ -2,6 +2,7 @@ package sync
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	things "github.com/arthursoares/things-cloud-sdk"
@@ -15,7 +16,7 @@ func (s *Syncer) getTask(uuid string) (*things.Task, error) {
 			uuid, type, title, note, status, schedule,
 			scheduled_date, deadline_date, completion_date, creation_date, modification_date,
 			"index", today_index, in_trash, area_uuid, project_uuid, heading_uuid,
-			alarm_time_offset, recurrence_rule, deleted
+			alarm_time_offset, recurrence_rule, extra, deleted
 		FROM tasks
 		WHERE uuid = ?
 	`, uuid)
@@ -36,6 +37,7 @@ func (s *Syncer) getTask(uuid string) (*things.Task, error) {
 		headingUUID      sql.NullString
 		alarmTimeOffset  sql.NullInt64
 		recurrenceRule   sql.NullString
+		extra            sql.NullString
 		deleted          int
 	)
 
@@ -43,7 +45,7 @@ func (s *Syncer) getTask(uuid string) (*things.Task, error) {
 		&t.UUID, &taskType, &t.Title, &t.Note, &status, &schedule,
 		&scheduledDate, &deadlineDate, &completionDate, &creationDate, &modificationDate,
 		&t.Index, &t.TodayIndex, &inTrash, &areaUUID, &projectUUID, &headingUUID,
-		&alarmTimeOffset, &recurrenceRule, &deleted,
+		&alarmTimeOffset, &recurrenceRule, &extra, &deleted,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -60,12 +62,10 @@ func (s *Syncer) getTask(uuid string) (*things.Task, error) {
 
 	// Convert nullable timestamps
 	if scheduledDate.Valid {
-		ts := time.Unix(scheduledDate.Int64, 0).UTC()
-		t.ScheduledDate = &ts
+		t.ScheduledDate = things.DatePtr(things.DateFromUnix(scheduledDate.Int64))
 	}
 	if deadlineDate.Valid {
-		ts := time.Unix(deadlineDate.Int64, 0).UTC()
-		t.DeadlineDate = &ts
+		t.DeadlineDate = things.DatePtr(things.DateFromUnix(deadlineDate.Int64))
 	}
 	if completionDate.Valid {
 		ts := time.Unix(completionDate.Int64, 0).UTC()
@@ -96,6 +96,12 @@ func (s *Syncer) getTask(uuid string) (*things.Task, error) {
 		t.AlarmTimeOffset = &offset
 	}
 
+	if extra.Valid {
+		if err := json.Unmarshal([]byte(extra.String), &t.Extra); err != nil {
+			return nil, err
+		}
+	}
+
 	// Load tags from junction table
 	rows, err := s.db.Query(`SELECT tag_uuid FROM task_tags WHERE task_uuid = ?`, uuid)
 	if err != nil {
@@ -163,19 +169,30 @@ func (s *Syncer) saveTask(t *things.Task) error {
 		inTrash = 1
 	}
 
+	// Unmodelled payload fields are kept as a JSON object
+	var extra sql.NullString
+	if len(t.Extra) > 0 {
+		bs, err := json.Marshal(t.Extra)
+		if err != nil {
+			return err
+		}
+		extra = sql.NullString{String: string(bs), Valid: true}
+	}
+
 	// Insert or replace the task
 	_, err := s.db.Exec(`
 		INSERT OR REPLACE INTO tasks (
 			uuid, type, title, note, status, schedule,
 			scheduled_date, deadline_date, completion_date, creation_date, modification_date,
 			"index", today_index, in_trash, area_uuid, project_uuid, heading_uuid,
-			alarm_time_offset, recurrence_rule, deleted
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0)
+			alarm_time_offset, recurrence_rule, extra, deleted
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0)
 	`,
 		t.UUID, int(t.Type), t.Title, t.Note, int(t.Status), int(t.Schedule),
 		scheduledDate, deadlineDate, completionDate, creationDate, modificationDate,
 		t.Index, t.TodayIndex, inTrash, areaUUID, projectUUID, headingUUID,
 		alarmTimeOffset, sql.NullString{}, // recurrence_rule not directly on Task struct
+		extra,
 	)
 	if err != nil {
 		return err
@@ -398,3 +415,26 @@ func (s *Syncer) logChange(serverIndex int, change Change, payload string) error
 	`, serverIndex, time.Now().Unix(), change.ChangeType(), change.EntityType(), change.EntityUUID(), payload)
 	return err
 }
+
+// markNoteStale records that the note of a task diverged at serverIndex. An existing
+// mark keeps the index at which the note diverged first.
+func (s *Syncer) markNoteStale(taskUUID string, serverIndex int) error {
+	_, err := s.db.Exec(`
+		INSERT OR IGNORE INTO stale_notes (task_uuid, server_index)
+		VALUES (?, ?)
+	`, taskUUID, serverIndex)
+	return err
+}
+
+// clearNoteStale removes the stale mark of a task's note, if any.
+func (s *Syncer) clearNoteStale(taskUUID string) error {
+	_, err := s.db.Exec(`DELETE FROM stale_notes WHERE task_uuid = ?`, taskUUID)
+	return err
+}
+
+// isNoteStale reports whether the note of a task is marked stale.
+func (s *Syncer) isNoteStale(taskUUID string) (bool, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM stale_notes WHERE task_uuid = ?`, taskUUID).Scan(&n)
+	return n > 0, err
+}
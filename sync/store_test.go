@@ -1,6 +1,7 @@
 package sync
 
 import (
+	"encoding/json"
 	"path/filepath"
 	"testing"
 	"time"
@@ -62,6 +63,32 @@ func TestTaskStorage(t *testing.T) {
 		}
 	})
 
+	t.Run("unmodelled fields survive modifications", func(t *testing.T) {
+		items := []things.Item{
+			{UUID: "task-extra", Kind: things.ItemKindTask, Action: things.ItemActionCreated,
+				P: json.RawMessage(`{"tt":"Extra","ai":1,"xx":{"sn":{},"_t":"oo"}}`)},
+			{UUID: "task-extra", Kind: things.ItemKindTask, Action: things.ItemActionModified,
+				P: json.RawMessage(`{"tt":"Renamed","ai":2}`)},
+		}
+		if _, err := syncer.processItems(items, 0); err != nil {
+			t.Fatalf("processItems failed: %v", err)
+		}
+
+		retrieved, err := syncer.getTask("task-extra")
+		if err != nil {
+			t.Fatalf("getTask failed: %v", err)
+		}
+		if retrieved.Title != "Renamed" {
+			t.Errorf("Title mismatch: got %q", retrieved.Title)
+		}
+		if string(retrieved.Extra["ai"]) != "2" || string(retrieved.Extra["xx"]) != `{"sn":{},"_t":"oo"}` {
+			t.Errorf("Extra mismatch: got %s", retrieved.Extra)
+		}
+		if _, ok := retrieved.Extra["tt"]; ok {
+			t.Error("modelled field tt should not be in Extra")
+		}
+	})
+
 	t.Run("soft delete task", func(t *testing.T) {
 		task := &things.Task{UUID: "to-delete", Title: "Delete Me"}
 		syncer.saveTask(task)
@@ -79,8 +106,8 @@ func TestTaskStorage(t *testing.T) {
 
 	t.Run("save task with all optional fields", func(t *testing.T) {
 		now := time.Now().Truncate(time.Second).UTC()
-		scheduled := now.Add(24 * time.Hour)
-		deadline := now.Add(48 * time.Hour)
+		scheduled := things.DateOf(now).AddDays(1)
+		deadline := things.DateOf(now).AddDays(2)
 		completion := now.Add(time.Hour)
 		modification := now.Add(30 * time.Minute)
 		alarmOffset := 3600
@@ -155,10 +182,10 @@ func TestTaskStorage(t *testing.T) {
 		}
 
 		// Verify dates (compare Unix timestamps to avoid timezone issues)
-		if retrieved.ScheduledDate == nil || retrieved.ScheduledDate.Unix() != scheduled.Unix() {
+		if retrieved.ScheduledDate == nil || *retrieved.ScheduledDate != scheduled {
 			t.Errorf("ScheduledDate mismatch")
 		}
-		if retrieved.DeadlineDate == nil || retrieved.DeadlineDate.Unix() != deadline.Unix() {
+		if retrieved.DeadlineDate == nil || *retrieved.DeadlineDate != deadline {
 			t.Errorf("DeadlineDate mismatch")
 		}
 		if retrieved.CompletionDate == nil || retrieved.CompletionDate.Unix() != completion.Unix() {
@@ -3,19 +3,15 @@
 package sync
 
 import (
+	"context"
 	"database/sql"
-	"strings"
+	"errors"
 	"time"
 
 	things "github.com/arthursoares/things-cloud-sdk"
 	_ "modernc.org/sqlite"
 )
 
-const (
-	maxRetries    = 3
-	retryBaseWait = 2 * time.Second
-)
-
 // dbExecutor is the interface for database operations (satisfied by *sql.DB and *sql.Tx)
 type dbExecutor interface {
 	Query(query string, args ...any) (*sql.Rows, error)
@@ -25,14 +21,27 @@ type dbExecutor interface {
 
 // Syncer manages persistent sync with Things Cloud
 type Syncer struct {
-	rawDB   *sql.DB     // underlying connection for Close() and Begin()
-	db      dbExecutor  // current executor (db or tx)
+	rawDB   *sql.DB    // underlying connection for Close() and Begin()
+	db      dbExecutor // current executor (db or tx)
 	client  *things.Client
 	history *things.History
+	loc     *time.Location
+}
+
+// Option configures a Syncer created with Open
+type Option func(*Syncer)
+
+// WithLocation sets the user's time zone. It decides which calendar day is today, e.g.
+// for TasksInToday and for telling TaskMovedToToday from TaskMovedToAnytime.
+// It defaults to time.Local.
+func WithLocation(loc *time.Location) Option {
+	return func(s *Syncer) {
+		s.loc = loc
+	}
 }
 
 // Open creates or opens a sync database and connects to Things Cloud
-func Open(dbPath string, client *things.Client) (*Syncer, error) {
+func Open(dbPath string, client *things.Client, opts ...Option) (*Syncer, error) {
 	db, err := sql.Open("sqlite", dbPath)
 	if err != nil {
 		return nil, err
@@ -48,6 +57,10 @@ func Open(dbPath string, client *things.Client) (*Syncer, error) {
 		rawDB:  db,
 		db:     db,
 		client: client,
+		loc:    time.Local,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
 
 	if err := s.migrate(); err != nil {
@@ -58,26 +71,29 @@ func Open(dbPath string, client *things.Client) (*Syncer, error) {
 	return s, nil
 }
 
+// Location returns the user's time zone, see WithLocation
+func (s *Syncer) Location() *time.Location {
+	if s.loc == nil {
+		return time.Local
+	}
+	return s.loc
+}
+
 // Close closes the database connection
 func (s *Syncer) Close() error {
 	return s.rawDB.Close()
 }
 
-// isRetryableError returns true if the error is a temporary server error worth retrying.
-func isRetryableError(err error) bool {
-	if err == nil {
-		return false
-	}
-	errStr := err.Error()
-	return strings.Contains(errStr, "500") ||
-		strings.Contains(errStr, "502") ||
-		strings.Contains(errStr, "503") ||
-		strings.Contains(errStr, "504")
-}
-
 // Sync fetches new items from Things Cloud, updates local state,
 // and returns the list of changes in order
 func (s *Syncer) Sync() ([]Change, error) {
+	return s.SyncContext(context.Background())
+}
+
+// SyncContext is like Sync but carries a context. Canceling the context aborts
+// in-flight requests and the client's retry waits; pages already processed stay committed,
+// but the sync cursor is only advanced once the whole run completes.
+func (s *Syncer) SyncContext(ctx context.Context) ([]Change, error) {
 	// Get current sync state first
 	storedHistoryID, startIndex, err := s.getSyncState()
 	if err != nil {
@@ -91,19 +107,9 @@ func (s *Syncer) Sync() ([]Change, error) {
 			// Use stored history ID directly - no network call needed
 			s.history = s.client.HistoryWithID(storedHistoryID)
 		} else {
-			// First sync - need to fetch history ID from server
-			var h *things.History
-			var err error
-			for attempt := 0; attempt < maxRetries; attempt++ {
-				h, err = s.client.OwnHistory()
-				if err == nil {
-					break
-				}
-				if !isRetryableError(err) {
-					return nil, err
-				}
-				time.Sleep(retryBaseWait * time.Duration(1<<attempt))
-			}
+			// First sync - need to fetch history ID from server.
+			// Transient failures are retried by the client's retry policy.
+			h, err := s.client.OwnHistoryContext(ctx)
 			if err != nil {
 				return nil, err
 			}
@@ -118,14 +124,15 @@ func (s *Syncer) Sync() ([]Change, error) {
 
 	// Pre-check: Get latest server index to avoid out-of-bounds requests
 	// A 500 error occurs when start-index > server's current-item-index
-	serverIndex, err := s.getServerIndex()
+	serverIndex, err := s.getServerIndex(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// If our cursor is already at or beyond the server's index, nothing to fetch
+	// If our cursor is already at or beyond the server's index, nothing to fetch.
+	// The state is still saved, so a first sync of an empty history records it.
 	if startIndex >= serverIndex {
-		return nil, nil
+		return nil, s.saveSyncState(s.history.ID, startIndex)
 	}
 
 	// Fetch items from server
@@ -133,38 +140,30 @@ func (s *Syncer) Sync() ([]Change, error) {
 	hasMore := true
 
 	for hasMore {
-		// Fetch with retry for transient errors
-		var items []things.Item
-		var more bool
-		var fetchErr error
-		for attempt := 0; attempt < maxRetries; attempt++ {
-			items, more, fetchErr = s.history.Items(things.ItemsOptions{StartIndex: startIndex})
-			if fetchErr == nil {
-				break
-			}
-			if !isRetryableError(fetchErr) {
-				return nil, fetchErr
-			}
-			time.Sleep(retryBaseWait * time.Duration(1<<attempt))
+		// Transient errors are retried by the client's retry policy
+		commits, more, err := s.history.CommitsContext(ctx, things.ItemsOptions{StartIndex: startIndex})
+		if err != nil {
+			return nil, err
 		}
-		if fetchErr != nil {
-			return nil, fetchErr
+
+		// Don't start processing a page after the caller gave up
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
 
-		// No items returned means we're caught up
-		if len(items) == 0 {
+		// No commits returned means we're caught up
+		if len(commits) == 0 {
 			break
 		}
 
-		// Process each item
-		changes, err := s.processItems(items, startIndex)
+		// Process each commit, keeping its server index
+		changes, err := s.processCommits(commits)
 		if err != nil {
 			return nil, err
 		}
 		allChanges = append(allChanges, changes...)
 
-		// Use server's current-item-index as next start position
-		// (not len(items) - items get expanded from nested structure)
+		// Continue after the last commit we received
 		startIndex = s.history.LoadedServerIndex
 		hasMore = more
 	}
@@ -177,6 +176,37 @@ func (s *Syncer) Sync() ([]Change, error) {
 	return allChanges, nil
 }
 
+// ErrNotFastForward is returned by FastForward when the commit does not directly
+// follow the sync cursor, e.g. because another device committed in between
+var ErrNotFastForward = errors.New("commit does not follow the sync cursor")
+
+// FastForward applies a commit written by this client to the local state without
+// fetching it again, and advances the sync cursor past it. It returns ErrNotFastForward
+// if the cursor is not at the commit's ancestor index; the next Sync picks the commit
+// up in that case.
+func (s *Syncer) FastForward(res *things.CommitResult) ([]Change, error) {
+	historyID, index, err := s.getSyncState()
+	if err != nil {
+		return nil, err
+	}
+	if historyID == "" || historyID != res.HistoryID || index != res.AncestorIndex {
+		return nil, ErrNotFastForward
+	}
+
+	changes, err := s.processCommits([]things.Commit{res.Commit()})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveSyncState(historyID, res.ServerHeadIndex); err != nil {
+		return nil, err
+	}
+	if s.history != nil && s.history.ID == historyID {
+		s.history.LatestServerIndex = res.ServerHeadIndex
+		s.history.LoadedServerIndex = res.ServerHeadIndex
+	}
+	return changes, nil
+}
+
 // LastSyncedIndex returns the server index we've synced up to
 func (s *Syncer) LastSyncedIndex() int {
 	_, idx, _ := s.getSyncState()
@@ -266,8 +296,8 @@ func (s *Syncer) scanChangeLog(rows *sql.Rows) ([]Change, error) {
 // getServerIndex fetches the latest server index from Things Cloud.
 // This is used to pre-check before fetching items to avoid 500 errors
 // when our stored cursor is ahead of the server's current-item-index.
-func (s *Syncer) getServerIndex() (int, error) {
-	h, err := s.client.History(s.history.ID)
+func (s *Syncer) getServerIndex(ctx context.Context) (int, error) {
+	h, err := s.client.HistoryContext(ctx, s.history.ID)
 	if err != nil {
 		return 0, err
 	}
@@ -1,6 +1,9 @@
 package sync
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -8,8 +11,10 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	things "github.com/arthursoares/things-cloud-sdk"
+	"github.com/arthursoares/things-cloud-sdk/thingscloudtest"
 )
 
 // TestSync_PaginatesAllPages verifies that Sync() steps through every page of
@@ -147,3 +152,367 @@ func TestOpen(t *testing.T) {
 		}
 	})
 }
+
+func TestSyncContext_Canceled(t *testing.T) {
+	t.Parallel()
+
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// Always fail with a retryable status so Sync enters its backoff wait
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	client := things.NewWithOptions(ts.URL, "test@example.com", "password",
+		things.WithRetryPolicy(things.RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Second}))
+	syncer, err := Open(dbPath, client)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer syncer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = syncer.SyncContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 10*time.Second {
+		t.Errorf("retry wait was not interrupted, Sync took %s", elapsed)
+	}
+	if requests != 1 {
+		t.Errorf("expected a single request before cancellation, got %d", requests)
+	}
+}
+
+func TestSync_FakeServer(t *testing.T) {
+	t.Parallel()
+
+	server := thingscloudtest.NewServer("test@example.com", "password")
+	defer server.Close()
+	server.SetPageSize(2)
+
+	key := server.OwnHistoryKey()
+	for _, title := range []string{"One", "Two", "Three"} {
+		p, _ := json.Marshal(things.TaskActionItemPayload{Title: things.String(title), Type: things.TaskTypePtr(things.TaskTypeTask)})
+		if _, err := server.Seed(key, things.Item{UUID: "task-" + title, Kind: things.ItemKindTask, Action: things.ItemActionCreated, P: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := server.Client(things.WithoutValidation())
+	syncer, err := Open(filepath.Join(t.TempDir(), "test.db"), client)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer syncer.Close()
+
+	changes, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %d", len(changes))
+	}
+	if idx := syncer.LastSyncedIndex(); idx != 3 {
+		t.Errorf("expected LastSyncedIndex 3, got %d", idx)
+	}
+
+	// Complete a task from "another device" and sync again
+	h, err := client.OwnHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	status := things.TaskStatusCompleted
+	if _, err := h.Write(things.TaskActionItem{
+		Item: things.Item{UUID: "task-Two", Kind: things.ItemKindTask, Action: things.ItemActionModified},
+		P:    things.TaskActionItemPayload{Status: &status},
+	}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	changes, err = syncer.Sync()
+	if err != nil {
+		t.Fatalf("second Sync() failed: %v", err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d", len(changes))
+	}
+	if _, ok := changes[0].(TaskCompleted); !ok {
+		t.Errorf("expected TaskCompleted, got %T", changes[0])
+	}
+}
+
+func TestSync_LogsCommitServerIndex(t *testing.T) {
+	t.Parallel()
+
+	server := thingscloudtest.NewServer("test@example.com", "password")
+	defer server.Close()
+
+	task := func(title string) things.Item {
+		p, _ := json.Marshal(things.TaskActionItemPayload{Title: things.String(title), Type: things.TaskTypePtr(things.TaskTypeTask)})
+		return things.Item{UUID: "task-" + title, Kind: things.ItemKindTask, Action: things.ItemActionCreated, P: p}
+	}
+	key := server.OwnHistoryKey()
+	if _, err := server.Seed(key, task("One"), task("Two"), task("Three")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Seed(key, task("Four")); err != nil {
+		t.Fatal(err)
+	}
+
+	syncer, err := Open(filepath.Join(t.TempDir(), "test.db"), server.Client())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer syncer.Close()
+
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+
+	want := map[string]int{"task-One": 0, "task-Two": 0, "task-Three": 0, "task-Four": 1}
+	for uuid, idx := range want {
+		var got int
+		if err := syncer.db.QueryRow("SELECT server_index FROM change_log WHERE entity_uuid = ?", uuid).Scan(&got); err != nil {
+			t.Fatalf("reading change_log for %s: %v", uuid, err)
+		}
+		if got != idx {
+			t.Errorf("expected server_index %d for %s, got %d", idx, uuid, got)
+		}
+	}
+}
+
+func TestSyncer_FastForward(t *testing.T) {
+	t.Parallel()
+
+	server := thingscloudtest.NewServer("test@example.com", "password")
+	defer server.Close()
+
+	client := server.Client(things.WithoutValidation())
+	syncer, err := Open(filepath.Join(t.TempDir(), "test.db"), client)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer syncer.Close()
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+
+	h, err := client.OwnHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := func(uuid, title string) things.TaskActionItem {
+		return things.TaskActionItem{
+			Item: things.Item{UUID: uuid, Kind: things.ItemKindTask, Action: things.ItemActionCreated},
+			P:    things.TaskActionItemPayload{Title: things.String(title), Type: things.TaskTypePtr(things.TaskTypeTask)},
+		}
+	}
+	res, err := h.Write(task("mine", "Mine"))
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	before := len(server.Requests())
+	changes, err := syncer.FastForward(res)
+	if err != nil {
+		t.Fatalf("FastForward failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0].EntityUUID() != "mine" {
+		t.Errorf("expected the written task to be applied, got %v", changes)
+	}
+	if got := len(server.Requests()); got != before {
+		t.Errorf("expected no requests, got %d", got-before)
+	}
+	if idx := syncer.LastSyncedIndex(); idx != res.ServerHeadIndex {
+		t.Errorf("expected cursor at %d, got %d", res.ServerHeadIndex, idx)
+	}
+
+	// Another device commits before our next write, so our commit can't be fast-forwarded
+	p, _ := json.Marshal(things.TaskActionItemPayload{Title: things.String("Theirs"), Type: things.TaskTypePtr(things.TaskTypeTask)})
+	if _, err := server.Seed(server.OwnHistoryKey(), things.Item{UUID: "theirs", Kind: things.ItemKindTask, Action: things.ItemActionCreated, P: p}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	res, err = h.Write(task("mine-2", "Mine 2"))
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := syncer.FastForward(res); !errors.Is(err, ErrNotFastForward) {
+		t.Errorf("expected ErrNotFastForward, got %v", err)
+	}
+	changes, err = syncer.Sync()
+	if err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Errorf("expected both commits to be synced, got %d changes", len(changes))
+	}
+}
+
+func TestSync_DivergedNote(t *testing.T) {
+	t.Parallel()
+
+	server := thingscloudtest.NewServer("test@example.com", "password")
+	defer server.Close()
+	seed := func(action things.ItemAction, note things.Note) {
+		p, _ := json.Marshal(map[string]any{"tt": "Shopping", "tp": 0, "nt": note})
+		if _, err := server.Seed(server.OwnHistoryKey(), things.Item{UUID: "task", Kind: things.ItemKindTask, Action: action, P: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seed(things.ItemActionCreated, things.Note{TypeTag: "tx", Type: things.NoteTypeFullText, Value: "buy milk", Checksum: things.NoteChecksum("buy milk")})
+
+	syncer, err := Open(filepath.Join(t.TempDir(), "test.db"), server.Client())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer syncer.Close()
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+	note := func() string {
+		task, err := syncer.getTask("task")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return task.Note
+	}
+
+	// the local note no longer matches the one the next delta was made for
+	if _, err := syncer.db.Exec(`UPDATE tasks SET note = 'buy bread' WHERE uuid = 'task'`); err != nil {
+		t.Fatal(err)
+	}
+	seed(things.ItemActionModified, things.NewDeltaNote("buy milk", "buy oat milk"))
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+	if got := note(); got != "buy bread" {
+		t.Errorf("expected the diverged note to be kept, got %q", got)
+	}
+	if stale, _ := syncer.StaleNotes(); len(stale) != 1 || stale[0] != "task" {
+		t.Fatalf("expected the task to be marked for refetch, got %v", stale)
+	}
+
+	changes, err := syncer.RefetchNotes()
+	if err != nil {
+		t.Fatalf("RefetchNotes() failed: %v", err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %v", changes)
+	}
+	if nc, ok := changes[0].(TaskNoteChanged); !ok || nc.OldNote != "buy bread" {
+		t.Errorf("expected TaskNoteChanged from the diverged note, got %#v", changes[0])
+	}
+	if got := note(); got != "buy oat milk" {
+		t.Errorf("expected the replayed note, got %q", got)
+	}
+	if stale, _ := syncer.StaleNotes(); len(stale) != 0 {
+		t.Errorf("expected no stale notes after refetch, got %v", stale)
+	}
+}
+
+func TestSync_DivergedNoteLaterPatch(t *testing.T) {
+	t.Parallel()
+
+	server := thingscloudtest.NewServer("test@example.com", "password")
+	defer server.Close()
+	seed := func(action things.ItemAction, note things.Note) {
+		p, _ := json.Marshal(map[string]any{"tt": "Shopping", "tp": 0, "nt": note})
+		if _, err := server.Seed(server.OwnHistoryKey(), things.Item{UUID: "task", Kind: things.ItemKindTask, Action: action, P: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seed(things.ItemActionCreated, things.Note{TypeTag: "tx", Type: things.NoteTypeFullText, Value: "buy milk"})
+	// made for a different note, so it diverges on the server's history too
+	seed(things.ItemActionModified, things.NewDeltaNote("buy bread", "buy oat bread"))
+	// without a checksum, nothing tells this patch doesn't fit either
+	seed(things.ItemActionModified, things.Note{TypeTag: "tx", Type: things.NoteTypeDelta, Patches: []things.NotePatch{{Replacement: "- "}}})
+
+	syncer, err := Open(filepath.Join(t.TempDir(), "test.db"), server.Client())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer syncer.Close()
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+	note := func() string {
+		task, err := syncer.getTask("task")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return task.Note
+	}
+	if got := note(); got != "buy milk" {
+		t.Errorf("expected the last good note to be kept, got %q", got)
+	}
+	if stale, _ := syncer.StaleNotes(); len(stale) != 1 {
+		t.Fatalf("expected the note to stay stale, got %v", stale)
+	}
+
+	changes, err := syncer.RefetchNotes()
+	if err != nil {
+		t.Fatalf("RefetchNotes() failed: %v", err)
+	}
+	if len(changes) != 0 || note() != "buy milk" {
+		t.Errorf("expected the note to be kept, got %q and %v", note(), changes)
+	}
+	if stale, _ := syncer.StaleNotes(); len(stale) != 1 {
+		t.Errorf("expected the note to stay stale after refetch, got %v", stale)
+	}
+
+	// a full text note replaces whatever diverged
+	seed(things.ItemActionModified, things.Note{TypeTag: "tx", Type: things.NoteTypeFullText, Value: "buy eggs", Checksum: things.NoteChecksum("buy eggs")})
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+	if got := note(); got != "buy eggs" {
+		t.Errorf("expected the full text note, got %q", got)
+	}
+	if stale, _ := syncer.StaleNotes(); len(stale) != 0 {
+		t.Errorf("expected no stale notes, got %v", stale)
+	}
+}
+
+func TestParseNotePayload_Checksum(t *testing.T) {
+	patch := []things.NotePatch{{Replacement: "oat ", Position: 4}}
+	testCases := []struct {
+		Title            string
+		Note             things.Note
+		ExpectedNote     string
+		ExpectedDiverged bool
+	}{
+		{"Full text", things.Note{Type: things.NoteTypeFullText, Value: "buy eggs", Checksum: things.NoteChecksum("buy eggs")}, "buy eggs", false},
+		{"Full text without checksum", things.Note{Type: things.NoteTypeFullText, Value: "buy eggs"}, "buy eggs", false},
+		{"Full text w/ wrong checksum", things.Note{Type: things.NoteTypeFullText, Value: "buy eggs", Checksum: things.NoteChecksum("buy milk")}, "buy milk", true},
+		{"Delta", things.Note{Type: things.NoteTypeDelta, Patches: patch, Checksum: things.NoteChecksum("buy oat milk")}, "buy oat milk", false},
+		{"Delta w/ wrong checksum", things.Note{Type: things.NoteTypeDelta, Patches: patch, Checksum: things.NoteChecksum("buy milk")}, "buy milk", true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Title, func(t *testing.T) {
+			raw, _ := json.Marshal(testCase.Note)
+			note, err := parseNotePayload("buy milk", raw)
+			if note != testCase.ExpectedNote {
+				t.Errorf("Expected note %q, got %q", testCase.ExpectedNote, note)
+			}
+			var derr *things.NoteDivergedError
+			if diverged := errors.As(err, &derr); diverged != testCase.ExpectedDiverged {
+				t.Fatalf("Expected diverged %v, got %v", testCase.ExpectedDiverged, err)
+			}
+			if derr != nil && derr.Patch != -1 {
+				t.Errorf("Expected the note's checksum to mismatch, got %v", derr)
+			}
+		})
+	}
+}
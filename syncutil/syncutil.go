@@ -71,8 +71,8 @@ type DailySummary struct {
 
 // BuildDailySummary calculates activity stats from today's changes.
 func BuildDailySummary(syncer *sync.Syncer) DailySummary {
-	today := time.Now().Truncate(24 * time.Hour)
-	changes, _ := syncer.ChangesSince(today)
+	loc := syncer.Location()
+	changes, _ := syncer.ChangesSince(things.Today(loc).Time(loc))
 
 	summary := DailySummary{}
 	for _, c := range changes {
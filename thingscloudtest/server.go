@@ -0,0 +1,443 @@
+// Package thingscloudtest provides an in-process, stateful fake of the Things Cloud
+// API for tests which should not reach the network.
+//
+// The fake implements the endpoints used by the SDK: account verification, the own
+// history key list, history metadata, item paging, commits and app instance
+// registration. Every history keeps an append-only log of commits, which is paged
+// through /items like the real server does.
+package thingscloudtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	thingscloud "github.com/arthursoares/things-cloud-sdk"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultPageSize is the number of commits returned per /items request
+	DefaultPageSize = 1000
+	// SchemaVersion is the schema reported for all histories
+	SchemaVersion = 301
+)
+
+// errorBody mirrors the body the real server sends alongside error status codes
+const errorBody = `{"IsSyncronyErrorResponse":true}`
+
+// Commit is a single entry in a history's log. It maps item UUIDs to their raw
+// {"t","e","p"} envelopes, exactly as they were committed.
+type Commit map[string]json.RawMessage
+
+// history is the server side state of a single history. Commits are kept as the
+// bodies they were committed with and served back verbatim, so /items returns the
+// items of a commit in the order they were sent.
+type history struct {
+	commits []json.RawMessage
+}
+
+func (h *history) totalSize(n int) int {
+	size := 0
+	for _, c := range h.commits[:n] {
+		size += len(c)
+	}
+	return size
+}
+
+// Server is a running fake Things Cloud server
+type Server struct {
+	// URL is the base url of the server, suitable as endpoint for thingscloud.New
+	URL string
+	// Email and Password are the only accepted credentials. Password is updated
+	// when the client changes the account password.
+	Email    string
+	Password string
+
+	srv *httptest.Server
+
+	mu           sync.Mutex
+	pageSize     int
+	ownKey       string
+	histories    map[string]*history
+	historyOrder []string
+	appInstances map[string]thingscloud.AppInstanceRequest
+	failures     []int
+	requests     []string
+}
+
+// NewServer starts a fake server accepting the given credentials. The account
+// starts out with a single, empty history which is returned as history-key on
+// verification.
+func NewServer(email, password string) *Server {
+	s := &Server{
+		Email:        email,
+		Password:     password,
+		pageSize:     DefaultPageSize,
+		histories:    map[string]*history{},
+		appInstances: map[string]thingscloud.AppInstanceRequest{},
+	}
+	s.ownKey = s.CreateHistory()
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client returns a client talking to the server with valid credentials.
+// Retries are disabled unless opts configure them.
+func (s *Server) Client(opts ...thingscloud.Option) *thingscloud.Client {
+	opts = append([]thingscloud.Option{thingscloud.WithRetryPolicy(thingscloud.NoRetry())}, opts...)
+	return thingscloud.NewWithOptions(s.URL, s.Email, s.Password, opts...)
+}
+
+// SetPageSize changes the number of commits returned per /items request
+func (s *Server) SetPageSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pageSize = n
+}
+
+// OwnHistoryKey returns the key reported by account verification
+func (s *Server) OwnHistoryKey() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ownKey
+}
+
+// CreateHistory adds a new, empty history and returns its key
+func (s *Server) CreateHistory() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createHistory()
+}
+
+func (s *Server) createHistory() string {
+	key := uuid.New().String()
+	s.histories[key] = &history{}
+	s.historyOrder = append(s.historyOrder, key)
+	return key
+}
+
+// Seed appends a commit containing items to a history, as if another device wrote
+// them, and returns the new head index.
+func (s *Server) Seed(historyKey string, items ...thingscloud.Item) (int, error) {
+	// the body is built by hand to keep the items in order
+	var c bytes.Buffer
+	c.WriteByte('{')
+	for i, item := range items {
+		bs, err := json.Marshal(item)
+		if err != nil {
+			return 0, err
+		}
+		id, _ := json.Marshal(item.UUID)
+		if i > 0 {
+			c.WriteByte(',')
+		}
+		c.Write(id)
+		c.WriteByte(':')
+		c.Write(bs)
+	}
+	c.WriteByte('}')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.histories[historyKey]
+	if !ok {
+		return 0, fmt.Errorf("unknown history %q", historyKey)
+	}
+	return h.append(c.Bytes())
+}
+
+// append adds a commit body to the log, compacted the way it is served
+func (h *history) append(body []byte) (int, error) {
+	var c bytes.Buffer
+	if err := json.Compact(&c, body); err != nil {
+		return 0, err
+	}
+	h.commits = append(h.commits, c.Bytes())
+	return len(h.commits), nil
+}
+
+// Commits returns a copy of the log of a history
+func (s *Server) Commits(historyKey string) []Commit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.histories[historyKey]
+	if !ok {
+		return nil
+	}
+	commits := make([]Commit, len(h.commits))
+	for i, raw := range h.commits {
+		json.Unmarshal(raw, &commits[i]) //nolint:errcheck // validated when appended
+	}
+	return commits
+}
+
+// AppInstances returns the registered app instances, keyed by app instance id
+func (s *Server) AppInstances() map[string]thingscloud.AppInstanceRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := make(map[string]thingscloud.AppInstanceRequest, len(s.appInstances))
+	for k, v := range s.appInstances {
+		m[k] = v
+	}
+	return m
+}
+
+// FailNext makes the next requests fail with the given status codes, one per request
+func (s *Server) FailNext(statusCodes ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statusCodes...)
+}
+
+// Requests returns "METHOD /path?query" for every request received so far
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+	w.Header().Set("Content-Type", "application/json")
+
+	if len(s.failures) > 0 {
+		status := s.failures[0]
+		s.failures = s.failures[1:]
+		writeError(w, status)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 || parts[0] != "version" || parts[1] != "1" {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	parts = parts[2:]
+
+	switch {
+	case parts[0] == "account" && len(parts) == 2 && r.Method == http.MethodGet:
+		s.verify(w, r, parts[1])
+	case parts[0] == "account" && len(parts) == 2 && r.Method == http.MethodPut:
+		s.updateAccount(w, r, parts[1])
+	case parts[0] == "account" && len(parts) == 3 && parts[2] == "own-history-keys":
+		s.ownHistoryKeys(w, r, parts[1])
+	case parts[0] == "account" && len(parts) == 4 && parts[2] == "own-history-keys" && r.Method == http.MethodDelete:
+		s.deleteHistory(w, r, parts[1], parts[3])
+	case parts[0] == "history" && len(parts) == 2 && r.Method == http.MethodGet:
+		s.historyMeta(w, parts[1])
+	case parts[0] == "history" && len(parts) == 3 && parts[2] == "items" && r.Method == http.MethodGet:
+		s.items(w, r, parts[1])
+	case parts[0] == "history" && len(parts) == 3 && parts[2] == "commit" && r.Method == http.MethodPost:
+		s.commit(w, r, parts[1])
+	case parts[0] == "app-instance" && len(parts) == 2 && r.Method == http.MethodPut:
+		s.registerAppInstance(w, r, parts[1])
+	default:
+		writeError(w, http.StatusNotFound)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int) {
+	w.WriteHeader(status)
+	fmt.Fprint(w, errorBody)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	// commits are served as they were sent
+	enc.SetEscapeHTML(false)
+	enc.Encode(v) //nolint:errcheck
+}
+
+// authorized checks the account in the path and the Password authorization header
+func (s *Server) authorized(r *http.Request, email string) bool {
+	return email == s.Email && r.Header.Get("Authorization") == "Password "+s.Password
+}
+
+func (s *Server) verify(w http.ResponseWriter, r *http.Request, email string) {
+	if !s.authorized(r, email) {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, thingscloud.VerifyResponse{
+		SLAVersionAccepted: "https://cloud.culturedcode.com/sla/v1.5-rich.html?language=en",
+		Issues:             json.RawMessage(`[]`),
+		Email:              s.Email,
+		Status:             thingscloud.AccountStatusActive,
+		HistoryKey:         s.ownKey,
+	})
+}
+
+// updateAccount handles password changes, SLA acceptance and confirmation
+func (s *Server) updateAccount(w http.ResponseWriter, r *http.Request, email string) {
+	if !s.authorized(r, email) {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+	var body struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	if body.Password != "" {
+		s.Password = body.Password
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) ownHistoryKeys(w http.ResponseWriter, r *http.Request, email string) {
+	if !s.authorized(r, email) {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, append([]string{}, s.historyOrder...))
+	case http.MethodPost:
+		writeJSON(w, http.StatusOK, map[string]string{"new-history-key": s.createHistory()})
+	default:
+		writeError(w, http.StatusMethodNotAllowed)
+	}
+}
+
+// deleteHistory always answers 202, even for unknown keys, like the real server
+func (s *Server) deleteHistory(w http.ResponseWriter, r *http.Request, email, key string) {
+	if !s.authorized(r, email) {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+	if _, ok := s.histories[key]; ok {
+		delete(s.histories, key)
+		for i, k := range s.historyOrder {
+			if k == key {
+				s.historyOrder = append(s.historyOrder[:i], s.historyOrder[i+1:]...)
+				break
+			}
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) historyMeta(w http.ResponseWriter, key string) {
+	h, ok := s.histories[key]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"latest-server-index":       len(h.commits),
+		"latest-schema-version":     SchemaVersion,
+		"is-empty":                  len(h.commits) == 0,
+		"latest-total-content-size": h.totalSize(len(h.commits)),
+	})
+}
+
+// items pages through the log. A start-index beyond the head fails with a 500,
+// which is what the real server does.
+func (s *Server) items(w http.ResponseWriter, r *http.Request, key string) {
+	h, ok := s.histories[key]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	start, err := strconv.Atoi(r.URL.Query().Get("start-index"))
+	if err != nil || start < 0 {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	head := len(h.commits)
+	if start > head {
+		writeError(w, http.StatusInternalServerError)
+		return
+	}
+	end := start + s.pageSize
+	if end > head {
+		end = head
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items":                     append([]json.RawMessage{}, h.commits[start:end]...),
+		"current-item-index":        head,
+		"schema":                    SchemaVersion,
+		"start-total-content-size":  h.totalSize(start),
+		"end-total-content-size":    h.totalSize(end),
+		"latest-total-content-size": h.totalSize(head),
+	})
+}
+
+// commit appends the posted items to the log. A commit whose ancestor-index is not
+// the current head is rejected with 409 Conflict and the current server head.
+func (s *Server) commit(w http.ResponseWriter, r *http.Request, key string) {
+	h, ok := s.histories[key]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	ancestor, err := strconv.Atoi(r.URL.Query().Get("ancestor-index"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	if ancestor != len(h.commits) {
+		writeJSON(w, http.StatusConflict, map[string]any{
+			"IsSyncronyErrorResponse": true,
+			"server-head-index":       len(h.commits),
+		})
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	var c Commit
+	if err := json.Unmarshal(body, &c); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	for _, raw := range c {
+		var item thingscloud.Item
+		if err := json.Unmarshal(raw, &item); err != nil || item.Kind == "" {
+			writeError(w, http.StatusBadRequest)
+			return
+		}
+	}
+	head, err := h.append(body)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"server-head-index": head})
+}
+
+func (s *Server) registerAppInstance(w http.ResponseWriter, r *http.Request, id string) {
+	var req thingscloud.AppInstanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.histories[req.HistoryKey]; !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	req.AppInstanceID = id
+	s.appInstances[id] = req
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
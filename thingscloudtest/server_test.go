@@ -0,0 +1,214 @@
+package thingscloudtest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	thingscloud "github.com/arthursoares/things-cloud-sdk"
+)
+
+func taskItem(uuid, title string) thingscloud.Item {
+	p, _ := json.Marshal(map[string]any{"tt": title, "tp": 0})
+	return thingscloud.Item{UUID: uuid, Kind: thingscloud.ItemKindTask, Action: thingscloud.ItemActionCreated, P: p}
+}
+
+type rawItem struct {
+	thingscloud.Item
+}
+
+func (r rawItem) UUID() string {
+	return r.Item.UUID
+}
+
+func TestServer_Account(t *testing.T) {
+	t.Parallel()
+	s := NewServer("martin@example.com", "secret")
+	defer s.Close()
+
+	v, err := s.Client().Verify()
+	if err != nil {
+		t.Fatalf("Expected Verification to succeed, but didn't: %v", err)
+	}
+	if v.HistoryKey != s.OwnHistoryKey() {
+		t.Errorf("Expected history key %q, got %q", s.OwnHistoryKey(), v.HistoryKey)
+	}
+
+	bad := thingscloud.NewWithOptions(s.URL, "martin@example.com", "wrong", thingscloud.WithRetryPolicy(thingscloud.NoRetry()))
+	if _, err := bad.Verify(); !errors.Is(err, thingscloud.ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestServer_Histories(t *testing.T) {
+	t.Parallel()
+	s := NewServer("martin@example.com", "secret")
+	defer s.Close()
+	c := s.Client()
+
+	h, err := c.CreateHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs, err := c.Histories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hs) != 2 {
+		t.Fatalf("Expected 2 histories, got %d", len(hs))
+	}
+	if err := h.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if hs, _ := c.Histories(); len(hs) != 1 {
+		t.Errorf("Expected 1 history after deletion, got %d", len(hs))
+	}
+}
+
+func TestServer_WriteAndRead(t *testing.T) {
+	t.Parallel()
+	s := NewServer("martin@example.com", "secret")
+	defer s.Close()
+	s.SetPageSize(2)
+
+	key := s.OwnHistoryKey()
+	for _, title := range []string{"one", "two", "three"} {
+		if _, err := s.Seed(key, taskItem("task-"+title, title)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := s.Client(thingscloud.WithoutValidation())
+	h, err := c.History(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.LatestServerIndex != 3 {
+		t.Fatalf("Expected LatestServerIndex of 3, got %d", h.LatestServerIndex)
+	}
+
+	if _, err := h.Write(rawItem{taskItem("task-four", "four")}); err != nil {
+		t.Fatalf("Expected write to succeed, but didn't: %v", err)
+	}
+	if h.LatestServerIndex != 4 {
+		t.Errorf("Expected LatestServerIndex of 4 after write, got %d", h.LatestServerIndex)
+	}
+
+	reader := c.HistoryWithID(key)
+	var all []thingscloud.Item
+	start := 0
+	for {
+		items, hasMore, err := reader.Items(thingscloud.ItemsOptions{StartIndex: start})
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, items...)
+		if !hasMore {
+			break
+		}
+		start = reader.LoadedServerIndex
+	}
+	if len(all) != 4 {
+		t.Fatalf("Expected 4 items, got %d", len(all))
+	}
+	if all[3].UUID != "task-four" {
+		t.Errorf("Expected last item to be the written one, got %q", all[3].UUID)
+	}
+	if got := len(s.Requests()); got < 4 {
+		t.Errorf("Expected requests to be recorded, got %d", got)
+	}
+}
+
+func TestServer_CommitOrder(t *testing.T) {
+	t.Parallel()
+	s := NewServer("martin@example.com", "secret")
+	defer s.Close()
+	key := s.OwnHistoryKey()
+	if _, err := s.Seed(key, taskItem("c", "<c>"), taskItem("a", "a"), taskItem("b", "b")); err != nil {
+		t.Fatal(err)
+	}
+	h, err := s.Client(thingscloud.WithoutValidation()).History(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Write(rawItem{taskItem("z", "z")}, rawItem{taskItem("y", "y")}); err != nil {
+		t.Fatal(err)
+	}
+
+	commits, _, err := h.Commits(thingscloud.ItemsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	for _, c := range commits {
+		var uuids []string
+		for _, item := range c.Items {
+			uuids = append(uuids, item.UUID)
+		}
+		got = append(got, uuids)
+	}
+	expected := [][]string{{"c", "a", "b"}, {"z", "y"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected the items in commit order %v, got %v", expected, got)
+	}
+	if c := s.Commits(key); len(c) != 2 || c[0]["a"] == nil {
+		t.Errorf("Expected the log to hold both commits, got %v", c)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	t.Parallel()
+	s := NewServer("martin@example.com", "secret")
+	defer s.Close()
+	key := s.OwnHistoryKey()
+	s.Seed(key, taskItem("task-one", "one")) //nolint:errcheck
+	c := s.Client(thingscloud.WithoutValidation())
+
+	t.Run("StaleAncestor", func(t *testing.T) {
+		h := c.HistoryWithID(key)
+		_, err := h.Write(rawItem{taskItem("task-two", "two")})
+		if !thingscloud.IsConflict(err) {
+			t.Errorf("Expected conflict, got %v", err)
+		}
+	})
+
+	t.Run("StartIndexOutOfRange", func(t *testing.T) {
+		h := c.HistoryWithID(key)
+		_, _, err := h.Items(thingscloud.ItemsOptions{StartIndex: 5})
+		var apiErr *thingscloud.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+			t.Errorf("Expected 500, got %v", err)
+		}
+	})
+
+	t.Run("FailNext", func(t *testing.T) {
+		s.FailNext(http.StatusServiceUnavailable)
+		if _, err := c.History(key); !thingscloud.IsRetryable(err) {
+			t.Errorf("Expected injected 503, got %v", err)
+		}
+		if _, err := c.History(key); err != nil {
+			t.Errorf("Expected injected failure to be consumed, got %v", err)
+		}
+	})
+}
+
+func TestServer_AppInstance(t *testing.T) {
+	t.Parallel()
+	s := NewServer("martin@example.com", "secret")
+	defer s.Close()
+
+	err := s.Client().RegisterAppInstance(thingscloud.AppInstanceRequest{
+		AppInstanceID: "hash1-com.culturedcode.ThingsMac-hash2",
+		HistoryKey:    s.OwnHistoryKey(),
+		APNSToken:     "token123",
+		AppID:         "com.culturedcode.ThingsMac",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.AppInstances()["hash1-com.culturedcode.ThingsMac-hash2"]; got.APNSToken != "token123" {
+		t.Errorf("Expected app instance to be registered, got %#v", got)
+	}
+}
@@ -186,9 +186,9 @@ type Task struct {
 	Status           TaskStatus
 	Title            string
 	Note             string
-	ScheduledDate    *time.Time
+	ScheduledDate    *Date
 	CompletionDate   *time.Time
-	DeadlineDate     *time.Time
+	DeadlineDate     *Date
 	Index            int
 	AreaIDs          []string
 	ParentTaskIDs    []string
@@ -202,6 +202,9 @@ type Task struct {
 	TagIDs          []string
 	RecurrenceIDs   []string
 	DelegateIDs     []string
+	// Extra holds the latest raw value of every payload field Task has no field for,
+	// such as the xx extension data
+	Extra map[string]json.RawMessage
 }
 
 // TaskActionItemPayload describes the payload for modifying Tasks, and also Projects,
@@ -210,10 +213,10 @@ type TaskActionItemPayload struct {
 	Index             *int                   `json:"ix,omitempty"`
 	CreationDate      *Timestamp             `json:"cd,omitempty"`
 	ModificationDate  *Timestamp             `json:"md,omitempty"` // ok
-	ScheduledDate     *Timestamp             `json:"sr,omitempty"`
+	ScheduledDate     *Date                  `json:"sr,omitempty"`
 	CompletionDate    *Timestamp             `json:"sp,omitempty"`
-	DeadlineDate      *Timestamp             `json:"dd,omitempty"`  //
-	TaskIR            *Timestamp             `json:"tir,omitempty"` // hm, not sure what tir stands for
+	DeadlineDate      *Date                  `json:"dd,omitempty"`
+	TaskIR            *Date                  `json:"tir,omitempty"` // today index reference date
 	Status            *TaskStatus            `json:"ss,omitempty"`
 	Type              *TaskType              `json:"tp,omitempty"`
 	Title             *string                `json:"tt,omitempty"`
@@ -240,6 +243,8 @@ type TaskActionItemPayload struct {
 	ActionRequiredDate        *Timestamp             `json:"acrd,omitempty"`
 	DeadlineSuppression       *Timestamp             `json:"dds,omitempty"`
 	ExtensionData             json.RawMessage        `json:"xx,omitempty"`
+	// Raw is the JSON the payload was decoded from, nil for payloads built in code
+	Raw json.RawMessage `json:"-"`
 	//  {
 	//      "acrd": null,
 	//      "ar": [],
@@ -305,6 +310,8 @@ type TagActionItemPayload struct {
 	ShortHand     *string        `json:"sh"`
 	ParentTagIDs  *[]string      `json:"pn"`
 	ExtensionData json.RawMessage `json:"xx,omitempty"`
+	// Raw is the JSON the payload was decoded from, nil for payloads built in code
+	Raw json.RawMessage `json:"-"`
 }
 
 // TagActionItem describes an event on a tag
@@ -339,9 +346,12 @@ type Area struct {
 
 // AreaActionItemPayload describes the payload for modifying Areas
 type AreaActionItemPayload struct {
-	IX     *int     `json:"ix,omitempty"`
-	Title  *string  `json:"tt,omitempty"`
-	TagIDs []string `json:"tg,omitempty"`
+	IX            *int            `json:"ix,omitempty"`
+	Title         *string         `json:"tt,omitempty"`
+	TagIDs        []string        `json:"tg,omitempty"`
+	ExtensionData json.RawMessage `json:"xx,omitempty"`
+	// Raw is the JSON the payload was decoded from, nil for payloads built in code
+	Raw json.RawMessage `json:"-"`
 }
 
 // AreaActionItem describes an event on an Area
@@ -386,6 +396,8 @@ type CheckListActionItemPayload struct {
 	TaskIDs          *[]string       `json:"ts,omitempty"`
 	Leavable         *bool           `json:"lt,omitempty"`
 	ExtensionData    json.RawMessage `json:"xx,omitempty"`
+	// Raw is the JSON the payload was decoded from, nil for payloads built in code
+	Raw json.RawMessage `json:"-"`
 }
 
 // CheckListActionItem describes an event on a check list item
@@ -403,6 +415,8 @@ func (item CheckListActionItem) UUID() string {
 type TombstoneActionItemPayload struct {
 	DeletedObjectID string  `json:"dloid"`
 	DeletionDate    float64 `json:"dld"`
+	// Raw is the JSON the payload was decoded from, nil for payloads built in code
+	Raw json.RawMessage `json:"-"`
 }
 
 // TombstoneActionItem describes a tombstone deletion event
@@ -0,0 +1,339 @@
+package thingscloud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidItem is matched by errors.Is when items were rejected by validation
+var ErrInvalidItem = errors.New("invalid item")
+
+// validationNow is the clock used to decide which scheduled dates are in the future
+var validationNow = time.Now
+
+// Rule identifies the invariant a Violation breaks
+type Rule string
+
+const (
+	// RuleMalformedPayload is broken by payloads which do not decode
+	RuleMalformedPayload Rule = "malformed-payload"
+	// RuleRequiredField is broken by creations missing a field Things.app requires
+	RuleRequiredField Rule = "required-field"
+	// RuleScheduleDates is broken by st values which do not match the sr/tir dates
+	RuleScheduleDates Rule = "schedule-dates"
+	// RuleHeadingNeedsProject is broken by headings outside of a project
+	RuleHeadingNeedsProject Rule = "heading-needs-project"
+	// RuleChecklistNeedsTask is broken by checklist items without a task
+	RuleChecklistNeedsTask Rule = "checklist-needs-task"
+	// RuleDanglingReference is broken by references to entities which do not exist
+	RuleDanglingReference Rule = "dangling-reference"
+	// RuleNoteChecksum is broken by full text notes with a wrong checksum
+	RuleNoteChecksum Rule = "note-checksum"
+)
+
+// Violation is a single broken invariant of an item
+type Violation struct {
+	UUID string
+	Kind ItemKind
+	Rule Rule
+	// Field is the wire name of the offending payload field, e.g. "st"
+	Field   string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s (%s) %s: %s [%s]", v.UUID, v.Kind, v.Field, v.Message, v.Rule)
+}
+
+// ValidationError is returned by Validate, ValidateBatch and History.Write when items
+// break invariants Things.app relies on. Committing such items can crash the app on
+// every device syncing the history, see docs/client-side-bugs.md.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return fmt.Sprintf("%d invalid item fields: %s", len(e.Violations), strings.Join(msgs, "; "))
+}
+
+// Is makes errors.Is(err, ErrInvalidItem) match
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidItem
+}
+
+// References resolves the entities items may refer to, e.g. a memory.State. It is used
+// to detect references to tasks, areas and tags which do not exist.
+type References interface {
+	HasTask(uuid string) bool
+	HasArea(uuid string) bool
+	HasTag(uuid string) bool
+}
+
+// WithoutValidation stops History.Write from validating items before committing them
+func WithoutValidation() Option {
+	return func(c *Client) {
+		c.skipValidation = true
+	}
+}
+
+// WithReferences makes History.Write check references of written items against refs.
+// Items referring to entities created in the same commit, or the same WriteBatched
+// call, are always accepted.
+func WithReferences(refs References) Option {
+	return func(c *Client) {
+		c.refs = refs
+	}
+}
+
+// Validate checks a single item, see ValidateBatch
+func Validate(item Identifiable, refs References) error {
+	return ValidateBatch([]Identifiable{item}, refs)
+}
+
+// ValidateBatch checks items the way History.Write does before committing them: the
+// fields Things.app requires on creation, schedule and date consistency, headings
+// belonging to a project, checklist items belonging to a task and note checksums.
+// If refs is not nil, references to tasks, areas and tags which neither exist in refs
+// nor are written in the same batch are reported too. The result is nil or a
+// *ValidationError.
+func ValidateBatch(items []Identifiable, refs References) error {
+	decoded := make([]Item, 0, len(items))
+	for _, item := range items {
+		bs, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		var it Item
+		if err := json.Unmarshal(bs, &it); err != nil {
+			return err
+		}
+		it.UUID = item.UUID()
+		decoded = append(decoded, it)
+	}
+	return validateItems(decoded, refs)
+}
+
+// validateItems checks items in wire format
+func validateItems(items []Item, refs References) error {
+	v := validator{refs: refs, batch: map[string]ItemKind{}}
+	for _, item := range items {
+		v.batch[item.UUID] = item.Kind
+	}
+	for _, item := range items {
+		v.check(item)
+	}
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: v.violations}
+}
+
+// requiredFields lists the fields Things.app requires on creation, per kind family
+var requiredFields = map[string][]string{
+	"task":      {"tt", "tp", "st", "ss", "ix", "cd", "nt", "xx"},
+	"checklist": {"tt", "ts", "ss", "ix"},
+	"tag":       {"tt", "ix"},
+	"area":      {"tt", "ix"},
+	"tombstone": {"dloid"},
+}
+
+type validator struct {
+	refs       References
+	batch      map[string]ItemKind
+	item       Item
+	violations []Violation
+}
+
+func (v *validator) report(rule Rule, field, format string, args ...any) {
+	v.violations = append(v.violations, Violation{
+		UUID:    v.item.UUID,
+		Kind:    v.item.Kind,
+		Rule:    rule,
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) check(item Item) {
+	v.item = item
+	family := kindFamily(item.Kind)
+	if family == "" {
+		return
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(item.P, &fields); err != nil {
+		v.report(RuleMalformedPayload, "p", "payload is not an object: %v", err)
+		return
+	}
+	if item.Action == ItemActionCreated {
+		for _, f := range requiredFields[family] {
+			if isNull(fields[f]) {
+				v.report(RuleRequiredField, f, "required on creation")
+			}
+		}
+	}
+	switch family {
+	case "task":
+		v.checkTask(fields)
+	case "checklist":
+		ts, ok := v.ids(fields, "ts")
+		if (ok || item.Action == ItemActionCreated) && len(ts) == 0 {
+			v.report(RuleChecklistNeedsTask, "ts", "checklist items must belong to a task")
+		}
+		v.checkRefs("ts", ts, "task")
+	case "tag":
+		pn, _ := v.ids(fields, "pn")
+		v.checkRefs("pn", pn, "tag")
+	case "area":
+		tg, _ := v.ids(fields, "tg")
+		v.checkRefs("tg", tg, "tag")
+	}
+}
+
+func (v *validator) checkTask(fields map[string]json.RawMessage) {
+	var typ *TaskType
+	var st *TaskSchedule
+	if !v.decode(fields, "tp", &typ) || !v.decode(fields, "st", &st) {
+		return
+	}
+
+	sr := v.date(fields, "sr")
+	tir := v.date(fields, "tir")
+	if st != nil {
+		today := float64(civilDay(validationNow()))
+		switch {
+		case *st == TaskScheduleInbox && (sr != nil || tir != nil):
+			v.report(RuleScheduleDates, "st", "inbox tasks (st=0) must not have sr/tir dates")
+		case *st == TaskScheduleAnytime && sr != nil && *sr > today:
+			v.report(RuleScheduleDates, "st", "tasks scheduled for a future date must be deferred (st=2)")
+		case *st == TaskScheduleSomeday && sr != nil && *sr <= today:
+			v.report(RuleScheduleDates, "st", "deferred tasks (st=2) must not be scheduled for today or earlier, use st=1")
+		}
+	}
+
+	pr, prOK := v.ids(fields, "pr")
+	if typ != nil && *typ == TaskTypeHeading {
+		if (prOK || v.item.Action == ItemActionCreated) && len(pr) == 0 {
+			v.report(RuleHeadingNeedsProject, "pr", "headings must belong to a project")
+		}
+		if st != nil && *st != TaskScheduleAnytime {
+			v.report(RuleScheduleDates, "st", "headings must be scheduled for Anytime (st=1)")
+		}
+	}
+	v.checkRefs("pr", pr, "task")
+	agr, _ := v.ids(fields, "agr")
+	v.checkRefs("agr", agr, "task")
+	ar, _ := v.ids(fields, "ar")
+	v.checkRefs("ar", ar, "area")
+	tg, _ := v.ids(fields, "tg")
+	v.checkRefs("tg", tg, "tag")
+
+	v.checkNote(fields["nt"])
+}
+
+func (v *validator) checkNote(raw json.RawMessage) {
+	if isNull(raw) {
+		return
+	}
+	var note Note
+	if err := json.Unmarshal(raw, &note); err != nil {
+		v.report(RuleMalformedPayload, "nt", "note does not decode: %v", err)
+		return
+	}
+	if note.Type != NoteTypeFullText {
+		return
+	}
+	if note.TypeTag != "tx" {
+		v.report(RuleMalformedPayload, "nt", "note type tag must be \"tx\", got %q", note.TypeTag)
+	}
+	if want := NoteChecksum(note.Value); note.Checksum != want {
+		v.report(RuleNoteChecksum, "nt", "checksum is %d, but the text has checksum %d", note.Checksum, want)
+	}
+}
+
+// checkRefs reports ids which neither exist in the references nor in the batch
+func (v *validator) checkRefs(field string, ids []string, family string) {
+	if v.refs == nil {
+		return
+	}
+	for _, id := range ids {
+		if kind, ok := v.batch[id]; ok && kindFamily(kind) == family {
+			continue
+		}
+		var exists bool
+		switch family {
+		case "task":
+			exists = v.refs.HasTask(id)
+		case "area":
+			exists = v.refs.HasArea(id)
+		case "tag":
+			exists = v.refs.HasTag(id)
+		}
+		if !exists {
+			v.report(RuleDanglingReference, field, "%s %s does not exist", family, id)
+		}
+	}
+}
+
+// decode unmarshals an optional field, reporting malformed values
+func (v *validator) decode(fields map[string]json.RawMessage, field string, dst any) bool {
+	raw, ok := fields[field]
+	if !ok {
+		return true
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		v.report(RuleMalformedPayload, field, "does not decode: %v", err)
+		return false
+	}
+	return true
+}
+
+// date returns a date field as unix epoch, or nil if it is missing or null
+func (v *validator) date(fields map[string]json.RawMessage, field string) *float64 {
+	var d *float64
+	v.decode(fields, field, &d)
+	return d
+}
+
+// ids returns a list of UUIDs, and whether the field was present
+func (v *validator) ids(fields map[string]json.RawMessage, field string) ([]string, bool) {
+	var ids []string
+	if _, ok := fields[field]; !ok || !v.decode(fields, field, &ids) {
+		return nil, false
+	}
+	return ids, true
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+// civilDay returns the unix epoch of midnight UTC of the calendar day of t, which is
+// how Things stores dates
+func civilDay(t time.Time) int64 {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix()
+}
+
+// kindFamily groups the versions of an item kind
+func kindFamily(kind ItemKind) string {
+	switch kind {
+	case ItemKindTask, ItemKindTask4, ItemKindTask3, ItemKindTaskPlain:
+		return "task"
+	case ItemKindChecklistItem, ItemKindChecklistItem2, ItemKindChecklistItem3:
+		return "checklist"
+	case ItemKindTag, ItemKindTag4, ItemKindTagPlain:
+		return "tag"
+	case ItemKindArea, ItemKindArea3, ItemKindAreaPlain:
+		return "area"
+	case ItemKindTombstone:
+		return "tombstone"
+	}
+	return ""
+}
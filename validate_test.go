@@ -0,0 +1,142 @@
+package thingscloud_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	thingscloud "github.com/arthursoares/things-cloud-sdk"
+	memory "github.com/arthursoares/things-cloud-sdk/state/memory"
+	"github.com/arthursoares/things-cloud-sdk/thingscloudtest"
+	"github.com/arthursoares/things-cloud-sdk/write"
+)
+
+func rawTask(action thingscloud.ItemAction, p map[string]any) write.Envelope {
+	return write.Envelope{ID: "task", Action: action, Kind: thingscloud.ItemKindTask, Payload: p}
+}
+
+// validTask returns a complete task creation payload with the given overrides
+func validTask(overrides map[string]any) map[string]any {
+	p := map[string]any{
+		"tt": "Buy milk", "tp": 0, "st": 0, "ss": 0, "ix": 0, "cd": 1772620200,
+		"nt": write.EmptyNote(), "xx": write.DefaultExtension(),
+	}
+	for k, v := range overrides {
+		p[k] = v
+	}
+	return p
+}
+
+func TestValidateBatch(t *testing.T) {
+	t.Parallel()
+	today := time.Now()
+	day := func(offset int) int64 {
+		d := today.AddDate(0, 0, offset)
+		return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC).Unix()
+	}
+	state := memory.NewState()
+	state.Areas["area"] = &thingscloud.Area{UUID: "area"}
+
+	t.Run("Builders", func(t *testing.T) {
+		project := write.NewTask("Project").AsProject()
+		heading := write.NewTask("Heading").AsHeading().InProject(project.UUID())
+		task := write.NewTask("Task").UnderHeading(heading.UUID()).Note("call mum").InArea("area")
+		items := []thingscloud.Identifiable{
+			project, heading, task,
+			write.NewTask("Later").ScheduledOn(today.AddDate(0, 0, 3)),
+			write.NewTask("Now").Today(),
+			write.NewChecklistItem(task.UUID(), "Step"),
+			write.NewTag("Errand"),
+			write.NewArea("Home"),
+			write.UpdateTask(task.UUID()).Title("Renamed").Today(),
+			write.Tombstone(project.UUID()),
+		}
+		if err := thingscloud.ValidateBatch(items, state); err != nil {
+			t.Errorf("Expected builder output to be valid, but got %v", err)
+		}
+	})
+
+	for _, tc := range []struct {
+		name  string
+		item  write.Envelope
+		refs  thingscloud.References
+		rule  thingscloud.Rule
+		field string
+	}{
+		{"MissingExtension", rawTask(thingscloud.ItemActionCreated, validTask(map[string]any{"xx": nil})), nil, thingscloud.RuleRequiredField, "xx"},
+		{"MissingNote", rawTask(thingscloud.ItemActionCreated, map[string]any{"tt": "Buy milk", "tp": 0, "st": 0, "ss": 0, "ix": 0, "cd": 1, "xx": write.DefaultExtension()}), nil, thingscloud.RuleRequiredField, "nt"},
+		{"DeferredToday", rawTask(thingscloud.ItemActionModified, map[string]any{"st": 2, "sr": day(0), "tir": day(0)}), nil, thingscloud.RuleScheduleDates, "st"},
+		{"StartedFuture", rawTask(thingscloud.ItemActionModified, map[string]any{"st": 1, "sr": day(2), "tir": day(2)}), nil, thingscloud.RuleScheduleDates, "st"},
+		{"InboxWithDate", rawTask(thingscloud.ItemActionCreated, validTask(map[string]any{"sr": day(0)})), nil, thingscloud.RuleScheduleDates, "st"},
+		{"HeadingWithoutProject", rawTask(thingscloud.ItemActionCreated, validTask(map[string]any{"tp": 2, "st": 1})), nil, thingscloud.RuleHeadingNeedsProject, "pr"},
+		{"HeadingInInbox", rawTask(thingscloud.ItemActionModified, map[string]any{"tp": 2, "st": 0}), nil, thingscloud.RuleScheduleDates, "st"},
+		{"ChecklistWithoutTask", write.Envelope{ID: "item", Action: thingscloud.ItemActionModified, Kind: thingscloud.ItemKindChecklistItem3, Payload: map[string]any{"ts": []string{}}}, nil, thingscloud.RuleChecklistNeedsTask, "ts"},
+		{"DanglingArea", rawTask(thingscloud.ItemActionModified, map[string]any{"ar": []string{"gone"}}), state, thingscloud.RuleDanglingReference, "ar"},
+		{"DanglingTag", rawTask(thingscloud.ItemActionModified, map[string]any{"tg": []string{"gone"}}), state, thingscloud.RuleDanglingReference, "tg"},
+		{"NoteChecksum", rawTask(thingscloud.ItemActionModified, map[string]any{"nt": write.Note{TypeTag: "tx", Checksum: 1, Value: "call mum", Type: 1}}), nil, thingscloud.RuleNoteChecksum, "nt"},
+		{"MalformedSchedule", rawTask(thingscloud.ItemActionModified, map[string]any{"st": "today"}), nil, thingscloud.RuleMalformedPayload, "st"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := thingscloud.Validate(tc.item, tc.refs)
+			var verr *thingscloud.ValidationError
+			if !errors.As(err, &verr) || !errors.Is(err, thingscloud.ErrInvalidItem) {
+				t.Fatalf("Expected a ValidationError, but got %v", err)
+			}
+			if len(verr.Violations) != 1 {
+				t.Fatalf("Expected exactly one violation, but got %v", verr.Violations)
+			}
+			if v := verr.Violations[0]; v.Rule != tc.rule || v.Field != tc.field || v.UUID != tc.item.ID {
+				t.Errorf("Expected %s on %s, but got %+v", tc.rule, tc.field, v)
+			}
+		})
+	}
+
+	t.Run("ReferencesOptional", func(t *testing.T) {
+		item := rawTask(thingscloud.ItemActionModified, map[string]any{"ar": []string{"gone"}})
+		if err := thingscloud.Validate(item, nil); err != nil {
+			t.Errorf("Expected references not to be checked without refs, but got %v", err)
+		}
+	})
+}
+
+func TestHistory_WriteValidation(t *testing.T) {
+	t.Parallel()
+	s := thingscloudtest.NewServer("martin@example.com", "secret")
+	defer s.Close()
+	invalid := rawTask(thingscloud.ItemActionCreated, map[string]any{"tt": "Buy milk"})
+
+	t.Run("Rejects", func(t *testing.T) {
+		before := len(s.Requests())
+		_, err := s.Client().HistoryWithID(s.OwnHistoryKey()).Write(invalid)
+		if !errors.Is(err, thingscloud.ErrInvalidItem) {
+			t.Fatalf("Expected the write to be rejected, but got %v", err)
+		}
+		if got := len(s.Requests()); got != before {
+			t.Errorf("Expected no request to be sent, but got %d", got-before)
+		}
+	})
+
+	t.Run("OptOut", func(t *testing.T) {
+		c := s.Client(thingscloud.WithoutValidation())
+		if _, err := c.HistoryWithID(s.OwnHistoryKey()).Write(invalid); err != nil {
+			t.Errorf("Expected the write to succeed without validation, but got %v", err)
+		}
+	})
+
+	t.Run("References", func(t *testing.T) {
+		c := s.Client(thingscloud.WithReferences(memory.NewState()))
+		h, err := c.History(s.OwnHistoryKey())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = h.Write(write.NewTask("Task").InArea("gone"))
+		var verr *thingscloud.ValidationError
+		if !errors.As(err, &verr) || verr.Violations[0].Rule != thingscloud.RuleDanglingReference {
+			t.Fatalf("Expected a dangling reference, but got %v", err)
+		}
+		area := write.NewArea("Home")
+		if _, err := h.Write(area, write.NewTask("Task").InArea(area.UUID())); err != nil {
+			t.Errorf("Expected references within the commit to resolve, but got %v", err)
+		}
+	})
+}
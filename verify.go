@@ -1,6 +1,7 @@
 package thingscloud
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -27,21 +28,27 @@ type VerifyResponse struct {
 
 // Verify checks that the provided API credentials are valid.
 func (c *Client) Verify() (*VerifyResponse, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("/version/1/account/%s", c.EMail), nil)
-	req.Header.Set("Authorization", fmt.Sprintf("Password %s", c.password))
+	return c.VerifyContext(context.Background())
+}
+
+// VerifyContext is like Verify but carries a context for cancellation and deadlines
+func (c *Client) VerifyContext(ctx context.Context) (*VerifyResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("/version/1/account/%s", c.EMail), nil)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := c.authorization(ctx)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("Authorization", auth)
 	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusUnauthorized {
-			return nil, ErrUnauthorized
-		}
-		return nil, fmt.Errorf("http response code: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var v VerifyResponse
 	bs, err := io.ReadAll(resp.Body)
@@ -1,6 +1,8 @@
 package thingscloud
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -33,3 +35,20 @@ func TestClient_Verify(t *testing.T) {
 		}
 	})
 }
+
+func TestClient_VerifyContext(t *testing.T) {
+	t.Run("Canceled", func(t *testing.T) {
+		t.Parallel()
+		server := fakeServer(fakeResponse{200, "verify-success.json"})
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		c := New(fmt.Sprintf("http://%s", server.Listener.Addr().String()), "martin@example.com", "")
+		_, err := c.VerifyContext(ctx)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
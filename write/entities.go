@@ -0,0 +1,179 @@
+package write
+
+import (
+	things "github.com/arthursoares/things-cloud-sdk"
+)
+
+// ChecklistItemPayload is the payload of a checklist item creation, with all 9 fields
+type ChecklistItemPayload struct {
+	CreationDate     float64           `json:"cd"`
+	ModificationDate *float64          `json:"md"`
+	Title            string            `json:"tt"`
+	Status           things.TaskStatus `json:"ss"`
+	CompletionDate   *float64          `json:"sp"`
+	Index            int               `json:"ix"`
+	TaskIDs          []string          `json:"ts"`
+	Leavable         bool              `json:"lt"`
+	ExtensionData    Extension         `json:"xx"`
+}
+
+// ChecklistItemBuilder builds a checklist item creation. Create one with NewChecklistItem.
+type ChecklistItemBuilder struct {
+	id     string
+	taskID string
+	title  string
+	index  int
+}
+
+// NewChecklistItem starts building a checklist item of the given task
+func NewChecklistItem(taskID, title string) *ChecklistItemBuilder {
+	return &ChecklistItemBuilder{id: NewID(), taskID: taskID, title: title}
+}
+
+// WithID replaces the generated UUID
+func (b *ChecklistItemBuilder) WithID(id string) *ChecklistItemBuilder {
+	b.id = id
+	return b
+}
+
+// Index sets the position within the checklist
+func (b *ChecklistItemBuilder) Index(i int) *ChecklistItemBuilder {
+	b.index = i
+	return b
+}
+
+// Payload returns the creation payload
+func (b *ChecklistItemBuilder) Payload() ChecklistItemPayload {
+	return ChecklistItemPayload{
+		CreationDate:  timestamp(now()),
+		Title:         b.title,
+		Index:         b.index,
+		TaskIDs:       []string{b.taskID},
+		ExtensionData: DefaultExtension(),
+	}
+}
+
+// Envelope returns the item creating the checklist item
+func (b *ChecklistItemBuilder) Envelope() Envelope {
+	return Envelope{ID: b.id, Action: things.ItemActionCreated, Kind: things.ItemKindChecklistItem3, Payload: b.Payload()}
+}
+
+// UUID returns the UUID of the checklist item
+func (b *ChecklistItemBuilder) UUID() string { return b.id }
+
+// MarshalJSON encodes the item creating the checklist item
+func (b *ChecklistItemBuilder) MarshalJSON() ([]byte, error) { return b.Envelope().MarshalJSON() }
+
+// TagPayload is the payload of a tag creation, with all 5 fields
+type TagPayload struct {
+	Title         string    `json:"tt"`
+	Index         int       `json:"ix"`
+	ShortHand     *string   `json:"sh"`
+	ParentTagIDs  []string  `json:"pn"`
+	ExtensionData Extension `json:"xx"`
+}
+
+// TagBuilder builds a tag creation. Create one with NewTag.
+type TagBuilder struct {
+	id        string
+	title     string
+	shorthand *string
+	parentID  string
+}
+
+// NewTag starts building a tag
+func NewTag(title string) *TagBuilder {
+	return &TagBuilder{id: NewID(), title: title}
+}
+
+// WithID replaces the generated UUID
+func (b *TagBuilder) WithID(id string) *TagBuilder {
+	b.id = id
+	return b
+}
+
+// Shorthand sets the keyboard shortcut of the tag
+func (b *TagBuilder) Shorthand(key string) *TagBuilder {
+	b.shorthand = &key
+	return b
+}
+
+// Parent nests the tag below another tag
+func (b *TagBuilder) Parent(id string) *TagBuilder {
+	b.parentID = id
+	return b
+}
+
+// Payload returns the creation payload
+func (b *TagBuilder) Payload() TagPayload {
+	return TagPayload{
+		Title:         b.title,
+		Index:         -1237, // Things uses negative indices
+		ShortHand:     b.shorthand,
+		ParentTagIDs:  list(b.parentID),
+		ExtensionData: DefaultExtension(),
+	}
+}
+
+// Envelope returns the item creating the tag
+func (b *TagBuilder) Envelope() Envelope {
+	return Envelope{ID: b.id, Action: things.ItemActionCreated, Kind: things.ItemKindTag4, Payload: b.Payload()}
+}
+
+// UUID returns the UUID of the tag
+func (b *TagBuilder) UUID() string { return b.id }
+
+// MarshalJSON encodes the item creating the tag
+func (b *TagBuilder) MarshalJSON() ([]byte, error) { return b.Envelope().MarshalJSON() }
+
+// AreaPayload is the payload of an area creation
+type AreaPayload struct {
+	Index         int       `json:"ix"`
+	TagIDs        []string  `json:"tg"`
+	Title         string    `json:"tt"`
+	ExtensionData Extension `json:"xx"`
+}
+
+// AreaBuilder builds an area creation. Create one with NewArea.
+type AreaBuilder struct {
+	id     string
+	title  string
+	tagIDs []string
+}
+
+// NewArea starts building an area
+func NewArea(title string) *AreaBuilder {
+	return &AreaBuilder{id: NewID(), title: title}
+}
+
+// WithID replaces the generated UUID
+func (b *AreaBuilder) WithID(id string) *AreaBuilder {
+	b.id = id
+	return b
+}
+
+// WithTags sets the tags of the area
+func (b *AreaBuilder) WithTags(ids ...string) *AreaBuilder {
+	b.tagIDs = ids
+	return b
+}
+
+// Payload returns the creation payload
+func (b *AreaBuilder) Payload() AreaPayload {
+	return AreaPayload{
+		TagIDs:        append([]string{}, b.tagIDs...),
+		Title:         b.title,
+		ExtensionData: DefaultExtension(),
+	}
+}
+
+// Envelope returns the item creating the area
+func (b *AreaBuilder) Envelope() Envelope {
+	return Envelope{ID: b.id, Action: things.ItemActionCreated, Kind: things.ItemKindArea3, Payload: b.Payload()}
+}
+
+// UUID returns the UUID of the area
+func (b *AreaBuilder) UUID() string { return b.id }
+
+// MarshalJSON encodes the item creating the area
+func (b *AreaBuilder) MarshalJSON() ([]byte, error) { return b.Envelope().MarshalJSON() }
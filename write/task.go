@@ -0,0 +1,395 @@
+package write
+
+import (
+	"encoding/json"
+	"time"
+
+	things "github.com/arthursoares/things-cloud-sdk"
+)
+
+// TaskPayload is the payload of a task creation. It has all 34 fields, no omitempty,
+// and the field order matches what Things.app sends.
+type TaskPayload struct {
+	Type                      things.TaskType     `json:"tp"`
+	ScheduledDate             *int64              `json:"sr"`
+	DeadlineSuppression       *int64              `json:"dds"`
+	RecurrenceTaskIDs         []string            `json:"rt"`
+	ReminderDate              *int64              `json:"rmd"`
+	Status                    things.TaskStatus   `json:"ss"`
+	InTrash                   bool                `json:"tr"`
+	DelegateIDs               []string            `json:"dl"`
+	IsCompletedByChildren     bool                `json:"icp"`
+	Schedule                  things.TaskSchedule `json:"st"`
+	AreaIDs                   []string            `json:"ar"`
+	Title                     string              `json:"tt"`
+	DueOrder                  int                 `json:"do"`
+	LastActionItemID          *int64              `json:"lai"`
+	TaskIR                    *int64              `json:"tir"`
+	TagIDs                    []string            `json:"tg"`
+	ActionGroupIDs            []string            `json:"agr"`
+	Index                     int                 `json:"ix"`
+	CreationDate              float64             `json:"cd"`
+	Leavable                  bool                `json:"lt"`
+	IsCompletedCount          int                 `json:"icc"`
+	ModificationDate          *float64            `json:"md"`
+	TaskIndex                 int                 `json:"ti"`
+	DeadlineDate              *int64              `json:"dd"`
+	AlarmTimeOffset           *int                `json:"ato"`
+	Note                      Note                `json:"nt"`
+	InstanceCreationStartDate *int64              `json:"icsd"`
+	ParentTaskIDs             []string            `json:"pr"`
+	Rp                        *string             `json:"rp"`
+	ActionRequiredDate        *int64              `json:"acrd"`
+	CompletionDate            *float64            `json:"sp"`
+	SubtaskBehavior           int                 `json:"sb"`
+	Repeater                  *json.RawMessage    `json:"rr"`
+	ExtensionData             Extension           `json:"xx"`
+}
+
+// TaskBuilder builds a task creation. Create one with NewTask.
+type TaskBuilder struct {
+	id        string
+	title     string
+	typ       things.TaskType
+	when      *things.TaskSchedule
+	today     bool
+	scheduled *time.Time
+	deadline  *time.Time
+	note      string
+	areaID    string
+	projectID string
+	headingID string
+	tagIDs    []string
+}
+
+// NewTask starts building a task. Without further options it lands in the Inbox.
+func NewTask(title string) *TaskBuilder {
+	return &TaskBuilder{id: NewID(), title: title}
+}
+
+// WithID replaces the generated UUID
+func (b *TaskBuilder) WithID(id string) *TaskBuilder {
+	b.id = id
+	return b
+}
+
+// AsProject makes the task a project
+func (b *TaskBuilder) AsProject() *TaskBuilder {
+	b.typ = things.TaskTypeProject
+	return b
+}
+
+// AsHeading makes the task a heading. Headings are always scheduled for Anytime,
+// Things.app crashes on headings in the Inbox.
+func (b *TaskBuilder) AsHeading() *TaskBuilder {
+	b.typ = things.TaskTypeHeading
+	return b
+}
+
+func (b *TaskBuilder) schedule(st things.TaskSchedule, today bool) *TaskBuilder {
+	b.when, b.today = &st, today
+	return b
+}
+
+// Today schedules the task for today
+func (b *TaskBuilder) Today() *TaskBuilder { return b.schedule(things.TaskScheduleAnytime, true) }
+
+// Anytime schedules the task for Anytime
+func (b *TaskBuilder) Anytime() *TaskBuilder { return b.schedule(things.TaskScheduleAnytime, false) }
+
+// Someday schedules the task for Someday
+func (b *TaskBuilder) Someday() *TaskBuilder { return b.schedule(things.TaskScheduleSomeday, false) }
+
+// Inbox keeps the task in the Inbox, even if it is added to a project, heading or area
+func (b *TaskBuilder) Inbox() *TaskBuilder { return b.schedule(things.TaskScheduleInbox, false) }
+
+// ScheduledOn schedules the task for the calendar day of t. Future days go to Upcoming
+// (st=2) and other days to Today (st=1), even if Anytime or Someday was chosen: Things.app
+// crashes on deferred tasks dated today or earlier. Only an explicit Inbox is kept.
+func (b *TaskBuilder) ScheduledOn(t time.Time) *TaskBuilder {
+	b.scheduled = &t
+	return b
+}
+
+// Deadline sets the deadline to the calendar day of t
+func (b *TaskBuilder) Deadline(t time.Time) *TaskBuilder {
+	b.deadline = &t
+	return b
+}
+
+// Note sets the note text
+func (b *TaskBuilder) Note(text string) *TaskBuilder {
+	b.note = text
+	return b
+}
+
+// InArea adds the task to an area
+func (b *TaskBuilder) InArea(id string) *TaskBuilder {
+	b.areaID = id
+	return b
+}
+
+// InProject adds the task to a project
+func (b *TaskBuilder) InProject(id string) *TaskBuilder {
+	b.projectID = id
+	return b
+}
+
+// UnderHeading adds the task below a heading of a project
+func (b *TaskBuilder) UnderHeading(id string) *TaskBuilder {
+	b.headingID = id
+	return b
+}
+
+// WithTags sets the tags of the task
+func (b *TaskBuilder) WithTags(ids ...string) *TaskBuilder {
+	b.tagIDs = ids
+	return b
+}
+
+// Payload returns the creation payload
+func (b *TaskBuilder) Payload() TaskPayload {
+	p := TaskPayload{
+		Type:              b.typ,
+		RecurrenceTaskIDs: []string{},
+		DelegateIDs:       []string{},
+		AreaIDs:           list(b.areaID),
+		Title:             b.title,
+		TagIDs:            append([]string{}, b.tagIDs...),
+		ActionGroupIDs:    list(b.headingID),
+		CreationDate:      timestamp(now()),
+		ModificationDate:  nil, // must be null for creates — Things.app crashes otherwise
+		Note:              EmptyNote(),
+		ParentTaskIDs:     list(b.projectID),
+		ExtensionData:     DefaultExtension(),
+	}
+
+	// Headings are structural, and tasks in a project, below a heading or in an area
+	// are already triaged: they go to Anytime unless told otherwise
+	if b.typ == things.TaskTypeHeading || b.projectID != "" || b.headingID != "" || b.areaID != "" {
+		p.Schedule = things.TaskScheduleAnytime
+	}
+	if b.today {
+		today := day(now())
+		p.ScheduledDate, p.TaskIR = &today, &today
+	}
+	if b.scheduled != nil {
+		d := day(*b.scheduled)
+		p.ScheduledDate, p.TaskIR = &d, &d
+		p.Schedule = scheduleFor(d)
+	}
+	if b.when != nil && (b.scheduled == nil || *b.when == things.TaskScheduleInbox) {
+		p.Schedule = *b.when
+	}
+	if b.deadline != nil {
+		d := day(*b.deadline)
+		p.DeadlineDate = &d
+	}
+	if b.note != "" {
+		p.Note = TextNote(b.note)
+	}
+	return p
+}
+
+// Envelope returns the item creating the task
+func (b *TaskBuilder) Envelope() Envelope {
+	return Envelope{ID: b.id, Action: things.ItemActionCreated, Kind: things.ItemKindTask, Payload: b.Payload()}
+}
+
+// UUID returns the UUID of the task
+func (b *TaskBuilder) UUID() string { return b.id }
+
+// MarshalJSON encodes the item creating the task
+func (b *TaskBuilder) MarshalJSON() ([]byte, error) { return b.Envelope().MarshalJSON() }
+
+func list(id string) []string {
+	if id == "" {
+		return []string{}
+	}
+	return []string{id}
+}
+
+// TaskUpdate builds a sparse modification of a task: only the fields set are sent.
+// Create one with UpdateTask.
+type TaskUpdate struct {
+	id       string
+	fields   map[string]any
+	schedule bool
+}
+
+// UpdateTask starts building a modification of the task with the given id
+func UpdateTask(id string) *TaskUpdate {
+	return &TaskUpdate{id: id, fields: map[string]any{
+		"md": timestamp(now()),
+	}}
+}
+
+// Title changes the title
+func (u *TaskUpdate) Title(s string) *TaskUpdate {
+	u.fields["tt"] = s
+	return u
+}
+
+// Note replaces the note text
+func (u *TaskUpdate) Note(text string) *TaskUpdate {
+	u.fields["nt"] = TextNote(text)
+	return u
+}
+
+// EditNote changes the note from old to new with a delta note, the way Things.app
+// sends edits, so concurrent edits to other parts of the note are kept. old must be
+// the note text currently stored; nothing is sent if it equals new.
+func (u *TaskUpdate) EditNote(old, new string) *TaskUpdate {
+	if old != new {
+		u.fields["nt"] = things.NewDeltaNote(old, new)
+	}
+	return u
+}
+
+// ClearNote removes the note
+func (u *TaskUpdate) ClearNote() *TaskUpdate {
+	u.fields["nt"] = EmptyNote()
+	return u
+}
+
+// Complete marks the task as completed now
+func (u *TaskUpdate) Complete() *TaskUpdate {
+	u.fields["ss"] = things.TaskStatusCompleted
+	u.fields["sp"] = timestamp(now())
+	return u
+}
+
+// Cancel marks the task as canceled now
+func (u *TaskUpdate) Cancel() *TaskUpdate {
+	u.fields["ss"] = things.TaskStatusCanceled
+	u.fields["sp"] = timestamp(now())
+	return u
+}
+
+// Reopen marks the task as pending again
+func (u *TaskUpdate) Reopen() *TaskUpdate {
+	u.fields["ss"] = things.TaskStatusPending
+	u.fields["sp"] = nil
+	return u
+}
+
+// Trash moves the task to the trash, or restores it
+func (u *TaskUpdate) Trash(trashed bool) *TaskUpdate {
+	u.fields["tr"] = trashed
+	return u
+}
+
+func (u *TaskUpdate) setSchedule(st things.TaskSchedule, sr, tir any) *TaskUpdate {
+	u.fields["st"] = st
+	u.fields["sr"] = sr
+	u.fields["tir"] = tir
+	u.schedule = true
+	return u
+}
+
+// Today schedules the task for today
+func (u *TaskUpdate) Today() *TaskUpdate {
+	today := day(now())
+	return u.setSchedule(things.TaskScheduleAnytime, today, today)
+}
+
+// Anytime schedules the task for Anytime
+func (u *TaskUpdate) Anytime() *TaskUpdate {
+	return u.setSchedule(things.TaskScheduleAnytime, nil, nil)
+}
+
+// Someday schedules the task for Someday
+func (u *TaskUpdate) Someday() *TaskUpdate {
+	return u.setSchedule(things.TaskScheduleSomeday, nil, nil)
+}
+
+// Inbox moves the task back to the Inbox
+func (u *TaskUpdate) Inbox() *TaskUpdate {
+	return u.setSchedule(things.TaskScheduleInbox, nil, nil)
+}
+
+// ScheduledOn schedules the task for the calendar day of t. Future days go to Upcoming
+// (st=2) and other days to Today (st=1), even if Anytime or Someday was chosen: Things.app
+// crashes on deferred tasks dated today or earlier. Only an explicit Inbox is kept.
+func (u *TaskUpdate) ScheduledOn(t time.Time) *TaskUpdate {
+	d := day(t)
+	if st, ok := u.fields["st"]; ok && u.schedule && st == things.TaskScheduleInbox {
+		return u.setSchedule(things.TaskScheduleInbox, d, d)
+	}
+	return u.setSchedule(scheduleFor(d), d, d)
+}
+
+// scheduleFor returns the start state Things.app uses for a task scheduled on day d:
+// deferred until a future day, started otherwise
+func scheduleFor(d int64) things.TaskSchedule {
+	if d > day(now()) {
+		return things.TaskScheduleSomeday
+	}
+	return things.TaskScheduleAnytime
+}
+
+// Deadline sets the deadline to the calendar day of t
+func (u *TaskUpdate) Deadline(t time.Time) *TaskUpdate {
+	u.fields["dd"] = day(t)
+	return u
+}
+
+// ClearDeadline removes the deadline
+func (u *TaskUpdate) ClearDeadline() *TaskUpdate {
+	u.fields["dd"] = nil
+	return u
+}
+
+// triaged moves the task out of the Inbox unless a schedule was set
+func (u *TaskUpdate) triaged() *TaskUpdate {
+	if !u.schedule {
+		u.fields["st"] = things.TaskScheduleAnytime
+	}
+	return u
+}
+
+// InArea moves the task to an area, and out of the Inbox unless a schedule was set
+func (u *TaskUpdate) InArea(id string) *TaskUpdate {
+	u.fields["ar"] = []string{id}
+	return u.triaged()
+}
+
+// InProject moves the task to a project, and out of the Inbox unless a schedule was set
+func (u *TaskUpdate) InProject(id string) *TaskUpdate {
+	u.fields["pr"] = []string{id}
+	return u.triaged()
+}
+
+// UnderHeading moves the task below a heading, and out of the Inbox unless a schedule was set
+func (u *TaskUpdate) UnderHeading(id string) *TaskUpdate {
+	u.fields["agr"] = []string{id}
+	return u.triaged()
+}
+
+// WithTags replaces the tags of the task
+func (u *TaskUpdate) WithTags(ids ...string) *TaskUpdate {
+	u.fields["tg"] = append([]string{}, ids...)
+	return u
+}
+
+// Set sets a raw payload field, for fields without a dedicated method
+func (u *TaskUpdate) Set(key string, value any) *TaskUpdate {
+	u.fields[key] = value
+	return u
+}
+
+// Payload returns the fields of the modification
+func (u *TaskUpdate) Payload() map[string]any {
+	return u.fields
+}
+
+// Envelope returns the item modifying the task
+func (u *TaskUpdate) Envelope() Envelope {
+	return Envelope{ID: u.id, Action: things.ItemActionModified, Kind: things.ItemKindTask, Payload: u.fields}
+}
+
+// UUID returns the UUID of the modified task
+func (u *TaskUpdate) UUID() string { return u.id }
+
+// MarshalJSON encodes the item modifying the task
+func (u *TaskUpdate) MarshalJSON() ([]byte, error) { return u.Envelope().MarshalJSON() }
@@ -0,0 +1,127 @@
+// Package write builds wire-exact items for History.Write.
+//
+// Things.app is strict about what it accepts: creations must carry every field with
+// the exact types it writes itself, md must be null on creation, headings must be
+// scheduled for Anytime, and so on (see docs/client-side-bugs.md). The builders in
+// this package encode these rules, so
+//
+//	history.Write(write.NewTask("Buy milk").Today().InProject(projectID))
+//
+// produces the same payload Things.app would. Every builder implements
+// thingscloud.Identifiable and json.Marshaler and can be written directly.
+package write
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+
+	things "github.com/arthursoares/things-cloud-sdk"
+)
+
+// now is the clock used for creation, modification and stop dates
+var now = time.Now
+
+// Envelope is a single item ready to be written: {"t": action, "e": kind, "p": payload}
+type Envelope struct {
+	ID      string
+	Action  things.ItemAction
+	Kind    things.ItemKind
+	Payload any
+}
+
+// UUID returns the UUID of the item
+func (e Envelope) UUID() string { return e.ID }
+
+// MarshalJSON encodes the envelope in wire format
+func (e Envelope) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		T things.ItemAction `json:"t"`
+		E things.ItemKind   `json:"e"`
+		P any               `json:"p"`
+	}{e.Action, e.Kind, e.Payload})
+}
+
+// Note matches the note wire format exactly.
+// Field order must be _t, ch, v, t (matching what Things.app expects).
+type Note struct {
+	TypeTag  string `json:"_t"`
+	Checksum int64  `json:"ch"`
+	Value    string `json:"v"`
+	Type     int    `json:"t"`
+}
+
+// EmptyNote returns the note Things.app writes for tasks without a note
+func EmptyNote() Note {
+	return Note{TypeTag: "tx", Checksum: 0, Value: "", Type: things.NoteTypeFullText}
+}
+
+// TextNote returns a full text note with its checksum
+func TextNote(s string) Note {
+	return Note{TypeTag: "tx", Checksum: NoteChecksum(s), Value: s, Type: things.NoteTypeFullText}
+}
+
+// NoteChecksum returns the checksum Things.app stores with a note text
+func NoteChecksum(s string) int64 {
+	return things.NoteChecksum(s)
+}
+
+// Extension is the required xx field: {sn: {}, _t: "oo"}
+type Extension struct {
+	Sn      map[string]any `json:"sn"`
+	TypeTag string         `json:"_t"`
+}
+
+// DefaultExtension returns the extension data Things.app writes on creation
+func DefaultExtension() Extension {
+	return Extension{Sn: map[string]any{}, TypeTag: "oo"}
+}
+
+// NewID returns a random Base58 encoded UUID. Things.app crashes on other encodings.
+func NewID() string {
+	u := uuid.New()
+	// Base58 alphabet (Bitcoin/Flickr): no 0, O, I, l
+	const alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	n := new(big.Int).SetBytes(u[:])
+	base := big.NewInt(58)
+	mod := new(big.Int)
+	var encoded []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		encoded = append(encoded, alphabet[mod.Int64()])
+	}
+	// Reverse (big-endian)
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
+
+// timestamp returns t as fractional unix epoch
+func timestamp(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+// day returns the unix epoch of midnight UTC of the calendar day of t, which is how
+// Things stores dates
+func day(t time.Time) int64 {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix()
+}
+
+// Tombstone returns an item deleting the entity with the given id for good
+func Tombstone(id string) Envelope {
+	return Envelope{
+		ID:      NewID(),
+		Action:  things.ItemActionCreated,
+		Kind:    things.ItemKindTombstone,
+		Payload: TombstonePayload{DeletedObjectID: id, DeletionDate: timestamp(now())},
+	}
+}
+
+// TombstonePayload is the payload of a Tombstone2 item
+type TombstonePayload struct {
+	DeletionDate    float64 `json:"dld"`
+	DeletedObjectID string  `json:"dloid"`
+}
@@ -0,0 +1,191 @@
+package write
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	things "github.com/arthursoares/things-cloud-sdk"
+	"github.com/arthursoares/things-cloud-sdk/state/memory"
+	"github.com/arthursoares/things-cloud-sdk/thingscloudtest"
+)
+
+// fixed is the clock of all tests: 2026-03-04 10:30 UTC
+var fixed = time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)
+
+func init() {
+	now = func() time.Time { return fixed }
+}
+
+func marshal(t *testing.T, v any) string {
+	t.Helper()
+	bs, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(bs)
+}
+
+func TestNewTask(t *testing.T) {
+	t.Parallel()
+	t.Run("Inbox", func(t *testing.T) {
+		t.Parallel()
+		got := marshal(t, NewTask("Buy milk").WithID("task-1"))
+		expected := `{"t":0,"e":"Task6","p":{"tp":0,"sr":null,"dds":null,"rt":[],"rmd":null,"ss":0,"tr":false,"dl":[],"icp":false,"st":0,"ar":[],"tt":"Buy milk","do":0,"lai":null,"tir":null,"tg":[],"agr":[],"ix":0,"cd":1772620200,"lt":false,"icc":0,"md":null,"ti":0,"dd":null,"ato":null,"nt":{"_t":"tx","ch":0,"v":"","t":1},"icsd":null,"pr":[],"rp":null,"acrd":null,"sp":null,"sb":0,"rr":null,"xx":{"sn":{},"_t":"oo"}}}`
+		if got != expected {
+			t.Errorf("Expected %s but got %s", expected, got)
+		}
+	})
+
+	t.Run("TodayInProject", func(t *testing.T) {
+		t.Parallel()
+		p := NewTask("Buy milk").Today().InProject("project-1").WithTags("tag-1", "tag-2").Note("2 litres").Payload()
+		today := fixed.Truncate(24 * time.Hour).Unix()
+		if p.Schedule != things.TaskScheduleAnytime || *p.ScheduledDate != today || *p.TaskIR != today {
+			t.Errorf("Expected Anytime with today's date, but got st=%d sr=%v tir=%v", p.Schedule, p.ScheduledDate, p.TaskIR)
+		}
+		if len(p.ParentTaskIDs) != 1 || p.ParentTaskIDs[0] != "project-1" || len(p.TagIDs) != 2 {
+			t.Errorf("Expected project and tags, but got %v %v", p.ParentTaskIDs, p.TagIDs)
+		}
+		if p.Note.Value != "2 litres" || p.Note.Checksum != NoteChecksum("2 litres") {
+			t.Errorf("Expected a text note with checksum, but got %+v", p.Note)
+		}
+	})
+
+	t.Run("Triaged", func(t *testing.T) {
+		t.Parallel()
+		testCases := []struct {
+			Name     string
+			Builder  *TaskBuilder
+			Expected things.TaskSchedule
+		}{
+			{"Heading", NewTask("h").AsHeading(), things.TaskScheduleAnytime},
+			{"Area", NewTask("a").InArea("area-1"), things.TaskScheduleAnytime},
+			{"Heading below", NewTask("a").UnderHeading("heading-1"), things.TaskScheduleAnytime},
+			{"Scheduled", NewTask("a").ScheduledOn(fixed), things.TaskScheduleAnytime},
+			{"Upcoming", NewTask("a").ScheduledOn(fixed.AddDate(0, 0, 3)), things.TaskScheduleSomeday},
+			{"Someday dated today", NewTask("a").Someday().ScheduledOn(fixed), things.TaskScheduleAnytime},
+			{"Anytime dated later", NewTask("a").Anytime().ScheduledOn(fixed.AddDate(0, 0, 3)), things.TaskScheduleSomeday},
+			{"Explicit inbox", NewTask("a").InArea("area-1").Inbox(), things.TaskScheduleInbox},
+			{"Someday", NewTask("a").InProject("project-1").Someday(), things.TaskScheduleSomeday},
+		}
+		for _, testCase := range testCases {
+			if got := testCase.Builder.Payload().Schedule; got != testCase.Expected {
+				t.Errorf("%s: expected st=%d but got %d", testCase.Name, testCase.Expected, got)
+			}
+		}
+	})
+}
+
+func TestUpdateTask(t *testing.T) {
+	t.Parallel()
+	t.Run("Complete", func(t *testing.T) {
+		t.Parallel()
+		got := marshal(t, UpdateTask("task-1").Complete())
+		expected := `{"t":1,"e":"Task6","p":{"md":1772620200,"sp":1772620200,"ss":3}}`
+		if got != expected {
+			t.Errorf("Expected %s but got %s", expected, got)
+		}
+	})
+
+	t.Run("MoveToProject", func(t *testing.T) {
+		t.Parallel()
+		got := marshal(t, UpdateTask("task-1").InProject("project-1"))
+		expected := `{"t":1,"e":"Task6","p":{"md":1772620200,"pr":["project-1"],"st":1}}`
+		if got != expected {
+			t.Errorf("Expected %s but got %s", expected, got)
+		}
+	})
+
+	t.Run("EditNote", func(t *testing.T) {
+		t.Parallel()
+		got := marshal(t, UpdateTask("task-1").EditNote("buy milk", "buy oat milk"))
+		expected := fmt.Sprintf(`{"t":1,"e":"Task6","p":{"md":1772620200,"nt":{"_t":"tx","t":2,"ps":[{"r":"oat ","p":4,"l":0,"ch":%d}]}}}`, NoteChecksum("buy oat milk"))
+		if got != expected {
+			t.Errorf("Expected %s but got %s", expected, got)
+		}
+		if p := UpdateTask("task-1").EditNote("same", "same").Payload(); p["nt"] != nil {
+			t.Errorf("Expected an unchanged note not to be sent, but got %v", p["nt"])
+		}
+	})
+
+	t.Run("ExplicitSchedule", func(t *testing.T) {
+		t.Parallel()
+		p := UpdateTask("task-1").Someday().ScheduledOn(fixed.AddDate(0, 0, 3)).InArea("area-1").Payload()
+		date := fixed.Truncate(24*time.Hour).AddDate(0, 0, 3).Unix()
+		if p["st"] != things.TaskScheduleSomeday || p["sr"] != date {
+			t.Errorf("Expected the explicit schedule to be kept, but got st=%v sr=%v", p["st"], p["sr"])
+		}
+
+		p = UpdateTask("task-1").Someday().ScheduledOn(fixed.AddDate(0, 0, -1)).Payload()
+		if p["st"] != things.TaskScheduleAnytime {
+			t.Errorf("Expected a past day to start the task, but got st=%v", p["st"])
+		}
+	})
+}
+
+func TestEntities(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		Name     string
+		Item     any
+		Expected string
+	}{
+		{"ChecklistItem", NewChecklistItem("task-1", "Eggs").Index(2),
+			`{"t":0,"e":"ChecklistItem3","p":{"cd":1772620200,"md":null,"tt":"Eggs","ss":0,"sp":null,"ix":2,"ts":["task-1"],"lt":false,"xx":{"sn":{},"_t":"oo"}}}`},
+		{"Tag", NewTag("Errand").Shorthand("e"),
+			`{"t":0,"e":"Tag4","p":{"tt":"Errand","ix":-1237,"sh":"e","pn":[],"xx":{"sn":{},"_t":"oo"}}}`},
+		{"Area", NewArea("Home").WithTags("tag-1"),
+			`{"t":0,"e":"Area3","p":{"ix":0,"tg":["tag-1"],"tt":"Home","xx":{"sn":{},"_t":"oo"}}}`},
+		{"Tombstone", Tombstone("task-1"),
+			`{"t":0,"e":"Tombstone2","p":{"dld":1772620200,"dloid":"task-1"}}`},
+	}
+	for _, testCase := range testCases {
+		if got := marshal(t, testCase.Item); got != testCase.Expected {
+			t.Errorf("%s: expected %s but got %s", testCase.Name, testCase.Expected, got)
+		}
+	}
+}
+
+func TestNewID(t *testing.T) {
+	t.Parallel()
+	id := NewID()
+	if len(id) < 20 || strings.ContainsAny(id, "0OIl-") {
+		t.Errorf("Expected a Base58 encoded UUID, but got %q", id)
+	}
+	if NewID() == id {
+		t.Error("Expected IDs to be random")
+	}
+}
+
+func TestBuilders_Write(t *testing.T) {
+	t.Parallel()
+	s := thingscloudtest.NewServer("martin@example.com", "secret")
+	defer s.Close()
+	h := s.Client().HistoryWithID(s.OwnHistoryKey())
+
+	task := NewTask("Buy milk").Today()
+	if _, err := h.Write(task, NewChecklistItem(task.UUID(), "Oat milk"), NewArea("Home"), NewTag("Errand")); err != nil {
+		t.Fatalf("Expected creations to be accepted, but got %v", err)
+	}
+	if _, err := h.Write(UpdateTask(task.UUID()).Title("Buy oat milk").Complete()); err != nil {
+		t.Fatalf("Expected update to be accepted, but got %v", err)
+	}
+
+	state := memory.NewState()
+	for item, err := range h.All(t.Context(), 0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		state.Update(item)
+	}
+	got := state.Tasks[task.UUID()]
+	if got == nil || got.Title != "Buy oat milk" || got.Status != things.TaskStatusCompleted {
+		t.Fatalf("Expected completed task in state, but got %+v", got)
+	}
+	if len(state.CheckListItems) != 1 || len(state.Areas) != 1 || len(state.Tags) != 1 {
+		t.Errorf("Expected checklist item, area and tag in state, but got %d, %d, %d", len(state.CheckListItems), len(state.Areas), len(state.Tags))
+	}
+}